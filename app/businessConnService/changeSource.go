@@ -0,0 +1,28 @@
+package businessConnService
+
+import (
+	"context"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+type changeSourceKey struct{}
+
+// WithChangeSource - return ctx with source of changes of products made
+// with it, source is saved in history of products by repository
+func WithChangeSource(ctx context.Context, source models.ChangeSource) context.Context {
+	return context.WithValue(ctx, changeSourceKey{}, source)
+}
+
+// ChangeSourceFromContext - return source of changes saved in ctx, if it
+// has no request id, id of current request is used
+func ChangeSourceFromContext(ctx context.Context) models.ChangeSource {
+	source, _ := ctx.Value(changeSourceKey{}).(models.ChangeSource)
+
+	if source.RequestID == "" {
+		source.RequestID = logging.RequestIDFromContext(ctx)
+	}
+
+	return source
+}
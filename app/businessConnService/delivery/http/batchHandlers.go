@@ -0,0 +1,301 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// ndjsonContentType - content type of body with one json product on line
+const ndjsonContentType = "application/x-ndjson"
+
+// maxSyncBatchSize - max products of batch which is uploaded synchronously
+const maxSyncBatchSize = 100
+
+// errEmptyBatch - batch has no products
+var errEmptyBatch = errors.New("batch is empty")
+
+// parseBatch - split body of batch upload to json products, body is json
+// array or ndjson where empty lines are skipped. Products are not decoded
+// here, so invalid product is error of its row
+func parseBatch(body io.Reader, ndjson bool) ([]json.RawMessage, error) {
+	products := []json.RawMessage{}
+
+	if !ndjson {
+		if err := json.NewDecoder(body).Decode(&products); err != nil {
+			return nil, err
+		}
+	} else {
+		reader := bufio.NewReader(body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				products = append(products, json.RawMessage(line))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(products) == 0 {
+		return nil, errEmptyBatch
+	}
+
+	return products, nil
+}
+
+// swagger:operation POST /api/v1/sellers/{seller_id}/offers:batch handleLoadBatch
+//
+// Upload products of seller from json array or ndjson (Content-Type
+// application/x-ndjson) instead of xlsx file. Batch is processed by task
+// like /api/v1/sellers/{seller_id}/tasks: every product is row which
+// creates, updates or deletes (available false) product, task id is
+// returned and state, stats and dry run preview of task are available.
+// Product without currency has currency of batch, product without
+// available is kept available. Row of product is its number in batch.
+// Task id of queued task is returned with 202. Batch of at most 100
+// products can be uploaded synchronously, then stats and errors of rows
+// are returned with 200 when task is done
+// ---
+// summary: Upload batch of products
+// operationId: handleLoadBatch
+// consumes:
+// - application/json
+// - application/x-ndjson
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: dry_run
+//   in: query
+//   description: Do not change products, only save preview of changes of task.
+//   required: false
+//   type: boolean
+// - name: currency
+//   in: query
+//   description: Currency of prices of products without currency, RUB by default.
+//   required: false
+//   type: string
+// - name: sync
+//   in: query
+//   description: Wait until task is done and return its result instead of task id.
+//   required: false
+//   type: boolean
+// - name: products
+//   in: body
+//   required: true
+//   schema:
+//     type: array
+//     items:
+//       $ref: '#/definitions/ProductInfo'
+// responses:
+//   200:
+//     description: result of synchronous task, state of failed or cancelled task is FAILED or CANCELLED
+//     schema:
+//       $ref: '#/definitions/BatchResult'
+//   202:
+//     description: task is queued, its id is returned
+//     schema:
+//       type: integer
+//       format: int64
+//   400:
+//     description: Invalid seller_id, dry_run, currency, sync or body supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   413:
+//     description: Body is larger than maxUploadSize
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleLoadBatch(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context(), h.logger)
+
+	sellerID, _, ok := h.parseOfferVars(w, r, false)
+	if !ok {
+		return
+	}
+
+	log = log.WithField(logging.SellerIDField, sellerID)
+
+	query := r.URL.Query()
+
+	var dryRun, sync bool
+	var err error
+
+	if value := query.Get("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			h.respondBadRequest(w, r, codeInvalidDryRun, "dry_run", "must be boolean")
+			return
+		}
+	}
+
+	if value := query.Get("sync"); value != "" {
+		if sync, err = strconv.ParseBool(value); err != nil {
+			h.respondBadRequest(w, r, codeInvalidSync, "sync", "must be boolean")
+			return
+		}
+	}
+
+	currency := models.DefaultCurrency
+	if value := query.Get("currency"); value != "" {
+		if !tools.IsCurrency(value) {
+			h.respondBadRequest(w, r, codeInvalidCurrency, "currency", "must be ISO 4217 code")
+			return
+		}
+		currency = value
+	}
+
+	if h.maxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	products, err := parseBatch(r.Body, mediaType == ndjsonContentType)
+	if err != nil {
+		log.WithError(err).Info("Invalid batch")
+
+		switch {
+		case isBodyTooLarge(err):
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge,
+				"Body is larger than "+strconv.FormatInt(h.maxUploadSize, 10)+" bytes")
+		case errors.Is(err, errEmptyBatch):
+			h.respondBadRequest(w, r, codeInvalidRequestBody, "products", "must not be empty")
+		default:
+			h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody,
+				"Request body must be json array or ndjson of ProductInfo")
+		}
+		return
+	}
+
+	if sync && len(products) > maxSyncBatchSize {
+		h.respondBadRequest(w, r, codeInvalidSync, "sync",
+			fmt.Sprintf("is allowed for at most %d products", maxSyncBatchSize))
+		return
+	}
+
+	taskID, err := h.usecase.CreateTask(r.Context())
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	log = log.WithField(logging.TaskIDField, taskID)
+
+	task := models.Task{
+		TaskID:       taskID,
+		SellerID:     sellerID,
+		RequestID:    logging.RequestIDFromContext(r.Context()),
+		TraceCarrier: tracing.InjectCarrier(r.Context()),
+		Products:     products,
+		Currency:     currency,
+		DryRun:       dryRun,
+	}
+
+	var result chan models.BatchResult
+	if sync {
+		result = make(chan models.BatchResult, 1)
+		task.Result = result
+	}
+
+	h.taskQueue <- task
+
+	log.WithFields(logrus.Fields{
+		"dry_run":  dryRun,
+		"sync":     sync,
+		"products": len(products),
+	}).Info("Batch task queued")
+
+	if !sync {
+		taskIDJSON, err := json.Marshal(taskID)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(taskIDJSON)
+		return
+	}
+
+	// task keeps running if client is gone, its result is available by task id
+	select {
+	case batchResult, ok := <-result:
+		// task failed or was cancelled before result, so its saved state is
+		// returned
+		if !ok {
+			unfinished, err := h.unfinishedBatchResult(r.Context(), taskID)
+			if err != nil {
+				h.respondError(w, r, err)
+				return
+			}
+			batchResult = *unfinished
+		}
+
+		batchResultJSON, err := json.Marshal(batchResult)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(batchResultJSON)
+	case <-r.Context().Done():
+		log.WithError(r.Context().Err()).Info("Request finished before batch task is done")
+	}
+}
+
+// unfinishedBatchResult - result of synchronous task which is closed without
+// result, it has saved state and stats of task and errors of its rows
+func (h *handlers) unfinishedBatchResult(ctx context.Context, taskID int64) (*models.BatchResult, error) {
+	taskState, err := h.usecase.SelectTaskState(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// stats are saved only for products changed before task is finished
+	taskStats := &models.TaskStats{TaskID: taskID}
+	if savedStats, err := h.usecase.SelectTaskStatsByTaskID(ctx, taskID); err == nil {
+		taskStats = savedStats
+	} else if !errors.Is(err, domainErrors.ErrNotFound) {
+		return nil, err
+	}
+
+	rowErrors, err := h.usecase.SelectTaskErrors(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BatchResult{
+		TaskID: taskID,
+		State:  taskState.State,
+		Stats:  *taskStats,
+		Errors: rowErrors,
+	}, nil
+}
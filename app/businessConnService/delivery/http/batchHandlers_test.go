@@ -0,0 +1,172 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBatch(t *testing.T) {
+	products, err := parseBatch(strings.NewReader(`[{"offer_id":1}, {"offer_id":2}]`), false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`{"offer_id":1}`), json.RawMessage(`{"offer_id":2}`)},
+			products)
+	}
+
+	// test invalid line of ndjson is kept as its row
+
+	products, err = parseBatch(strings.NewReader("{\"offer_id\":1}\n\n{\"offer_id\":\n{\"offer_id\":3}"), true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`{"offer_id":1}`), json.RawMessage(`{"offer_id":`),
+			json.RawMessage(`{"offer_id":3}`)}, products)
+	}
+
+	_, err = parseBatch(strings.NewReader(`{"offer_id":1}`), false)
+	assert.Error(t, err)
+
+	_, err = parseBatch(strings.NewReader(`[]`), false)
+	assert.Equal(t, errEmptyBatch, err)
+
+	_, err = parseBatch(strings.NewReader("\n\n"), true)
+	assert.Equal(t, errEmptyBatch, err)
+}
+
+func TestHandleLoadBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	taskQueue := make(chan models.Task, 1)
+	router := NewHandlers(usecase, taskQueue, Config{MaxUploadSize: 1 << 10}, logrus.New())
+
+	// test batch is queued as task and task id is returned
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(5), nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/sellers/1/offers:batch?dry_run=true&currency=USD",
+		strings.NewReader("{\"offer_id\":1,\"name\":\"телефон\",\"price\":\"10\"}\n{\"offer_id\":2}\n"))
+	request.Header.Set("Content-Type", ndjsonContentType)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if assert.Equal(t, http.StatusAccepted, response.Code) {
+		assert.Equal(t, "5", response.Body.String())
+
+		task := <-taskQueue
+		assert.Equal(t, int64(5), task.TaskID)
+		assert.Equal(t, int64(1), task.SellerID)
+		assert.Equal(t, "USD", task.Currency)
+		assert.True(t, task.DryRun)
+		assert.Len(t, task.Products, 2)
+		assert.Nil(t, task.Result)
+	}
+
+	// test synchronous batch returns result of task
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(6), nil)
+
+	go func() {
+		task := <-taskQueue
+		task.Result <- models.BatchResult{
+			TaskID: task.TaskID,
+			State:  "DONE",
+			Stats:  models.TaskStats{TaskID: task.TaskID, ProductsCreated: 1, RowsWithErrors: 1},
+			Errors: []models.RowError{{Row: 2, OfferID: 2, Error: "Misrepresentation of values",
+				Details: []models.ErrorDetail{{Field: "name", Message: "must not be empty"}}}},
+		}
+		close(task.Result)
+	}()
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/v1/sellers/1/offers:batch?sync=true",
+		strings.NewReader(`[{"offer_id":1,"name":"телефон","price":"10"},{"offer_id":2}]`)))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `{"task_id":6,"state":"DONE","stats":{"task_id":6,"products_created":1,"products_updated":0,`+
+			`"products_deleted":0,"rows_with_errors":1},"errors":[{"row":2,"offer_id":2,"error":"Misrepresentation of values",`+
+			`"details":[{"field":"name","message":"must not be empty"}]}]}`, response.Body.String())
+	}
+
+	// test synchronous task which failed before result
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(7), nil)
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(7)).Return(&models.TaskState{TaskID: 7, State: "FAILED"}, nil)
+	usecase.EXPECT().SelectTaskStatsByTaskID(gomock.Any(), int64(7)).
+		Return(nil, domainErrors.NotFound("task stats not found"))
+	usecase.EXPECT().SelectTaskErrors(gomock.Any(), int64(7)).Return([]models.RowError{}, nil)
+
+	go func() {
+		task := <-taskQueue
+		close(task.Result)
+	}()
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/v1/sellers/1/offers:batch?sync=true",
+		strings.NewReader(`[{"offer_id":1}]`)))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `{"task_id":7,"state":"FAILED","stats":{"task_id":7,"products_created":0,"products_updated":0,`+
+			`"products_deleted":0,"rows_with_errors":0},"errors":[]}`, response.Body.String())
+	}
+
+	// test synchronous task which state is not found
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(8), nil)
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(8)).Return(nil, domainErrors.NotFound("task not found"))
+
+	go func() {
+		task := <-taskQueue
+		close(task.Result)
+	}()
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/api/v1/sellers/1/offers:batch?sync=true",
+		strings.NewReader(`[{"offer_id":1}]`)))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleLoadBatchIncorrectData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{MaxUploadSize: 1 << 10}, logrus.New())
+
+	largeBatch := "[" + strings.Repeat(`{},`, maxSyncBatchSize) + "{}]"
+
+	for _, testCase := range []struct {
+		url    string
+		body   string
+		status int
+		code   string
+	}{
+		{"/api/v1/sellers/0/offers:batch", `[{}]`, http.StatusBadRequest, codeInvalidSellerID},
+		{"/api/v1/sellers/1/offers:batch?dry_run=maybe", `[{}]`, http.StatusBadRequest, codeInvalidDryRun},
+		{"/api/v1/sellers/1/offers:batch?sync=maybe", `[{}]`, http.StatusBadRequest, codeInvalidSync},
+		{"/api/v1/sellers/1/offers:batch?currency=rub", `[{}]`, http.StatusBadRequest, codeInvalidCurrency},
+		{"/api/v1/sellers/1/offers:batch", `{}`, http.StatusBadRequest, codeInvalidRequestBody},
+		{"/api/v1/sellers/1/offers:batch", `[]`, http.StatusBadRequest, codeInvalidRequestBody},
+		{"/api/v1/sellers/1/offers:batch?sync=true", largeBatch, http.StatusBadRequest, codeInvalidSync},
+		{"/api/v1/sellers/1/offers:batch", "[" + strings.Repeat(`{},`, 1<<10) + "{}]",
+			http.StatusRequestEntityTooLarge, codeBodyTooLarge},
+	} {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodPost, testCase.url, strings.NewReader(testCase.body)))
+
+		assert.Equal(t, testCase.status, response.Code, testCase.url)
+		assert.Contains(t, response.Body.String(), `"code":"`+testCase.code+`"`, testCase.url)
+	}
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// Codes of errors returned to client in ErrorResponse
+const (
+	codeInvalidSellerID       = "invalid_seller_id"
+	codeInvalidTaskID         = "invalid_task_id"
+	codeInvalidOfferID        = "invalid_offer_id"
+	codeInvalidDryRun         = "invalid_dry_run"
+	codeInvalidSync           = "invalid_sync"
+	codeInvalidSourceURL      = "invalid_source_url"
+	codeInvalidChecksum       = "invalid_checksum"
+	codeInvalidIfMatch        = "invalid_if_match"
+	codeInvalidIncludeDeleted = "invalid_include_deleted"
+	codeInvalidCurrency       = "invalid_currency"
+	codeInvalidPrice          = "invalid_price"
+	codeInvalidLimit          = "invalid_limit"
+	codeInvalidOffset         = "invalid_offset"
+	codeInvalidRequestBody    = "invalid_request_body"
+	codeInvalidMultipart      = "invalid_multipart"
+	codeMultipartTooLarge     = "multipart_too_large"
+	codeBodyTooLarge          = "body_too_large"
+	codeNotFound              = "not_found"
+	codeConflict              = "conflict"
+	codeValidation            = "validation_error"
+	codeForbidden             = "forbidden"
+	codePreconditionFailed    = "precondition_failed"
+	codeTooManyRequests       = "too_many_requests"
+	codeInternal              = "internal_error"
+)
+
+// domainErrorStatuses - http status and error code for every kind of domain error
+var domainErrorStatuses = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{domainErrors.ErrNotFound, http.StatusNotFound, codeNotFound},
+	{domainErrors.ErrConflict, http.StatusConflict, codeConflict},
+	{domainErrors.ErrValidation, http.StatusBadRequest, codeValidation},
+	{domainErrors.ErrForbidden, http.StatusForbidden, codeForbidden},
+	{domainErrors.ErrPreconditionFailed, http.StatusPreconditionFailed, codePreconditionFailed},
+}
+
+// respondError - translate error returned by usecase to http status and
+// write it as json ErrorResponse, errors which are not domain are hidden
+// from client behind internal error
+func (h *handlers) respondError(w http.ResponseWriter, r *http.Request, err error) {
+	log := logging.FromContext(r.Context(), h.logger)
+
+	for _, item := range domainErrorStatuses {
+		if errors.Is(err, item.kind) {
+			log.WithError(err).Info("Request failed")
+
+			details := []models.ErrorDetail{}
+			for _, field := range domainErrors.Fields(err) {
+				details = append(details, models.ErrorDetail{
+					Field:   field.Field,
+					Message: field.Message,
+				})
+			}
+
+			h.writeError(w, r, item.status, item.code, domainErrors.Message(err), details...)
+			return
+		}
+	}
+
+	log.WithError(err).Error("InternalError")
+	h.writeError(w, r, http.StatusInternalServerError, codeInternal,
+		http.StatusText(http.StatusInternalServerError))
+}
+
+// respondBadRequest - write ErrorResponse about invalid field of request
+func (h *handlers) respondBadRequest(w http.ResponseWriter, r *http.Request, code, field, message string) {
+	logging.FromContext(r.Context(), h.logger).
+		WithField("field", field).
+		Info("Invalid request: " + message)
+
+	h.writeError(w, r, http.StatusBadRequest, code, "Invalid "+field,
+		models.ErrorDetail{Field: field, Message: message})
+}
+
+// writeError - write ErrorResponse with status, request id is taken from
+// request context so that client can report it
+func (h *handlers) writeError(w http.ResponseWriter, r *http.Request, status int, code, message string,
+	details ...models.ErrorDetail) {
+
+	body, err := json.Marshal(models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: logging.RequestIDFromContext(r.Context()),
+	})
+	if err != nil {
+		logging.FromContext(r.Context(), h.logger).WithError(err).Error("InternalError")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// isBodyTooLarge - body of request was cut by http.MaxBytesReader
+func isBodyTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "http: request body too large")
+}
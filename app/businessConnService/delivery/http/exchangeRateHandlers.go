@@ -0,0 +1,158 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// swagger:operation GET /api/v1/exchangeRates handleListExchangeRates
+//
+// Get exchange rates used for conversion of prices, rate is price of one
+// unit of currency in RUB
+// ---
+// summary: Get exchange rates
+// operationId: handleListExchangeRates
+// produces:
+// - application/json
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       type: array
+//       items:
+//         $ref: '#/definitions/ExchangeRate'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.usecase.SelectExchangeRates(r.Context())
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	ratesJSON, err := json.Marshal(rates)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(ratesJSON)
+}
+
+// swagger:operation PUT /api/v1/exchangeRates/{currency} handleUpdateExchangeRate
+//
+// Set exchange rate of currency, rate of new currency is created.
+// Only admin can change rates, admin token is passed in Authorization header
+// ---
+// summary: Set exchange rate
+// operationId: handleUpdateExchangeRate
+// consumes:
+// - application/json
+// parameters:
+// - name: currency
+//   in: path
+//   required: true
+//   type: string
+// - name: Authorization
+//   in: header
+//   description: Bearer admin token
+//   required: true
+//   type: string
+// - name: body
+//   in: body
+//   required: true
+//   schema:
+//     $ref: '#/definitions/ExchangeRate'
+// responses:
+//   204:
+//     description: rate is set
+//   400:
+//     description: Invalid currency or rate supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   403:
+//     description: Admin token is invalid
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleUpdateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rate := new(models.ExchangeRate)
+	if err := json.NewDecoder(r.Body).Decode(rate); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be json ExchangeRate")
+		return
+	}
+
+	// currency is taken from path only
+	rate.Currency = mux.Vars(r)["currency"]
+
+	if err := tools.ValidateExchangeRate(rate); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if _, err := h.usecase.UpdateExchangeRate(r.Context(), rate); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:operation DELETE /api/v1/exchangeRates/{currency} handleDeleteExchangeRate
+//
+// Delete exchange rate of currency, prices in it are not converted anymore.
+// Only admin can change rates, admin token is passed in Authorization header
+// ---
+// summary: Delete exchange rate
+// operationId: handleDeleteExchangeRate
+// parameters:
+// - name: currency
+//   in: path
+//   required: true
+//   type: string
+// - name: Authorization
+//   in: header
+//   description: Bearer admin token
+//   required: true
+//   type: string
+// responses:
+//   204:
+//     description: rate is deleted
+//   403:
+//     description: Admin token is invalid
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No rate of currency
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleDeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rowsAffected, err := h.usecase.DeleteExchangeRate(r.Context(), mux.Vars(r)["currency"])
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if rowsAffected == 0 {
+		h.respondError(w, r, domainErrors.NotFound("exchange rate not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
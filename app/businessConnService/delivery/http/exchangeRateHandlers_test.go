@@ -0,0 +1,153 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleListExchangeRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	rates := []*models.ExchangeRate{
+		{Currency: "USD", Rate: decimal.RequireFromString("90.5"), UpdatedAt: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectExchangeRates(gomock.Any()).Return(rates, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleListExchangeRates(response, httptest.NewRequest(http.MethodGet, "/api/v1/exchangeRates", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `[{"currency":"USD","rate":"90.5","updated_at":"2021-01-01T12:00:00Z"}]`, response.Body.String())
+	}
+
+	// test db error
+
+	usecase.EXPECT().SelectExchangeRates(gomock.Any()).Return(nil, errors.New("DB error"))
+
+	response = httptest.NewRecorder()
+	handlers.handleListExchangeRates(response, httptest.NewRequest(http.MethodGet, "/api/v1/exchangeRates", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestHandleUpdateExchangeRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:    usecase,
+		taskQueue:  make(chan models.Task),
+		logger:     logrus.New(),
+		adminToken: "secret",
+	}
+
+	handler := handlers.requireAdmin(handlers.handleUpdateExchangeRate)
+
+	newRequest := func(currency, token, body string) *http.Request {
+		request := httptest.NewRequest(http.MethodPut, "/api/v1/exchangeRates/"+currency, strings.NewReader(body))
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		return mux.SetURLVars(request, map[string]string{"currency": currency})
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().UpdateExchangeRate(gomock.Any(),
+		&models.ExchangeRate{Currency: "USD", Rate: decimal.RequireFromString("90.5")}).Return(int64(1), nil)
+
+	response := httptest.NewRecorder()
+	handler(response, newRequest("USD", "secret", `{"rate":"90.5"}`))
+
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	// test without admin token
+
+	for _, token := range []string{"", "wrong"} {
+		response = httptest.NewRecorder()
+		handler(response, newRequest("USD", token, `{"rate":"90.5"}`))
+
+		assert.Equal(t, http.StatusForbidden, response.Code, token)
+	}
+
+	// test invalid rate
+
+	response = httptest.NewRecorder()
+	handler(response, newRequest("usd", "secret", `{"rate":"-1"}`))
+
+	if assert.Equal(t, http.StatusBadRequest, response.Code) {
+		assert.Contains(t, response.Body.String(), `"field":"currency"`)
+		assert.Contains(t, response.Body.String(), `"field":"rate"`)
+	}
+
+	// test admin routes are forbidden without configured token
+
+	handlers.adminToken = ""
+
+	response = httptest.NewRecorder()
+	handler(response, newRequest("USD", "", `{"rate":"90.5"}`))
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+func TestHandleDeleteExchangeRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	newRequest := func(currency string) *http.Request {
+		request := httptest.NewRequest(http.MethodDelete, "/api/v1/exchangeRates/"+currency, nil)
+		return mux.SetURLVars(request, map[string]string{"currency": currency})
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().DeleteExchangeRate(gomock.Any(), "USD").Return(int64(1), nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleDeleteExchangeRate(response, newRequest("USD"))
+
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	// test rate does not exist
+
+	usecase.EXPECT().DeleteExchangeRate(gomock.Any(), "EUR").Return(int64(0), nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleDeleteExchangeRate(response, newRequest("EUR"))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
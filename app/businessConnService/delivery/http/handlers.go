@@ -1,72 +1,151 @@
 package http
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/middlewares"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
 )
 
+// multipartMemory - max size of multipart form kept in memory, rest of files
+// are saved on disk
+const multipartMemory = 32 << 20
+
+// xlsxContentType - content type of xlsx files
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// checksumPrefix - prefix of checksum of source_url, only sha256 is supported
+const checksumPrefix = "sha256:"
+
+// checksumRegexp - sha256 checksum in hex without prefix
+var checksumRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// isSourceURL - value is http(s) url with host or file url with path,
+// file url is checked against source dir by task
+func isSourceURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	}
+
+	return false
+}
+
 type handlers struct {
 	usecase   businessConnService.IUsecase
 	logger    *logrus.Logger
 	taskQueue chan models.Task
+	// maxUploadSize limits body of /loadProduct, zero means without limit
+	maxUploadSize int64
+	// adminToken is required for admin routes, they are forbidden if it is empty
+	adminToken string
+	// limiter limits rate of requests, nil means without limit
+	limiter *middlewares.RateLimiter
+}
+
+// Config - settings of handlers
+type Config struct {
+	// MaxUploadSize limits body of /loadProduct, zero means without limit
+	MaxUploadSize int64
+	// AdminToken is required for admin routes, they are forbidden if it is empty
+	AdminToken string
+	// RateLimiter limits rate of requests to all routes, nil means without
+	// limit. Its limit can be changed while handlers serve requests
+	RateLimiter *middlewares.RateLimiter
 }
 
 // NewHandlers - create new handlers using gorilla router
-func NewHandlers(us businessConnService.IUsecase, taskQueue chan models.Task, logger *logrus.Logger) *mux.Router {
+func NewHandlers(us businessConnService.IUsecase, taskQueue chan models.Task, config Config,
+	logger *logrus.Logger) *mux.Router {
 	handlers := handlers{
-		usecase:   us,
-		taskQueue: taskQueue,
-		logger:    logger,
+		usecase:       us,
+		taskQueue:     taskQueue,
+		logger:        logger,
+		maxUploadSize: config.MaxUploadSize,
+		adminToken:    config.AdminToken,
+		limiter:       config.RateLimiter,
 	}
 
 	r := mux.NewRouter()
 
-	r.HandleFunc("/loadProduct",
-		middlewares.LogRequestMiddleware(handlers.logger, handlers.handleLoadProduct)).
-		Methods("POST")
-
-	r.HandleFunc("/getProduct",
-		middlewares.LogRequestMiddleware(handlers.logger, handlers.handleGetProducts)).
-		Methods("GET")
-
-	r.HandleFunc("/getTaskState/{task_id:[0-9]+}",
-		middlewares.LogRequestMiddleware(handlers.logger, handlers.handleGetTaskState)).
-		Methods("GET")
-
-	r.HandleFunc("/getTaskStats/{task_id:[0-9]+}",
-		middlewares.LogRequestMiddleware(handlers.logger, handlers.handleGetTaskStats)).
-		Methods("GET")
+	handlers.registerV1Routes(r.PathPrefix(apiV1Prefix).Subrouter())
+	handlers.registerLegacyRoutes(r)
+	handlers.registerDebugRoutes(r)
 
 	return r
 }
 
-// swagger:operation POST /loadProduct handleLoadProduct
+// withMiddlewares - wrap handler of route with tracing and logging middlewares
+func (h *handlers) withMiddlewares(route string, next http.HandlerFunc) http.HandlerFunc {
+	return middlewares.TracingMiddleware(route,
+		middlewares.LogRequestMiddleware(h.logger, h.limitRate(next)))
+}
+
+// swagger:operation POST /api/v1/sellers/{seller_id}/tasks handleLoadProduct
 //
-// Get sellerID and xlsx files and return task id
+// Get sellerID and xlsx files and return task id. Instead of uploading
+// file it can be passed as source_url, task downloads it with limit of
+// size and time, checks its checksum and processes it as uploaded file.
+// Task which can not fetch source is FAILED
 // ---
 // produces:
 // - multipart/form-data
 // parameters:
 // - name: seller_id
-//   in: formData
+//   in: path
 //   description: The seller_id needs to match customer id with products.
 //   required: true
-//   type: text
+//   type: integer
 // - name: products
 //   in: formData
-//   description: Files with products info.
-//   required: true
+//   description: Files with products info, they are not required with source_url.
+//   required: false
 //   type: file
+// - name: source_url
+//   in: formData
+//   description: Http(s) url of xlsx file or file url of file in sourceDir, it is fetched by task.
+//   required: false
+//   type: string
+// - name: checksum
+//   in: formData
+//   description: Checksum of file of source_url as sha256:<hex>, task fails if it does not match.
+//   required: false
+//   type: string
+// - name: dry_run
+//   in: formData
+//   description: Do not change products, only save preview of changes of task.
+//   required: false
+//   type: boolean
+// - name: currency
+//   in: formData
+//   description: Currency of prices in rows without currency column, RUB by default.
+//   required: false
+//   type: string
 // responses:
 //   200:
 //     description: successful operation
@@ -75,153 +154,330 @@ func NewHandlers(us businessConnService.IUsecase, taskQueue chan models.Task, lo
 //       task_id: string
 //       description: Return task id
 //   400:
-//       description: Invalid seller_id supplied
+//     description: Invalid seller_id, dry_run, currency, source_url, checksum or multipart form supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   413:
+//     description: Multipart form is larger than maxUploadSize
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
 //   500:
 //     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
 func (h *handlers) handleLoadProduct(w http.ResponseWriter, r *http.Request) {
-	sellerIDStr := r.FormValue("seller_id")
+	log := logging.FromContext(r.Context(), h.logger)
+
+	if h.maxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	}
+
+	// form without files is enough for task with source_url
+	if err := r.ParseMultipartForm(multipartMemory); err != nil &&
+		!(errors.Is(err, http.ErrNotMultipart) && r.FormValue("source_url") != "") {
+		log.WithError(err).Info("Failed to parse multipart form")
+
+		if isBodyTooLarge(err) {
+			h.writeError(w, r, http.StatusRequestEntityTooLarge, codeMultipartTooLarge,
+				"Multipart form is larger than "+strconv.FormatInt(h.maxUploadSize, 10)+" bytes")
+			return
+		}
+
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidMultipart, "Invalid multipart form")
+		return
+	}
+
+	// seller_id is part of path in api v1 and form field in legacy route
+	sellerIDStr, ok := mux.Vars(r)["seller_id"]
+	if !ok {
+		sellerIDStr = r.FormValue("seller_id")
+	}
 	if sellerIDStr == "" {
-		h.logger.Info("Empty sellerID")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondBadRequest(w, r, codeInvalidSellerID, "seller_id", "is required")
 		return
 	}
 
 	sellerIDInt, err := strconv.ParseInt(sellerIDStr, 10, 64)
-	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	if err != nil || sellerIDInt <= 0 {
+		h.respondBadRequest(w, r, codeInvalidSellerID, "seller_id", "must be positive integer")
 		return
 	}
 
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	log = log.WithField(logging.SellerIDField, sellerIDInt)
+
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			h.respondBadRequest(w, r, codeInvalidDryRun, "dry_run", "must be boolean")
+			return
+		}
+	}
+
+	currency := models.DefaultCurrency
+	if value := r.FormValue("currency"); value != "" {
+		if !tools.IsCurrency(value) {
+			h.respondBadRequest(w, r, codeInvalidCurrency, "currency", "must be ISO 4217 code")
+			return
+		}
+		currency = value
+	}
+
+	sourceURL := r.FormValue("source_url")
+	if sourceURL != "" && !isSourceURL(sourceURL) {
+		h.respondBadRequest(w, r, codeInvalidSourceURL, "source_url", "must be http(s) or file url")
 		return
 	}
 
-	taskID, err := h.usecase.CreateTask()
+	checksum := ""
+	if value := r.FormValue("checksum"); value != "" {
+		checksum = strings.TrimPrefix(strings.ToLower(value), checksumPrefix)
+		if sourceURL == "" || !checksumRegexp.MatchString(checksum) {
+			h.respondBadRequest(w, r, codeInvalidChecksum, "checksum", "must be sha256:<hex> of source_url")
+			return
+		}
+	}
+
+	var files map[string][]*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+
+	taskID, err := h.usecase.CreateTask(r.Context())
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondError(w, r, err)
 		return
 	}
 
+	log = log.WithField(logging.TaskIDField, taskID)
+
 	taskIDJSON, err := json.Marshal(taskID)
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondError(w, r, err)
 		return
 	}
 
 	task := models.Task{
-		TaskID:   taskID,
-		SellerID: sellerIDInt,
-		Files:    r.MultipartForm.File,
+		TaskID:       taskID,
+		SellerID:     sellerIDInt,
+		RequestID:    logging.RequestIDFromContext(r.Context()),
+		TraceCarrier: tracing.InjectCarrier(r.Context()),
+		Files:        files,
+		SourceURL:    sourceURL,
+		SourceSHA256: checksum,
+		Currency:     currency,
+		DryRun:       dryRun,
 	}
 
 	h.taskQueue <- task
 
+	log.WithFields(logrus.Fields{
+		"dry_run":     dryRun,
+		"with_source": sourceURL != "",
+	}).Info("Task queued")
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(taskIDJSON)
 }
 
-// swagger:operation GET /getProduct handleGetProducts
+// handleGetProducts - legacy /getProduct, get UserListRequest in body and
+// return xlsx file with all products that match with request data,
+// in api v1 it is replaced by GET /offers with query params
+func (h *handlers) handleGetProducts(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context(), h.logger)
+
+	userListRequest := new(models.UserListRequest)
+
+	if err := json.NewDecoder(r.Body).Decode(userListRequest); err != nil {
+		log.WithError(err).Info("Invalid userListRequest")
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be json UserListRequest")
+		return
+	}
+
+	products, err := h.usecase.SelectProductsBySpecificProductInfo(r.Context(), userListRequest)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.writeProductsXlsx(w, r, products)
+}
+
+// swagger:operation GET /api/v1/tasks/{task_id} handleGetTaskState
 //
-// Get UserListRequest and return xlsx file with all products
-// that match with request data
+// Get task id and return state
 // ---
+// summary: Get task state by task id
+// operationId: handleGetTaskState
 // produces:
 // - application/json
 // parameters:
-// - name: userListRequest
-//   in: json
-//   description: userListRequest may contain seller_id, offer_id and name.
-//   required: false
-//   type: #/definitions/UserListRequest
+// - name: task_id
+//   in: path
+//   required: true
+//   type: string
 // responses:
 //   200:
 //     description: successful operation
 //     schema:
-//       type: file
-//       description: Return xlsx file
+//       type: string
+//       state: string
+//       description: Return state
 //   400:
-//     description: Invalid userListRequest supplied
+//     description: Invalid taskID supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such task
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
 //   500:
 //     description: Sth went wrong
-func (h *handlers) handleGetProducts(w http.ResponseWriter, r *http.Request) {
-	userListRequest := new(models.UserListRequest)
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetTaskState(w http.ResponseWriter, r *http.Request) {
+	taskIDStr, ok := mux.Vars(r)["task_id"]
+	if !ok {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "is required")
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(userListRequest); err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Info("BadRequest")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "must be integer")
 		return
 	}
 
-	products, err := h.usecase.SelectProductsBySpecificProductInfo(userListRequest)
-	switch {
-	case err == sql.ErrNoRows:
-		h.logger.WithField("ErrInfo", err.Error()).Info("No such products")
-		http.Error(w, "No such products", http.StatusBadRequest)
+	taskState, err := h.usecase.SelectTaskState(r.Context(), taskID)
+	if err != nil {
+		h.respondError(w, r, err)
 		return
-	case err != nil:
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	taskStateJSON, err := json.Marshal(taskState)
+	if err != nil {
+		h.respondError(w, r, err)
 		return
 	}
 
-	f := excelize.NewFile()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(taskStateJSON)
+}
 
-	for i, product := range products {
-		counterStr := strconv.Itoa(i + 1)
+// swagger:operation GET /api/v1/tasks/{task_id}/stats handleGetTaskStats
+//
+// Get task id and return stats
+// ---
+// summary: Get stats by task id
+// operationId: handleGetTaskStats
+// produces:
+// - application/json
+// parameters:
+// - name: task_id
+//   in: path
+//   required: true
+//   type: string
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       $ref: '#/definitions/TaskStats'
+//   400:
+//     description: Invalid taskID supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such task
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetTaskStats(w http.ResponseWriter, r *http.Request) {
+	taskIDStr, ok := mux.Vars(r)["task_id"]
+	if !ok {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "is required")
+		return
+	}
 
-		err = f.SetCellValue("Sheet1", "A"+counterStr, product.OfferID)
-		if err != nil {
-			h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "must be integer")
+		return
+	}
 
-		err = f.SetCellValue("Sheet1", "B"+counterStr, product.Name)
-		if err != nil {
-			h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+	stats, err := h.usecase.SelectTaskStatsByTaskID(r.Context(), taskID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
 
-		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price)
-		if err != nil {
-			h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+	statsJSON, err := json.Marshal(stats)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
 
-		err = f.SetCellValue("Sheet1", "D"+counterStr, product.Quantity)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statsJSON)
+}
+
+// writeProductsXlsx - write products as xlsx file, every product is one row
+// with columns in order of tools.XlsxColumns, so file can be uploaded back
+func (h *handlers) writeProductsXlsx(w http.ResponseWriter, r *http.Request, products []*models.ProductInfo) {
+	f := excelize.NewFile()
+
+	for i, product := range products {
+		row, err := tools.ConvertProductInfoToXlsxRow(product)
 		if err != nil {
-			h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.respondError(w, r, err)
 			return
 		}
 
-		err = f.SetCellValue("Sheet1", "D"+counterStr, product.Available)
-		if err != nil {
-			h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+		for j, value := range row {
+			axis, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				h.respondError(w, r, err)
+				return
+			}
+
+			switch value := value.(type) {
+			case nil:
+				continue
+			case string:
+				if value == "" {
+					continue
+				}
+				err = f.SetCellStr("Sheet1", axis, value)
+			case decimal.Decimal:
+				// decimal is written as number with exact value instead of float
+				err = f.SetCellDefault("Sheet1", axis, value.String())
+			default:
+				err = f.SetCellValue("Sheet1", axis, value)
+			}
+			if err != nil {
+				h.respondError(w, r, err)
+				return
+			}
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Type", xlsxContentType)
 	f.Write(w)
 }
 
-// swagger:operation GET /getTaskState/{task_id} handleGetTaskState
+// swagger:operation GET /api/v1/tasks/{task_id}/preview handleGetTaskPreview
 //
-// Get task id and return state
+// Get what dry run task would do with every row of uploaded files: create,
+// update with changed fields, delete, skip or error. Preview is returned
+// as xlsx file if it is asked in Accept header
 // ---
-// summary: Get task state by task id
-// operationId: handleGetTaskState
+// summary: Get preview of dry run task
+// operationId: handleGetTaskPreview
 // produces:
 // - application/json
+// - application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // parameters:
 // - name: task_id
 //   in: path
@@ -231,57 +487,61 @@ func (h *handlers) handleGetProducts(w http.ResponseWriter, r *http.Request) {
 //   200:
 //     description: successful operation
 //     schema:
-//       type: string
-//       state: string
-//       description: Return state
+//       type: array
+//       items:
+//         $ref: '#/definitions/PreviewItem'
 //   400:
 //     description: Invalid taskID supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No preview of task, task is not dry run or not done
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
 //   500:
 //     description: Sth went wrong
-func (h *handlers) handleGetTaskState(w http.ResponseWriter, r *http.Request) {
-	taskIDStr, ok := mux.Vars(r)["task_id"]
-	if !ok {
-		h.logger.WithField("TaskID", taskIDStr).Info("BadRequest")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetTaskPreview(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(mux.Vars(r)["task_id"], 10, 64)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "must be integer")
 		return
 	}
 
-	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	preview, err := h.usecase.SelectTaskPreview(r.Context(), taskID)
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondError(w, r, err)
 		return
 	}
 
-	taskState, err := h.usecase.SelectTaskState(taskID)
-	switch {
-	case err == sql.ErrNoRows:
-		h.logger.WithField("TaskID", taskID).Info("BadRequest no such task")
-		http.Error(w, "No such task", http.StatusBadRequest)
+	if len(preview) == 0 {
+		h.respondError(w, r, domainErrors.NotFound("task preview not found"))
 		return
-	case err != nil:
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), xlsxContentType) {
+		h.writePreviewXlsx(w, r, preview)
 		return
 	}
 
-	taskStateJSON, err := json.Marshal(taskState)
+	previewJSON, err := json.Marshal(preview)
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(taskStateJSON)
+	w.Write(previewJSON)
 }
 
-// swagger:operation GET /getTaskStats/{task_id} handleGetTaskStats
+// swagger:operation GET /api/v1/tasks/{task_id}/errors handleGetTaskErrors
 //
-// Get task id and return stats
+// Get errors of rows which were not uploaded by batch task, row is number
+// of product in batch. Task without errors has empty list
 // ---
-// summary: Get stats by task id
-// operationId: handleGetTaskStats
+// summary: Get errors of rows of batch task
+// operationId: handleGetTaskErrors
 // produces:
 // - application/json
 // parameters:
@@ -293,45 +553,153 @@ func (h *handlers) handleGetTaskState(w http.ResponseWriter, r *http.Request) {
 //   200:
 //     description: successful operation
 //     schema:
-//       $ref: '#/definitions/TaskStats'
+//       type: array
+//       items:
+//         $ref: '#/definitions/RowError'
 //   400:
 //     description: Invalid taskID supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
 //   500:
 //     description: Sth went wrong
-func (h *handlers) handleGetTaskStats(w http.ResponseWriter, r *http.Request) {
-	taskIDStr, ok := mux.Vars(r)["task_id"]
-	if !ok {
-		h.logger.WithField("TaskID", taskIDStr).Info("BadRequest")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetTaskErrors(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.ParseInt(mux.Vars(r)["task_id"], 10, 64)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "must be integer")
 		return
 	}
 
-	taskID, err := strconv.ParseInt(taskIDStr, 10, 64)
+	rowErrors, err := h.usecase.SelectTaskErrors(r.Context(), taskID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	rowErrorsJSON, err := json.Marshal(rowErrors)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(rowErrorsJSON)
+}
+
+// swagger:operation POST /api/v1/tasks/{task_id}/rollback handleRollbackTask
+//
+// Revert all creates, updates and deletes of products made by finished task.
+// Rollback is done by new task, its id is returned and its state and stats
+// are available as for upload. Products changed after task are not reverted
+// and counted in rows_with_errors of stats
+// ---
+// summary: Rollback task
+// operationId: handleRollbackTask
+// produces:
+// - application/json
+// parameters:
+// - name: task_id
+//   in: path
+//   required: true
+//   type: string
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       type: string
+//       task_id: string
+//       description: Return id of rollback task
+//   400:
+//     description: Invalid taskID supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such task
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   409:
+//     description: Task is not finished
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleRollbackTask(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context(), h.logger)
+
+	taskID, err := strconv.ParseInt(mux.Vars(r)["task_id"], 10, 64)
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondBadRequest(w, r, codeInvalidTaskID, "task_id", "must be integer")
 		return
 	}
 
-	stats, err := h.usecase.SelectTaskStatsByTaskID(taskID)
-	switch {
-	case err == sql.ErrNoRows:
-		h.logger.WithField("TaskID", taskID).Info("BadRequest no such task")
-		http.Error(w, "No such task", http.StatusBadRequest)
+	taskState, err := h.usecase.SelectTaskState(r.Context(), taskID)
+	if err != nil {
+		h.respondError(w, r, err)
 		return
-	case err != nil:
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	// changes of running task can not be reverted yet
+	if taskState.State != "DONE" && taskState.State != "CANCELLED" {
+		h.respondError(w, r, domainErrors.Conflict("Task is not finished, its state is "+taskState.State))
 		return
 	}
 
-	statsJSON, err := json.Marshal(stats)
+	rollbackTaskID, err := h.usecase.CreateTask(r.Context())
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	rollbackTaskIDJSON, err := json.Marshal(rollbackTaskID)
 	if err != nil {
-		h.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondError(w, r, err)
 		return
 	}
 
+	h.taskQueue <- models.Task{
+		TaskID:         rollbackTaskID,
+		RequestID:      logging.RequestIDFromContext(r.Context()),
+		TraceCarrier:   tracing.InjectCarrier(r.Context()),
+		RollbackTaskID: taskID,
+	}
+
+	log.WithFields(logrus.Fields{
+		logging.TaskIDField:         rollbackTaskID,
+		logging.RollbackTaskIDField: taskID,
+	}).Info("Rollback task queued")
+
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(statsJSON)
+	w.Write(rollbackTaskIDJSON)
+}
+
+// writePreviewXlsx - write preview as xlsx file with header, every item is
+// one row, changes of fields are written as "field: old -> new"
+func (h *handlers) writePreviewXlsx(w http.ResponseWriter, r *http.Request, preview []*models.PreviewItem) {
+	f := excelize.NewFile()
+
+	header := []interface{}{"file", "sheet", "row", "offer_id", "action", "changes", "error"}
+	if err := f.SetSheetRow("Sheet1", "A1", &header); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	for i, item := range preview {
+		changes := []string{}
+		for _, change := range item.Changes {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", change.Field, change.Old, change.New))
+		}
+
+		row := []interface{}{item.File, item.Sheet, item.Row, item.OfferID, item.Action,
+			strings.Join(changes, "; "), item.Error}
+		if err := f.SetSheetRow("Sheet1", "A"+strconv.Itoa(i+2), &row); err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", xlsxContentType)
+	f.Write(w)
 }
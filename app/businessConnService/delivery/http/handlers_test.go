@@ -2,20 +2,22 @@ package http
 
 import (
 	"bytes"
-	"database/sql"
 	"errors"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -33,7 +35,7 @@ func TestHandleGetTaskState(t *testing.T) {
 		State:  "IN PROGRESS",
 	}
 
-	usecase.EXPECT().SelectTaskState(expectedData.TaskID).Return(expectedData, nil)
+	usecase.EXPECT().SelectTaskState(gomock.Any(), expectedData.TaskID).Return(expectedData, nil)
 
 	outputJSON := `{"task_id":1,"state":"IN PROGRESS"}`
 
@@ -84,10 +86,13 @@ func TestHandleGetTaskState(t *testing.T) {
 	handlers.handleGetTaskState(responseBadRequestIncorrectQuery, badRequestIncorrectQuery)
 
 	assert.Equal(t, http.StatusBadRequest, responseBadRequestIncorrectQuery.Code)
+	assert.Equal(t, `{"code":"invalid_task_id","message":"Invalid task_id",`+
+		`"details":[{"field":"task_id","message":"must be integer"}]}`,
+		responseBadRequestIncorrectQuery.Body.String())
 
 	// test DB return error
 
-	usecase.EXPECT().SelectTaskState(expectedData.TaskID).Return(expectedData, errors.New("DB error"))
+	usecase.EXPECT().SelectTaskState(gomock.Any(), expectedData.TaskID).Return(expectedData, errors.New("DB error"))
 
 	requestDBError := httptest.NewRequest(http.MethodGet, "/getTaskState/", nil)
 
@@ -103,9 +108,9 @@ func TestHandleGetTaskState(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, responseDBError.Code)
 
-	// test DB return sql.NoRows (Bad Request)
+	// test DB return not found error (Not Found)
 
-	usecase.EXPECT().SelectTaskState(expectedData.TaskID).Return(expectedData, sql.ErrNoRows)
+	usecase.EXPECT().SelectTaskState(gomock.Any(), expectedData.TaskID).Return(nil, domainErrors.NotFound("not found"))
 
 	requestDBNoRows := httptest.NewRequest(http.MethodGet, "/getTaskState/", nil)
 
@@ -119,7 +124,8 @@ func TestHandleGetTaskState(t *testing.T) {
 
 	handlers.handleGetTaskState(responseDBNoRows, requestDBNoRows)
 
-	assert.Equal(t, http.StatusBadRequest, responseDBNoRows.Code)
+	assert.Equal(t, http.StatusNotFound, responseDBNoRows.Code)
+	assert.Equal(t, `{"code":"not_found","message":"not found"}`, responseDBNoRows.Body.String())
 }
 
 func TestHandleGetTaskStats(t *testing.T) {
@@ -138,7 +144,7 @@ func TestHandleGetTaskStats(t *testing.T) {
 		RowsWithErrors:  0,
 	}
 
-	usecase.EXPECT().SelectTaskStatsByTaskID(expectedData.TaskID).Return(expectedData, nil)
+	usecase.EXPECT().SelectTaskStatsByTaskID(gomock.Any(), expectedData.TaskID).Return(expectedData, nil)
 
 	outputJSON := `{"task_id":1,"products_created":5,"products_updated":2,"products_deleted":1,"rows_with_errors":0}`
 
@@ -192,7 +198,7 @@ func TestHandleGetTaskStats(t *testing.T) {
 
 	// test DB return error
 
-	usecase.EXPECT().SelectTaskStatsByTaskID(expectedData.TaskID).Return(expectedData, errors.New("DB error"))
+	usecase.EXPECT().SelectTaskStatsByTaskID(gomock.Any(), expectedData.TaskID).Return(expectedData, errors.New("DB error"))
 
 	requestDBError := httptest.NewRequest(http.MethodGet, "/getTaskStats/", nil)
 
@@ -208,9 +214,9 @@ func TestHandleGetTaskStats(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, responseDBError.Code)
 
-	// test DB return sql.NoRows (Bad Request)
+	// test DB return not found error (Not Found)
 
-	usecase.EXPECT().SelectTaskStatsByTaskID(expectedData.TaskID).Return(expectedData, sql.ErrNoRows)
+	usecase.EXPECT().SelectTaskStatsByTaskID(gomock.Any(), expectedData.TaskID).Return(nil, domainErrors.NotFound("not found"))
 
 	requestDBNoRows := httptest.NewRequest(http.MethodGet, "/getTaskStats/", nil)
 
@@ -224,7 +230,8 @@ func TestHandleGetTaskStats(t *testing.T) {
 
 	handlers.handleGetTaskStats(responseDBNoRows, requestDBNoRows)
 
-	assert.Equal(t, http.StatusBadRequest, responseDBNoRows.Code)
+	assert.Equal(t, http.StatusNotFound, responseDBNoRows.Code)
+	assert.Equal(t, `{"code":"not_found","message":"not found"}`, responseDBNoRows.Body.String())
 
 }
 
@@ -249,7 +256,8 @@ func TestHandleGetProducts(t *testing.T) {
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -257,13 +265,14 @@ func TestHandleGetProducts(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
 	}
 
-	usecase.EXPECT().SelectProductsBySpecificProductInfo(inputData).Return(expectedData, nil)
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), inputData).Return(expectedData, nil)
 
 	handlers := &handlers{
 		usecase:   usecase,
@@ -291,7 +300,7 @@ func TestHandleGetProducts(t *testing.T) {
 
 	// test DB return error
 
-	usecase.EXPECT().SelectProductsBySpecificProductInfo(inputData).Return(expectedData, errors.New("DB error"))
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), inputData).Return(expectedData, errors.New("DB error"))
 
 	requestDBError := httptest.NewRequest(http.MethodGet, "/getProduct", strings.NewReader(inputDataJSON))
 
@@ -301,9 +310,9 @@ func TestHandleGetProducts(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, responseDBError.Code)
 
-	// test DB return sql.NoRows (Bad Request)
+	// test DB return not found error (Not Found)
 
-	usecase.EXPECT().SelectProductsBySpecificProductInfo(inputData).Return(expectedData, sql.ErrNoRows)
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), inputData).Return(nil, domainErrors.NotFound("not found"))
 
 	requestDBNoRows := httptest.NewRequest(http.MethodGet, "/getProduct", strings.NewReader(inputDataJSON))
 
@@ -311,7 +320,8 @@ func TestHandleGetProducts(t *testing.T) {
 
 	handlers.handleGetProducts(responseDBNoRows, requestDBNoRows)
 
-	assert.Equal(t, http.StatusBadRequest, responseDBNoRows.Code)
+	assert.Equal(t, http.StatusNotFound, responseDBNoRows.Code)
+	assert.Equal(t, `{"code":"not_found","message":"not found"}`, responseDBNoRows.Body.String())
 }
 
 func TestHandleLoadProduct(t *testing.T) {
@@ -324,7 +334,7 @@ func TestHandleLoadProduct(t *testing.T) {
 
 	testTaskID := int64(1)
 
-	usecase.EXPECT().CreateTask().Return(testTaskID, nil)
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(testTaskID, nil)
 
 	outputJSON := "1"
 
@@ -333,7 +343,8 @@ func TestHandleLoadProduct(t *testing.T) {
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -341,7 +352,8 @@ func TestHandleLoadProduct(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
@@ -358,7 +370,7 @@ func TestHandleLoadProduct(t *testing.T) {
 		err = f.SetCellValue("Sheet1", "B"+counterStr, product.Name)
 		assert.NoError(t, err)
 
-		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price)
+		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price.String())
 		assert.NoError(t, err)
 
 		err = f.SetCellValue("Sheet1", "D"+counterStr, product.Quantity)
@@ -427,7 +439,8 @@ func TestHandleLoadProductIncorrectData(t *testing.T) {
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -435,7 +448,8 @@ func TestHandleLoadProductIncorrectData(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
@@ -452,7 +466,7 @@ func TestHandleLoadProductIncorrectData(t *testing.T) {
 		err = f.SetCellValue("Sheet1", "B"+counterStr, product.Name)
 		assert.NoError(t, err)
 
-		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price)
+		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price.String())
 		assert.NoError(t, err)
 
 		err = f.SetCellValue("Sheet1", "D"+counterStr, product.Quantity)
@@ -492,6 +506,7 @@ func TestHandleLoadProductIncorrectData(t *testing.T) {
 	handlers.handleLoadProduct(response, request)
 
 	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_seller_id"`)
 }
 
 func TestHandleLoadProductDBError(t *testing.T) {
@@ -502,14 +517,15 @@ func TestHandleLoadProductDBError(t *testing.T) {
 
 	testTaskID := int64(1)
 
-	usecase.EXPECT().CreateTask().Return(testTaskID, errors.New("DB error"))
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(testTaskID, errors.New("DB error"))
 
 	loadData := []*models.ProductInfo{
 		{
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -517,7 +533,8 @@ func TestHandleLoadProductDBError(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
@@ -534,7 +551,7 @@ func TestHandleLoadProductDBError(t *testing.T) {
 		err = f.SetCellValue("Sheet1", "B"+counterStr, product.Name)
 		assert.NoError(t, err)
 
-		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price)
+		err = f.SetCellValue("Sheet1", "C"+counterStr, product.Price.String())
 		assert.NoError(t, err)
 
 		err = f.SetCellValue("Sheet1", "D"+counterStr, product.Quantity)
@@ -575,3 +592,359 @@ func TestHandleLoadProductDBError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, response.Code)
 }
+
+func TestHandleLoadProductTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	body := &bytes.Buffer{}
+
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("products", "testFile.xlsx")
+	assert.NoError(t, err)
+
+	_, err = part.Write(bytes.Repeat([]byte("a"), 1024))
+	assert.NoError(t, err)
+
+	err = writer.WriteField("seller_id", "1")
+	assert.NoError(t, err)
+
+	err = writer.Close()
+	assert.NoError(t, err)
+
+	handlers := &handlers{
+		usecase:       usecase,
+		taskQueue:     make(chan models.Task),
+		logger:        logrus.New(),
+		maxUploadSize: 512,
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/loadProduct", body)
+	request.Header.Add("Content-Type", writer.FormDataContentType())
+
+	response := httptest.NewRecorder()
+
+	handlers.handleLoadProduct(response, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"multipart_too_large"`)
+}
+
+func TestHandleRollbackTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	taskQueue := make(chan models.Task, 1)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: taskQueue,
+		logger:    logrus.New(),
+	}
+
+	newRequest := func(taskID string) *http.Request {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+taskID+"/rollback", nil)
+		return mux.SetURLVars(request, map[string]string{"task_id": taskID})
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(1)).Return(&models.TaskState{TaskID: 1, State: "DONE"}, nil)
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(2), nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleRollbackTask(response, newRequest("1"))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, "2", response.Body.String())
+
+		task := <-taskQueue
+		assert.Equal(t, int64(2), task.TaskID)
+		assert.Equal(t, int64(1), task.RollbackTaskID)
+	}
+
+	// test task is not finished
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(1)).
+		Return(&models.TaskState{TaskID: 1, State: "IN PROGRESS"}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleRollbackTask(response, newRequest("1"))
+
+	assert.Equal(t, http.StatusConflict, response.Code)
+
+	// test task does not exist
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(3)).Return(nil, domainErrors.NotFound("task not found"))
+
+	response = httptest.NewRecorder()
+	handlers.handleRollbackTask(response, newRequest("3"))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleLoadProductDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	taskQueue := make(chan models.Task, 1)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: taskQueue,
+		logger:    logrus.New(),
+	}
+
+	newRequest := func(dryRun string) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+
+		part, err := writer.CreateFormFile("products", "testFile.xlsx")
+		assert.NoError(t, err)
+
+		err = excelize.NewFile().Write(part)
+		assert.NoError(t, err)
+
+		err = writer.WriteField("seller_id", "1")
+		assert.NoError(t, err)
+
+		err = writer.WriteField("dry_run", dryRun)
+		assert.NoError(t, err)
+
+		err = writer.Close()
+		assert.NoError(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/loadProduct", body)
+		request.Header.Add("Content-Type", writer.FormDataContentType())
+
+		return request
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(1), nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleLoadProduct(response, newRequest("true"))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		task := <-taskQueue
+		assert.True(t, task.DryRun)
+		assert.Equal(t, models.DefaultCurrency, task.Currency)
+	}
+
+	// test invalid dry_run
+
+	response = httptest.NewRecorder()
+	handlers.handleLoadProduct(response, newRequest("maybe"))
+
+	if assert.Equal(t, http.StatusBadRequest, response.Code) {
+		assert.Contains(t, response.Body.String(), codeInvalidDryRun)
+	}
+}
+
+func TestHandleLoadProductSourceURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	taskQueue := make(chan models.Task, 1)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: taskQueue,
+		logger:    logrus.New(),
+	}
+
+	checksum := strings.Repeat("ab", 32)
+
+	newRequest := func(form url.Values) *http.Request {
+		form.Set("seller_id", "1")
+
+		request := httptest.NewRequest(http.MethodPost, "/loadProduct", strings.NewReader(form.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		return request
+	}
+
+	// test source_url is passed to task without multipart form
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(1), nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleLoadProduct(response, newRequest(url.Values{
+		"source_url": {"https://example.com/products.xlsx"},
+		"checksum":   {"SHA256:" + strings.ToUpper(checksum)},
+	}))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		task := <-taskQueue
+		assert.Equal(t, "https://example.com/products.xlsx", task.SourceURL)
+		assert.Equal(t, checksum, task.SourceSHA256)
+		assert.Nil(t, task.Files)
+	}
+
+	// test invalid source
+
+	for _, testCase := range []struct {
+		form url.Values
+		code string
+	}{
+		{url.Values{}, codeInvalidMultipart},
+		{url.Values{"source_url": {"ftp://example.com/products.xlsx"}}, codeInvalidSourceURL},
+		{url.Values{"source_url": {"https:///products.xlsx"}}, codeInvalidSourceURL},
+		{url.Values{"source_url": {"file:///data/products.xlsx"}, "checksum": {"md5:abc"}}, codeInvalidChecksum},
+	} {
+		response := httptest.NewRecorder()
+		handlers.handleLoadProduct(response, newRequest(testCase.form))
+
+		if assert.Equal(t, http.StatusBadRequest, response.Code) {
+			assert.Contains(t, response.Body.String(), testCase.code)
+		}
+	}
+}
+
+func TestHandleGetTaskPreview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	newRequest := func(taskID string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/preview", nil)
+		return mux.SetURLVars(request, map[string]string{"task_id": taskID})
+	}
+
+	preview := []*models.PreviewItem{
+		{
+			TaskID:   1,
+			SellerID: 1,
+			OfferID:  1,
+			Action:   models.ProductUpdated,
+			Changes:  []models.FieldChange{{Field: "price", Old: 10.5, New: 12.0}},
+			File:     "testFile.xlsx",
+			Sheet:    "Sheet1",
+			Row:      1,
+		},
+		{
+			TaskID:   1,
+			SellerID: 1,
+			Action:   models.PreviewError,
+			Error:    "invalid offer_id",
+			File:     "testFile.xlsx",
+			Sheet:    "Sheet1",
+			Row:      2,
+		},
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectTaskPreview(gomock.Any(), int64(1)).Return(preview, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleGetTaskPreview(response, newRequest("1"))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Contains(t, response.Body.String(), `"action":"UPDATE"`)
+		assert.Contains(t, response.Body.String(), `"error":"invalid offer_id"`)
+	}
+
+	// test xlsx
+
+	usecase.EXPECT().SelectTaskPreview(gomock.Any(), int64(1)).Return(preview, nil)
+
+	request := newRequest("1")
+	request.Header.Set("Accept", xlsxContentType)
+
+	response = httptest.NewRecorder()
+	handlers.handleGetTaskPreview(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		f, err := excelize.OpenReader(response.Body)
+		if assert.NoError(t, err) {
+			changes, err := f.GetCellValue("Sheet1", "F2")
+			assert.NoError(t, err)
+			assert.Equal(t, "price: 10.5 -> 12", changes)
+		}
+	}
+
+	// test task without preview
+
+	usecase.EXPECT().SelectTaskPreview(gomock.Any(), int64(2)).Return([]*models.PreviewItem{}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleGetTaskPreview(response, newRequest("2"))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleGetTaskErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	newRequest := func(taskID string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/errors", nil)
+		return mux.SetURLVars(request, map[string]string{"task_id": taskID})
+	}
+
+	rowErrors := []models.RowError{
+		{
+			Row:     2,
+			OfferID: 7,
+			Error:   "invalid fields",
+			Details: []models.ErrorDetail{{Field: "price", Message: "must be positive"}},
+		},
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectTaskErrors(gomock.Any(), int64(1)).Return(rowErrors, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleGetTaskErrors(response, newRequest("1"))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Contains(t, response.Body.String(), `"offer_id":7`)
+		assert.Contains(t, response.Body.String(), `"field":"price"`)
+	}
+
+	// test task without errors
+
+	usecase.EXPECT().SelectTaskErrors(gomock.Any(), int64(2)).Return([]models.RowError{}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleGetTaskErrors(response, newRequest("2"))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, "[]", response.Body.String())
+	}
+
+	// test invalid task id
+
+	response = httptest.NewRecorder()
+	handlers.handleGetTaskErrors(response, newRequest("abc"))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
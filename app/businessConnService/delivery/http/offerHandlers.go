@@ -0,0 +1,956 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// etag - build ETag of product from its version
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch - get expected version of product from If-Match header,
+// zero is returned when header is absent or matches any version
+func parseIfMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	header = strings.TrimPrefix(header, "W/")
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, domainErrors.Validation("If-Match must be ETag of product")
+	}
+
+	return version, nil
+}
+
+// parseOfferVars - get seller_id and offer_id from path of request, if
+// they are invalid error response is written and false is returned
+func (h *handlers) parseOfferVars(w http.ResponseWriter, r *http.Request, withOffer bool) (int64, int64, bool) {
+	vars := mux.Vars(r)
+
+	sellerID, err := strconv.ParseInt(vars["seller_id"], 10, 64)
+	if err != nil || sellerID <= 0 {
+		h.respondBadRequest(w, r, codeInvalidSellerID, "seller_id", "must be positive integer")
+		return 0, 0, false
+	}
+
+	if !withOffer {
+		return sellerID, 0, true
+	}
+
+	offerID, err := strconv.ParseInt(vars["offer_id"], 10, 64)
+	if err != nil || offerID <= 0 {
+		h.respondBadRequest(w, r, codeInvalidOfferID, "offer_id", "must be positive integer")
+		return 0, 0, false
+	}
+
+	return sellerID, offerID, true
+}
+
+// selectOffer - select product which is not deleted, deleted product is
+// not found until it is restored
+func (h *handlers) selectOffer(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	product, err := h.usecase.SelectProduct(ctx, sellerID, offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.DeletedAt != nil {
+		return nil, domainErrors.NotFound("product not found")
+	}
+
+	return product, nil
+}
+
+// versionMismatch - explain why conditional update or delete changed nothing:
+// product does not exist, is deleted or has other version
+func (h *handlers) versionMismatch(ctx context.Context, sellerID, offerID int64) error {
+	if _, err := h.selectOffer(ctx, sellerID, offerID); err != nil {
+		return err
+	}
+
+	return domainErrors.PreconditionFailed("Product was changed, its version does not match If-Match")
+}
+
+// writeProduct - write product as json with its ETag
+func (h *handlers) writeProduct(w http.ResponseWriter, r *http.Request, status int, product *models.ProductInfo) {
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(product.Version))
+	w.WriteHeader(status)
+	w.Write(productJSON)
+}
+
+// parseQueryID - get optional positive id from query params of request,
+// zero is returned when param is absent
+func parseQueryID(r *http.Request, name string) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, domainErrors.Validation("must be positive integer")
+	}
+
+	return id, nil
+}
+
+// parseQueryPrice - get optional decimal price from query params of request,
+// nil is returned when param is absent
+func parseQueryPrice(r *http.Request, name string) (*decimal.Decimal, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, domainErrors.Validation("must be decimal")
+	}
+
+	return &price, nil
+}
+
+// parseQueryIDs - get optional list of positive ids from query params of
+// request, ids are comma separated and param can be repeated, nil is
+// returned when param is absent
+func parseQueryIDs(r *http.Request, name string) ([]int64, error) {
+	var ids []int64
+
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+			if err != nil || id <= 0 {
+				return nil, domainErrors.Validation("must be comma separated positive integers")
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// listOffersParams - query params of search of products, they are shared by
+// handleListOffers and handleListSellerOffers
+// swagger:parameters handleListOffers handleListSellerOffers
+type listOffersParams struct {
+	// in: query
+	OfferID int64 `json:"offer_id"`
+	// Comma separated ids of sellers, products of any of them are found
+	// in: query
+	// collectionFormat: csv
+	SellerIDs []int64 `json:"seller_ids"`
+	// Comma separated ids of offers, products with any of them are found
+	// in: query
+	// collectionFormat: csv
+	OfferIDs []int64 `json:"offer_ids"`
+	// Comma separated ids of sellers whose products are skipped
+	// in: query
+	// collectionFormat: csv
+	ExcludeSellerIDs []int64 `json:"exclude_seller_ids"`
+	// Comma separated ids of offers which are skipped
+	// in: query
+	// collectionFormat: csv
+	ExcludeOfferIDs []int64 `json:"exclude_offer_ids"`
+	// Substring of name of product
+	// in: query
+	Name string `json:"name"`
+	// in: query
+	SKU string `json:"sku"`
+	// Category path, products of subcategories are found too
+	// in: query
+	Category string `json:"category"`
+	// in: query
+	Brand string `json:"brand"`
+	// Return deleted products which are not purged yet
+	// in: query
+	IncludeDeleted bool `json:"include_deleted"`
+	// Convert prices to currency by exchange rates, converted price is returned in converted_price
+	// in: query
+	Currency string `json:"currency"`
+	// Min price, converted price is compared if currency is set
+	// in: query
+	MinPrice *decimal.Decimal `json:"min_price"`
+	// Max price, converted price is compared if currency is set
+	// in: query
+	MaxPrice *decimal.Decimal `json:"max_price"`
+	// Sort by price ascending (price) or descending (-price)
+	// in: query
+	// enum: price,-price
+	Sort string `json:"sort"`
+}
+
+// swagger:operation GET /api/v1/offers handleListOffers
+//
+// Search products by seller_id, offer_id, substring of name, sku, category
+// and brand, the same search is available for one seller on
+// /api/v1/sellers/{seller_id}/offers.
+// Lists of ids (at most 1000 ids in every list) find products with any of
+// ids, exclude lists skip products with any of ids.
+// Deleted products are skipped unless include_deleted is set.
+// Products can be filtered and sorted by price normalised to currency.
+// Products are returned as xlsx file if it is asked in Accept header
+// ---
+// summary: Search products
+// operationId: handleListOffers
+// produces:
+// - application/json
+// - application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// parameters:
+// - name: seller_id
+//   in: query
+//   required: false
+//   type: integer
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       type: array
+//       items:
+//         $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid seller_id, offer_id, lists of ids, include_deleted, price, sort or currency supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleListOffers(w http.ResponseWriter, r *http.Request) {
+	sellerID, err := parseQueryID(r, "seller_id")
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidSellerID, "seller_id", domainErrors.Message(err))
+		return
+	}
+
+	h.listOffers(w, r, sellerID)
+}
+
+// swagger:operation GET /api/v1/sellers/{seller_id}/offers handleListSellerOffers
+//
+// Search products of seller, query params and results are the same as of
+// /api/v1/offers
+// ---
+// summary: Search products of seller
+// operationId: handleListSellerOffers
+// produces:
+// - application/json
+// - application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       type: array
+//       items:
+//         $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid seller_id, offer_id, lists of ids, include_deleted, price, sort or currency supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleListSellerOffers(w http.ResponseWriter, r *http.Request) {
+	sellerID, _, ok := h.parseOfferVars(w, r, false)
+	if !ok {
+		return
+	}
+
+	h.listOffers(w, r, sellerID)
+}
+
+// listOffers - search products by query params of request, zero sellerID
+// means products of all sellers
+func (h *handlers) listOffers(w http.ResponseWriter, r *http.Request, sellerID int64) {
+	userListRequest := &models.UserListRequest{
+		SellerID: sellerID,
+		Name:     r.URL.Query().Get("name"),
+		SKU:      r.URL.Query().Get("sku"),
+		Category: r.URL.Query().Get("category"),
+		Brand:    r.URL.Query().Get("brand"),
+	}
+
+	offerID, err := parseQueryID(r, "offer_id")
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidOfferID, "offer_id", domainErrors.Message(err))
+		return
+	}
+	userListRequest.OfferID = offerID
+
+	for _, list := range []struct {
+		name string
+		code string
+		ids  *[]int64
+	}{
+		{"seller_ids", codeInvalidSellerID, &userListRequest.SellerIDs},
+		{"offer_ids", codeInvalidOfferID, &userListRequest.OfferIDs},
+		{"exclude_seller_ids", codeInvalidSellerID, &userListRequest.ExcludeSellerIDs},
+		{"exclude_offer_ids", codeInvalidOfferID, &userListRequest.ExcludeOfferIDs},
+	} {
+		if *list.ids, err = parseQueryIDs(r, list.name); err != nil {
+			h.respondBadRequest(w, r, list.code, list.name, domainErrors.Message(err))
+			return
+		}
+	}
+
+	if value := r.URL.Query().Get("include_deleted"); value != "" {
+		includeDeleted, err := strconv.ParseBool(value)
+		if err != nil {
+			h.respondBadRequest(w, r, codeInvalidIncludeDeleted, "include_deleted", "must be boolean")
+			return
+		}
+		userListRequest.IncludeDeleted = includeDeleted
+	}
+
+	userListRequest.Currency = r.URL.Query().Get("currency")
+	userListRequest.Sort = r.URL.Query().Get("sort")
+
+	if userListRequest.MinPrice, err = parseQueryPrice(r, "min_price"); err != nil {
+		h.respondBadRequest(w, r, codeInvalidPrice, "min_price", domainErrors.Message(err))
+		return
+	}
+
+	if userListRequest.MaxPrice, err = parseQueryPrice(r, "max_price"); err != nil {
+		h.respondBadRequest(w, r, codeInvalidPrice, "max_price", domainErrors.Message(err))
+		return
+	}
+
+	products, err := h.usecase.SelectProductsBySpecificProductInfo(r.Context(), userListRequest)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), xlsxContentType) {
+		h.writeProductsXlsx(w, r, products)
+		return
+	}
+
+	productsJSON, err := json.Marshal(products)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(productsJSON)
+}
+
+// swagger:operation GET /api/v1/sellers/{seller_id}/offers/{offer_id} handleGetOffer
+//
+// Get product of seller
+// ---
+// summary: Get product by seller id and offer id
+// operationId: handleGetOffer
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: successful operation, ETag header contains version of product
+//     schema:
+//       $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid seller_id or offer_id supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such product
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	product, err := h.selectOffer(r.Context(), sellerID, offerID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.writeProduct(w, r, http.StatusOK, product)
+}
+
+// swagger:operation POST /api/v1/sellers/{seller_id}/offers handleCreateOffer
+//
+// Create product of seller
+// ---
+// summary: Create product
+// operationId: handleCreateOffer
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: product
+//   in: body
+//   required: true
+//   schema:
+//     $ref: '#/definitions/ProductInfo'
+// responses:
+//   201:
+//     description: product created, ETag header contains version of product
+//     schema:
+//       $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid product supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   409:
+//     description: Product already exists
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleCreateOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, _, ok := h.parseOfferVars(w, r, false)
+	if !ok {
+		return
+	}
+
+	// currency is not required in body
+	product := &models.ProductInfo{Currency: models.DefaultCurrency}
+	if err := json.NewDecoder(r.Body).Decode(product); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be json ProductInfo")
+		return
+	}
+
+	// fields set by storage are ignored as in batch upload
+	tools.ClearServerFields(product)
+	product.SellerID = sellerID
+
+	if err := tools.ValidateProductInfo(product); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if _, err := h.usecase.CreateProduct(r.Context(), product); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	// deleted product is created with next version, so version is selected
+	created, err := h.usecase.SelectProduct(r.Context(), product.SellerID, product.OfferID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", apiV1Prefix+"/sellers/"+strconv.FormatInt(created.SellerID, 10)+
+		"/offers/"+strconv.FormatInt(created.OfferID, 10))
+	h.writeProduct(w, r, http.StatusCreated, created)
+}
+
+// swagger:operation PUT /api/v1/sellers/{seller_id}/offers/{offer_id} handleReplaceOffer
+//
+// Replace all fields of product, if If-Match header is set product is
+// changed only if its ETag matches
+// ---
+// summary: Replace product
+// operationId: handleReplaceOffer
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: If-Match
+//   in: header
+//   required: false
+//   type: string
+// - name: product
+//   in: body
+//   required: true
+//   schema:
+//     $ref: '#/definitions/ProductInfo'
+// responses:
+//   200:
+//     description: successful operation, ETag header contains new version of product
+//     schema:
+//       $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid product supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such product
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   412:
+//     description: Product was changed, ETag does not match If-Match
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleReplaceOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidIfMatch, "If-Match", domainErrors.Message(err))
+		return
+	}
+
+	// currency is not required in body
+	product := &models.ProductInfo{Currency: models.DefaultCurrency}
+	if err := json.NewDecoder(r.Body).Decode(product); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be json ProductInfo")
+		return
+	}
+
+	// fields set by storage are ignored as in batch upload, version is
+	// taken from If-Match
+	tools.ClearServerFields(product)
+	product.SellerID = sellerID
+	product.OfferID = offerID
+	product.Version = version
+
+	if err := tools.ValidateProductInfo(product); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.updateProduct(w, r, product)
+}
+
+// swagger:operation PATCH /api/v1/sellers/{seller_id}/offers/{offer_id} handlePatchOffer
+//
+// Change only fields of product which are present in body, if If-Match
+// header is set product is changed only if its ETag matches
+// ---
+// summary: Change product partially
+// operationId: handlePatchOffer
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: If-Match
+//   in: header
+//   required: false
+//   type: string
+// - name: patch
+//   in: body
+//   required: true
+//   schema:
+//     $ref: '#/definitions/ProductPatch'
+// responses:
+//   200:
+//     description: successful operation, ETag header contains new version of product
+//     schema:
+//       $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid patch supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such product
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   412:
+//     description: Product was changed, ETag does not match If-Match
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handlePatchOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidIfMatch, "If-Match", domainErrors.Message(err))
+		return
+	}
+
+	patch := new(models.ProductPatch)
+	if err := json.NewDecoder(r.Body).Decode(patch); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, codeInvalidRequestBody, "Request body must be json ProductPatch")
+		return
+	}
+
+	product, err := h.selectOffer(r.Context(), sellerID, offerID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if version != 0 && version != product.Version {
+		h.respondError(w, r, domainErrors.PreconditionFailed("Product was changed, its version does not match If-Match"))
+		return
+	}
+
+	if patch.Name != nil {
+		product.Name = *patch.Name
+	}
+	if patch.Price != nil {
+		product.Price = *patch.Price
+	}
+	if patch.Currency != nil {
+		product.Currency = *patch.Currency
+	}
+	if patch.Quantity != nil {
+		product.Quantity = *patch.Quantity
+	}
+	if patch.Available != nil {
+		product.Available = *patch.Available
+	}
+	if patch.SKU != nil {
+		product.SKU = *patch.SKU
+	}
+	if patch.Description != nil {
+		product.Description = *patch.Description
+	}
+	if patch.Category != nil {
+		product.Category = *patch.Category
+	}
+	if patch.Brand != nil {
+		product.Brand = *patch.Brand
+	}
+	if patch.Images != nil {
+		product.Images = *patch.Images
+	}
+	if patch.Weight != nil {
+		product.Weight = patch.Weight
+	}
+	if patch.Dimensions != nil {
+		product.Dimensions = patch.Dimensions
+	}
+	if patch.Extras != nil {
+		product.Extras = patch.Extras
+	}
+
+	if err := tools.ValidateProductInfo(product); err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	// product is updated only if nobody changed it after select above
+	h.updateProduct(w, r, product)
+}
+
+// updateProduct - update product with check of its version and write
+// new state of product to response
+func (h *handlers) updateProduct(w http.ResponseWriter, r *http.Request, product *models.ProductInfo) {
+	rowsAffected, err := h.usecase.UpdateProduct(r.Context(), product)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if rowsAffected == 0 {
+		h.respondError(w, r, h.versionMismatch(r.Context(), product.SellerID, product.OfferID))
+		return
+	}
+
+	updated, err := h.usecase.SelectProduct(r.Context(), product.SellerID, product.OfferID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.writeProduct(w, r, http.StatusOK, updated)
+}
+
+// swagger:operation DELETE /api/v1/sellers/{seller_id}/offers/{offer_id} handleDeleteOffer
+//
+// Delete product, if If-Match header is set product is deleted only
+// if its ETag matches. Deleted product can be restored until it is purged
+// ---
+// summary: Delete product
+// operationId: handleDeleteOffer
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: If-Match
+//   in: header
+//   required: false
+//   type: string
+// responses:
+//   204:
+//     description: product deleted
+//   400:
+//     description: Invalid seller_id or offer_id supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such product
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   412:
+//     description: Product was changed, ETag does not match If-Match
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleDeleteOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidIfMatch, "If-Match", domainErrors.Message(err))
+		return
+	}
+
+	rowsAffected, err := h.usecase.DeleteProduct(r.Context(), sellerID, offerID, version)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if rowsAffected == 0 {
+		h.respondError(w, r, h.versionMismatch(r.Context(), sellerID, offerID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:operation POST /api/v1/sellers/{seller_id}/offers/{offer_id}/restore handleRestoreOffer
+//
+// Restore deleted product which is not purged yet, if If-Match header is
+// set product is restored only if its ETag matches
+// ---
+// summary: Restore product
+// operationId: handleRestoreOffer
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: If-Match
+//   in: header
+//   required: false
+//   type: string
+// responses:
+//   200:
+//     description: product restored, ETag header contains new version of product
+//     schema:
+//       $ref: '#/definitions/ProductInfo'
+//   400:
+//     description: Invalid seller_id or offer_id supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: No such product or it is purged
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   409:
+//     description: Product is not deleted
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   412:
+//     description: Product was changed, ETag does not match If-Match
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleRestoreOffer(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidIfMatch, "If-Match", domainErrors.Message(err))
+		return
+	}
+
+	rowsAffected, err := h.usecase.RestoreProduct(r.Context(), sellerID, offerID, version)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if rowsAffected == 0 {
+		h.respondError(w, r, h.restoreMismatch(r.Context(), sellerID, offerID))
+		return
+	}
+
+	product, err := h.usecase.SelectProduct(r.Context(), sellerID, offerID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	h.writeProduct(w, r, http.StatusOK, product)
+}
+
+// restoreMismatch - explain why restore changed nothing: product does not
+// exist, is not deleted or has other version
+func (h *handlers) restoreMismatch(ctx context.Context, sellerID, offerID int64) error {
+	products, err := h.usecase.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{
+		SellerID:       sellerID,
+		OfferID:        offerID,
+		IncludeDeleted: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(products) == 0:
+		return domainErrors.NotFound("product not found")
+	case products[0].DeletedAt == nil:
+		return domainErrors.Conflict("product is not deleted")
+	}
+
+	return domainErrors.PreconditionFailed("Product was changed, its version does not match If-Match")
+}
+
+// swagger:operation GET /api/v1/sellers/{seller_id}/offers/{offer_id}/history handleGetOfferHistory
+//
+// Get all changes of product with their old and new values and source of
+// change (task, file, sheet and row or request id), history of deleted
+// product is kept
+// ---
+// summary: Get history of product
+// operationId: handleGetOfferHistory
+// produces:
+// - application/json
+// parameters:
+// - name: seller_id
+//   in: path
+//   required: true
+//   type: integer
+// - name: offer_id
+//   in: path
+//   required: true
+//   type: integer
+// responses:
+//   200:
+//     description: successful operation, changes are ordered from oldest to newest
+//     schema:
+//       type: array
+//       items:
+//         $ref: '#/definitions/ProductChange'
+//   400:
+//     description: Invalid seller_id or offer_id supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   404:
+//     description: Product never existed
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleGetOfferHistory(w http.ResponseWriter, r *http.Request) {
+	sellerID, offerID, ok := h.parseOfferVars(w, r, true)
+	if !ok {
+		return
+	}
+
+	changes, err := h.usecase.SelectProductHistory(r.Context(), sellerID, offerID)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	if len(changes) == 0 {
+		h.respondError(w, r, domainErrors.NotFound("product history not found"))
+		return
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(changesJSON)
+}
@@ -0,0 +1,571 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOfferRequest(method, body string, vars map[string]string) *http.Request {
+	request := httptest.NewRequest(method, "/api/v1/sellers/1/offers", strings.NewReader(body))
+
+	return mux.SetURLVars(request, vars)
+}
+
+func TestHandleGetOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	expectedData := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Version:   3,
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(expectedData, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleGetOffer(response, newOfferRequest(http.MethodGet, "", vars))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+		assert.Equal(t, `{"seller_id":1,"offer_id":2,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,`+
+			`"available":true,"version":3}`, response.Body.String())
+	}
+
+	// test not found
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).
+		Return(nil, domainErrors.NotFound("product not found"))
+
+	response = httptest.NewRecorder()
+	handlers.handleGetOffer(response, newOfferRequest(http.MethodGet, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// test deleted product is not found
+
+	deletedAt := time.Now()
+	deletedData := *expectedData
+	deletedData.DeletedAt = &deletedAt
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(&deletedData, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleGetOffer(response, newOfferRequest(http.MethodGet, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// test bad offer id
+
+	response = httptest.NewRecorder()
+	handlers.handleGetOffer(response, newOfferRequest(http.MethodGet, "",
+		map[string]string{"seller_id": "1", "offer_id": "0"}))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_offer_id"`)
+}
+
+func TestHandleCreateOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1"}
+	inputJSON := `{"offer_id":2,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true}`
+
+	expectedData := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+	}
+
+	// test expect behavior
+
+	createdData := *expectedData
+	createdData.Version = 1
+
+	usecase.EXPECT().CreateProduct(gomock.Any(), expectedData).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(&createdData, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleCreateOffer(response, newOfferRequest(http.MethodPost, inputJSON, vars))
+
+	if assert.Equal(t, http.StatusCreated, response.Code) {
+		assert.Equal(t, "/api/v1/sellers/1/offers/2", response.Header().Get("Location"))
+		assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+	}
+
+	// test product already exists
+
+	usecase.EXPECT().CreateProduct(gomock.Any(), gomock.Any()).
+		Return(int64(0), domainErrors.Conflict("product already exists"))
+
+	response = httptest.NewRecorder()
+	handlers.handleCreateOffer(response, newOfferRequest(http.MethodPost, inputJSON, vars))
+
+	assert.Equal(t, http.StatusConflict, response.Code)
+
+	// test fields set by storage are ignored
+
+	usecase.EXPECT().CreateProduct(gomock.Any(), expectedData).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(&createdData, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleCreateOffer(response, newOfferRequest(http.MethodPost,
+		`{"offer_id":2,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,`+
+			`"version":7,"deleted_at":"2021-01-01T00:00:00Z","converted_price":"1.5"}`, vars))
+
+	assert.Equal(t, http.StatusCreated, response.Code)
+
+	// test invalid values
+
+	response = httptest.NewRecorder()
+	handlers.handleCreateOffer(response, newOfferRequest(http.MethodPost,
+		`{"offer_id":2,"name":"телефон","price":-1,"quantity":10,"available":true}`, vars))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Equal(t, `{"code":"validation_error","message":"Misrepresentation of values",`+
+		`"details":[{"field":"price","message":"must not be negative"}]}`, response.Body.String())
+
+	// test invalid json
+
+	response = httptest.NewRecorder()
+	handlers.handleCreateOffer(response, newOfferRequest(http.MethodPost, `{"offer_id":`, vars))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_request_body"`)
+}
+
+func TestHandleReplaceOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+	inputJSON := `{"name":"телефон","price":"150","currency":"RUB","quantity":5,"available":true}`
+
+	updatedData := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("150"),
+		Currency:  "RUB",
+		Quantity:  5,
+		Available: true,
+		Version:   4,
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().UpdateProduct(gomock.Any(), &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("150"),
+		Currency:  "RUB",
+		Quantity:  5,
+		Available: true,
+		Version:   3,
+	}).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(updatedData, nil)
+
+	request := newOfferRequest(http.MethodPut, inputJSON, vars)
+	request.Header.Set("If-Match", `"3"`)
+
+	response := httptest.NewRecorder()
+	handlers.handleReplaceOffer(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `"4"`, response.Header().Get("ETag"))
+	}
+
+	// test product was changed by other request
+
+	usecase.EXPECT().UpdateProduct(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(updatedData, nil)
+
+	request = newOfferRequest(http.MethodPut, inputJSON, vars)
+	request.Header.Set("If-Match", `"3"`)
+
+	response = httptest.NewRecorder()
+	handlers.handleReplaceOffer(response, request)
+
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	// test fields set by storage are ignored
+
+	usecase.EXPECT().UpdateProduct(gomock.Any(), &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("150"),
+		Currency:  "RUB",
+		Quantity:  5,
+		Available: true,
+	}).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(updatedData, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleReplaceOffer(response, newOfferRequest(http.MethodPut,
+		`{"name":"телефон","price":"150","currency":"RUB","quantity":5,"available":true,`+
+			`"version":7,"deleted_at":"2021-01-01T00:00:00Z","converted_price":"1.5"}`, vars))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	// test product does not exist
+
+	usecase.EXPECT().UpdateProduct(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).
+		Return(nil, domainErrors.NotFound("product not found"))
+
+	response = httptest.NewRecorder()
+	handlers.handleReplaceOffer(response, newOfferRequest(http.MethodPut, inputJSON, vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// test invalid If-Match
+
+	request = newOfferRequest(http.MethodPut, inputJSON, vars)
+	request.Header.Set("If-Match", `"abc"`)
+
+	response = httptest.NewRecorder()
+	handlers.handleReplaceOffer(response, request)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_if_match"`)
+}
+
+func TestHandlePatchOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+
+	storedData := func() *models.ProductInfo {
+		return &models.ProductInfo{
+			SellerID:  1,
+			OfferID:   2,
+			Name:      "телефон",
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
+			Quantity:  10,
+			Available: true,
+			Version:   3,
+		}
+	}
+
+	// test expect behavior
+
+	patchedData := storedData()
+	patchedData.Quantity = 7
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(storedData(), nil)
+	usecase.EXPECT().UpdateProduct(gomock.Any(), patchedData).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(patchedData, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handlePatchOffer(response, newOfferRequest(http.MethodPatch, `{"quantity":7}`, vars))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	// test patch of attributes
+
+	weight := decimal.RequireFromString("0.2")
+	patchedData = storedData()
+	patchedData.Category = "электроника/телефоны"
+	patchedData.Images = []string{"http://img/1.png"}
+	patchedData.Weight = &weight
+	patchedData.Extras = map[string]interface{}{"color": "black"}
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(storedData(), nil)
+	usecase.EXPECT().UpdateProduct(gomock.Any(), patchedData).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(patchedData, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handlePatchOffer(response, newOfferRequest(http.MethodPatch, `{"category":"электроника/телефоны",`+
+		`"images":["http://img/1.png"],"weight":"0.2","extras":{"color":"black"}}`, vars))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(storedData(), nil)
+
+	response = httptest.NewRecorder()
+	handlers.handlePatchOffer(response, newOfferRequest(http.MethodPatch, `{"images":["img.png"]}`, vars))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	// test If-Match does not match
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(storedData(), nil)
+
+	request := newOfferRequest(http.MethodPatch, `{"quantity":7}`, vars)
+	request.Header.Set("If-Match", `"2"`)
+
+	response = httptest.NewRecorder()
+	handlers.handlePatchOffer(response, request)
+
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	// test invalid value
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(storedData(), nil)
+
+	response = httptest.NewRecorder()
+	handlers.handlePatchOffer(response, newOfferRequest(http.MethodPatch, `{"name":""}`, vars))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	// test deleted product is not patched
+
+	deletedAt := time.Now()
+	deletedData := storedData()
+	deletedData.DeletedAt = &deletedAt
+
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(deletedData, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handlePatchOffer(response, newOfferRequest(http.MethodPatch, `{"quantity":7}`, vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleDeleteOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+
+	// test expect behavior
+
+	usecase.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(2), int64(3)).Return(int64(1), nil)
+
+	request := newOfferRequest(http.MethodDelete, "", vars)
+	request.Header.Set("If-Match", `"3"`)
+
+	response := httptest.NewRecorder()
+	handlers.handleDeleteOffer(response, request)
+
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	// test product does not exist
+
+	usecase.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(2), int64(0)).Return(int64(0), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).
+		Return(nil, domainErrors.NotFound("product not found"))
+
+	response = httptest.NewRecorder()
+	handlers.handleDeleteOffer(response, newOfferRequest(http.MethodDelete, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// test product is already deleted
+
+	deletedAt := time.Now()
+
+	usecase.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(2), int64(0)).Return(int64(0), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).
+		Return(&models.ProductInfo{SellerID: 1, OfferID: 2, Version: 4, DeletedAt: &deletedAt}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleDeleteOffer(response, newOfferRequest(http.MethodDelete, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleGetOfferHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+
+	expectedData := []*models.ProductChange{
+		{
+			ID:       1,
+			SellerID: 1,
+			OfferID:  2,
+			Action:   models.ProductCreated,
+			NewValue: &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "tele", Price: decimal.RequireFromString("10"), Currency: "RUB",
+				Quantity: 1, Available: true, Version: 1},
+			Source:    models.ChangeSource{TaskID: 5, File: "products.xlsx", Sheet: "Sheet1", Row: 3},
+			ChangedAt: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SelectProductHistory(gomock.Any(), int64(1), int64(2)).Return(expectedData, nil)
+
+	response := httptest.NewRecorder()
+	handlers.handleGetOfferHistory(response, newOfferRequest(http.MethodGet, "", vars))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `[{"id":1,"seller_id":1,"offer_id":2,"action":"CREATE","old_value":null,`+
+			`"new_value":{"seller_id":1,"offer_id":2,"name":"tele","price":"10","currency":"RUB","quantity":1,"available":true,"version":1},`+
+			`"source":{"task_id":5,"file":"products.xlsx","sheet":"Sheet1","row":3},`+
+			`"changed_at":"2021-01-01T12:00:00Z"}]`, response.Body.String())
+	}
+
+	// test product never existed
+
+	usecase.EXPECT().SelectProductHistory(gomock.Any(), int64(1), int64(2)).Return([]*models.ProductChange{}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleGetOfferHistory(response, newOfferRequest(http.MethodGet, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestHandleRestoreOffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	handlers := &handlers{
+		usecase:   usecase,
+		taskQueue: make(chan models.Task),
+		logger:    logrus.New(),
+	}
+
+	vars := map[string]string{"seller_id": "1", "offer_id": "2"}
+
+	restoredData := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Version:   4,
+	}
+
+	deletedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	deletedData := *restoredData
+	deletedData.Version = 3
+	deletedData.DeletedAt = &deletedAt
+
+	searchDeleted := &models.UserListRequest{SellerID: 1, OfferID: 2, IncludeDeleted: true}
+
+	// test expect behavior
+
+	usecase.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(2), int64(3)).Return(int64(1), nil)
+	usecase.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(restoredData, nil)
+
+	request := newOfferRequest(http.MethodPost, "", vars)
+	request.Header.Set("If-Match", `"3"`)
+
+	response := httptest.NewRecorder()
+	handlers.handleRestoreOffer(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `"4"`, response.Header().Get("ETag"))
+	}
+
+	// test product is not deleted
+
+	usecase.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(2), int64(0)).Return(int64(0), nil)
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), searchDeleted).
+		Return([]*models.ProductInfo{restoredData}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleRestoreOffer(response, newOfferRequest(http.MethodPost, "", vars))
+
+	assert.Equal(t, http.StatusConflict, response.Code)
+
+	// test deleted product has other version
+
+	usecase.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(2), int64(2)).Return(int64(0), nil)
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), searchDeleted).
+		Return([]*models.ProductInfo{&deletedData}, nil)
+
+	request = newOfferRequest(http.MethodPost, "", vars)
+	request.Header.Set("If-Match", `"2"`)
+
+	response = httptest.NewRecorder()
+	handlers.handleRestoreOffer(response, request)
+
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+
+	// test product does not exist or is purged
+
+	usecase.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(2), int64(0)).Return(int64(0), nil)
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), searchDeleted).
+		Return([]*models.ProductInfo{}, nil)
+
+	response = httptest.NewRecorder()
+	handlers.handleRestoreOffer(response, newOfferRequest(http.MethodPost, "", vars))
+
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
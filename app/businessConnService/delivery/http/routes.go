@@ -0,0 +1,177 @@
+package http
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
+)
+
+// apiV1Prefix - prefix of all routes of first version of api
+const apiV1Prefix = "/api/v1"
+
+// registerV1Routes - register resource-oriented routes of api v1,
+// r must be subrouter with apiV1Prefix
+func (h *handlers) registerV1Routes(r *mux.Router) {
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/tasks",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/tasks", h.handleLoadProduct)).
+		Methods("POST")
+
+	r.HandleFunc("/offers",
+		h.withMiddlewares(apiV1Prefix+"/offers", h.handleListOffers)).
+		Methods("GET")
+
+	r.HandleFunc("/offers/search",
+		h.withMiddlewares(apiV1Prefix+"/offers/search", h.handleSearchOffers)).
+		Methods("GET")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers", h.handleListSellerOffers)).
+		Methods("GET")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers", h.handleCreateOffer)).
+		Methods("POST")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers:batch",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers:batch", h.handleLoadBatch)).
+		Methods("POST")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}", h.handleGetOffer)).
+		Methods("GET")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}", h.handleReplaceOffer)).
+		Methods("PUT")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}", h.handlePatchOffer)).
+		Methods("PATCH")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}", h.handleDeleteOffer)).
+		Methods("DELETE")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}/restore",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}/restore", h.handleRestoreOffer)).
+		Methods("POST")
+
+	r.HandleFunc("/sellers/{seller_id:[0-9]+}/offers/{offer_id:[0-9]+}/history",
+		h.withMiddlewares(apiV1Prefix+"/sellers/{seller_id}/offers/{offer_id}/history", h.handleGetOfferHistory)).
+		Methods("GET")
+
+	r.HandleFunc("/tasks/{task_id:[0-9]+}",
+		h.withMiddlewares(apiV1Prefix+"/tasks/{task_id}", h.handleGetTaskState)).
+		Methods("GET")
+
+	r.HandleFunc("/tasks/{task_id:[0-9]+}/stats",
+		h.withMiddlewares(apiV1Prefix+"/tasks/{task_id}/stats", h.handleGetTaskStats)).
+		Methods("GET")
+
+	r.HandleFunc("/tasks/{task_id:[0-9]+}/preview",
+		h.withMiddlewares(apiV1Prefix+"/tasks/{task_id}/preview", h.handleGetTaskPreview)).
+		Methods("GET")
+
+	r.HandleFunc("/tasks/{task_id:[0-9]+}/errors",
+		h.withMiddlewares(apiV1Prefix+"/tasks/{task_id}/errors", h.handleGetTaskErrors)).
+		Methods("GET")
+
+	r.HandleFunc("/tasks/{task_id:[0-9]+}/rollback",
+		h.withMiddlewares(apiV1Prefix+"/tasks/{task_id}/rollback", h.handleRollbackTask)).
+		Methods("POST")
+
+	r.HandleFunc("/exchangeRates",
+		h.withMiddlewares(apiV1Prefix+"/exchangeRates", h.handleListExchangeRates)).
+		Methods("GET")
+
+	r.HandleFunc("/exchangeRates/{currency}",
+		h.withMiddlewares(apiV1Prefix+"/exchangeRates/{currency}", h.requireAdmin(h.handleUpdateExchangeRate))).
+		Methods("PUT")
+
+	r.HandleFunc("/exchangeRates/{currency}",
+		h.withMiddlewares(apiV1Prefix+"/exchangeRates/{currency}", h.requireAdmin(h.handleDeleteExchangeRate))).
+		Methods("DELETE")
+}
+
+// registerLegacyRoutes - register verb-style routes of first release, they
+// are aliases of api v1 handlers and are kept for old clients
+func (h *handlers) registerLegacyRoutes(r *mux.Router) {
+	r.HandleFunc("/loadProduct",
+		h.withMiddlewares("/loadProduct", deprecated(h.handleLoadProduct))).
+		Methods("POST")
+
+	r.HandleFunc("/getProduct",
+		h.withMiddlewares("/getProduct", deprecated(h.handleGetProducts))).
+		Methods("GET")
+
+	r.HandleFunc("/getTaskState/{task_id:[0-9]+}",
+		h.withMiddlewares("/getTaskState/{task_id}", deprecated(h.handleGetTaskState))).
+		Methods("GET")
+
+	r.HandleFunc("/getTaskStats/{task_id:[0-9]+}",
+		h.withMiddlewares("/getTaskStats/{task_id}", deprecated(h.handleGetTaskStats))).
+		Methods("GET")
+}
+
+// registerDebugRoutes - register routes with runtime and cache metrics of
+// process, they are available only for admins
+func (h *handlers) registerDebugRoutes(r *mux.Router) {
+	r.HandleFunc("/debug/vars",
+		h.withMiddlewares("/debug/vars", h.requireAdmin(expvar.Handler().ServeHTTP))).
+		Methods("GET")
+}
+
+// deprecated - mark response of legacy route with Deprecation header so
+// that clients know they should move to api v1
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiV1Prefix+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
+
+// limitRate - reject request with 429 if rate limit of process is
+// exceeded, client can retry after time in Retry-After header
+func (h *handlers) limitRate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter == nil {
+			next(w, r)
+			return
+		}
+
+		if ok, retryAfter := h.limiter.Allow(); !ok {
+			logging.FromContext(r.Context(), h.logger).Info("Rate limit exceeded")
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			h.writeError(w, r, http.StatusTooManyRequests, codeTooManyRequests,
+				http.StatusText(http.StatusTooManyRequests))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// requireAdmin - pass request to admin route only with admin token in
+// Authorization header, without configured token admin routes are forbidden
+func (h *handlers) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			h.respondError(w, r, domainErrors.Forbidden("Admin token is required"))
+			return
+		}
+
+		next(w, r)
+	}
+}
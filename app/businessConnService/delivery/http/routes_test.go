@@ -0,0 +1,309 @@
+package http
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/middlewares"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutesTaskState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{}, logrus.New())
+
+	expectedData := &models.TaskState{
+		TaskID: 1,
+		State:  "DONE",
+	}
+
+	outputJSON := `{"task_id":1,"state":"DONE"}`
+
+	// test api v1 route
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(1)).Return(expectedData, nil)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, outputJSON, response.Body.String())
+		assert.Empty(t, response.Header().Get("Deprecation"))
+	}
+
+	// test legacy alias
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(1)).Return(expectedData, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/getTaskState/1", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, outputJSON, response.Body.String())
+		assert.Equal(t, "true", response.Header().Get("Deprecation"))
+	}
+}
+
+func TestRoutesTaskStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{}, logrus.New())
+
+	usecase.EXPECT().SelectTaskStatsByTaskID(gomock.Any(), int64(2)).Return(&models.TaskStats{TaskID: 2}, nil).Times(2)
+
+	for _, path := range []string{"/api/v1/tasks/2/stats", "/getTaskStats/2"} {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, response.Code, path)
+	}
+}
+
+func TestRoutesListOffers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{}, logrus.New())
+
+	products := []*models.ProductInfo{
+		{SellerID: 1, OfferID: 2, Name: "телефон", Price: decimal.RequireFromString("100"), Currency: "RUB",
+			Quantity: 1, Available: true, Version: 1},
+	}
+
+	outputJSON := `[{"seller_id":1,"offer_id":2,"name":"телефон","price":"100","currency":"RUB","quantity":1,` +
+		`"available":true,"version":1}]`
+
+	// test search by query params
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SellerID: 1, OfferID: 2, Name: "тел"}).Return(products, nil)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/offers?seller_id=1&offer_id=2&name=%D1%82%D0%B5%D0%BB", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, outputJSON, response.Body.String())
+	}
+
+	// test search in offers of seller
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SellerID: 1}).Return(products, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/sellers/1/offers", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, outputJSON, response.Body.String())
+	}
+
+	// test xlsx is returned if client accepts it
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SellerID: 1}).Return(products, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/sellers/1/offers", nil)
+	request.Header.Set("Accept", xlsxContentType)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, xlsxContentType, response.Header().Get("Content-Type"))
+	}
+
+	// test deleted products are asked
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SellerID: 1, IncludeDeleted: true}).Return(products, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/sellers/1/offers?include_deleted=true", nil))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/sellers/1/offers?include_deleted=maybe", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_include_deleted"`)
+
+	// test lists of ids, they are comma separated or repeated
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SellerIDs: []int64{1, 2}, OfferIDs: []int64{3, 4}, ExcludeOfferIDs: []int64{5}}).
+		Return(products, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/offers?seller_ids=1,2&offer_ids=3&offer_ids=4&exclude_offer_ids=5", nil))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers?exclude_seller_ids=1,abc", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_seller_id"`)
+
+	// test prices normalised to currency
+
+	minPrice := decimal.RequireFromString("1.5")
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{Currency: "USD", MinPrice: &minPrice, Sort: models.SortByPriceDesc}).Return(products, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/offers?currency=USD&min_price=1.5&sort=-price", nil))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers?max_price=NaN", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_price"`)
+
+	// test search by attributes
+
+	usecase.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(),
+		&models.UserListRequest{SKU: "PH-1", Category: "электроника", Brand: "acme"}).Return(products, nil)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/offers?sku=PH-1&category=%D1%8D%D0%BB%D0%B5%D0%BA%D1%82%D1%80%D0%BE%D0%BD%D0%B8%D0%BA%D0%B0&brand=acme",
+		nil))
+
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	// test bad query param
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers?offer_id=abc", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_offer_id"`)
+}
+
+func TestRoutesLoadProduct(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	taskQueue := make(chan models.Task, 1)
+
+	router := NewHandlers(usecase, taskQueue, Config{}, logrus.New())
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("products", "products.xlsx")
+	part.Write([]byte("data"))
+	writer.Close()
+
+	usecase.EXPECT().CreateTask(gomock.Any()).Return(int64(3), nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/sellers/7/tasks", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, "3", response.Body.String())
+
+		task := <-taskQueue
+		assert.Equal(t, int64(3), task.TaskID)
+		assert.Equal(t, int64(7), task.SellerID)
+	}
+}
+
+func TestRoutesDebugVars(t *testing.T) {
+	handlers := &handlers{
+		taskQueue:  make(chan models.Task),
+		logger:     logrus.New(),
+		adminToken: "secret",
+	}
+
+	router := mux.NewRouter()
+	handlers.registerDebugRoutes(router)
+
+	// test metrics are forbidden without admin token
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	// test metrics with admin token
+
+	request := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Contains(t, response.Body.String(), `"memstats"`)
+	}
+}
+
+func TestRoutesRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+	limiter := middlewares.NewRateLimiter(0.001, 2)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{RateLimiter: limiter}, logrus.New())
+
+	usecase.EXPECT().SelectTaskState(gomock.Any(), int64(1)).
+		Return(&models.TaskState{TaskID: 1, State: "DONE"}, nil).Times(3)
+
+	// test burst is allowed and next request is rejected
+
+	for i := 0; i < 2; i++ {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1", nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1", nil))
+
+	if assert.Equal(t, http.StatusTooManyRequests, response.Code) {
+		assert.Contains(t, response.Body.String(), `"code":"too_many_requests"`)
+		assert.NotEmpty(t, response.Header().Get("Retry-After"))
+		assert.NotEmpty(t, response.Header().Get(middlewares.RequestIDHeader))
+	}
+
+	// test changed limit is applied without new handlers
+
+	limiter.SetLimit(0, 0)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/1", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
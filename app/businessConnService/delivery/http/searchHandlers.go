@@ -0,0 +1,111 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// parseQueryInt - get optional integer from query params of request, zero
+// is returned when param is absent
+func parseQueryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, domainErrors.Validation("must be integer")
+	}
+
+	return number, nil
+}
+
+// swagger:operation GET /api/v1/offers/search handleSearchOffers
+//
+// Full-text search of products by name, sku, brand, category and
+// description. Every word of query is matched by prefix in russian and
+// english, products having all words are returned sorted by relevance
+// with snippets where found words are highlighted by <b></b>.
+// Deleted products are not found
+// ---
+// summary: Full-text search of products
+// operationId: handleSearchOffers
+// produces:
+// - application/json
+// parameters:
+// - name: q
+//   in: query
+//   description: Text of search
+//   required: true
+//   type: string
+// - name: seller_id
+//   in: query
+//   required: false
+//   type: integer
+// - name: limit
+//   in: query
+//   description: Max number of results, 20 by default, 100 at most
+//   required: false
+//   type: integer
+// - name: offset
+//   in: query
+//   description: Number of skipped results
+//   required: false
+//   type: integer
+// responses:
+//   200:
+//     description: successful operation
+//     schema:
+//       type: array
+//       items:
+//         $ref: '#/definitions/SearchResult'
+//   400:
+//     description: Invalid q, seller_id, limit or offset supplied
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+//   500:
+//     description: Sth went wrong
+//     schema:
+//       $ref: '#/definitions/ErrorResponse'
+func (h *handlers) handleSearchOffers(w http.ResponseWriter, r *http.Request) {
+	searchRequest := &models.SearchRequest{
+		Query: r.URL.Query().Get("q"),
+	}
+
+	sellerID, err := parseQueryID(r, "seller_id")
+	if err != nil {
+		h.respondBadRequest(w, r, codeInvalidSellerID, "seller_id", domainErrors.Message(err))
+		return
+	}
+	searchRequest.SellerID = sellerID
+
+	if searchRequest.Limit, err = parseQueryInt(r, "limit"); err != nil {
+		h.respondBadRequest(w, r, codeInvalidLimit, "limit", domainErrors.Message(err))
+		return
+	}
+
+	if searchRequest.Offset, err = parseQueryInt(r, "offset"); err != nil {
+		h.respondBadRequest(w, r, codeInvalidOffset, "offset", domainErrors.Message(err))
+		return
+	}
+
+	results, err := h.usecase.SearchProducts(r.Context(), searchRequest)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		h.respondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resultsJSON)
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleSearchOffers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	router := NewHandlers(usecase, make(chan models.Task), Config{}, logrus.New())
+
+	results := []*models.SearchResult{
+		{
+			Product: &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "телефон",
+				Price: decimal.RequireFromString("100"), Currency: "RUB", Quantity: 1, Available: true, Version: 1},
+			Rank:    0.5,
+			Snippet: "<b>телефон</b>",
+		},
+	}
+
+	// test expect behavior
+
+	usecase.EXPECT().SearchProducts(gomock.Any(),
+		&models.SearchRequest{Query: "тел", SellerID: 1, Limit: 10, Offset: 20}).Return(results, nil)
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet,
+		"/api/v1/offers/search?q=%D1%82%D0%B5%D0%BB&seller_id=1&limit=10&offset=20", nil))
+
+	if assert.Equal(t, http.StatusOK, response.Code) {
+		assert.Equal(t, `[{"product":{"seller_id":1,"offer_id":2,"name":"телефон","price":"100","currency":"RUB",`+
+			`"quantity":1,"available":true,"version":1},"rank":0.5,"snippet":"\u003cb\u003eтелефон\u003c/b\u003e"}]`,
+			response.Body.String())
+	}
+
+	// test bad query params
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers/search?q=a&limit=ten", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_limit"`)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers/search?q=a&offset=1.5", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"invalid_offset"`)
+
+	// test invalid request
+
+	usecase.EXPECT().SearchProducts(gomock.Any(), &models.SearchRequest{}).
+		Return(nil, domainErrors.InvalidFields("Invalid search request",
+			domainErrors.FieldError{Field: "q", Message: "must contain words"}))
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers/search", nil))
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), `"code":"validation_error"`)
+
+	// test db error
+
+	usecase.EXPECT().SearchProducts(gomock.Any(), &models.SearchRequest{Query: "a"}).
+		Return(nil, errors.New("DB error"))
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/api/v1/offers/search?q=a", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
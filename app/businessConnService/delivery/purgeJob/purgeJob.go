@@ -0,0 +1,73 @@
+package purgeJob
+
+import (
+	"context"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+type purgeJob struct {
+	usecase businessConnService.IUsecase
+	// retention - how long deleted products can be restored
+	retention time.Duration
+	interval  time.Duration
+	stopCh    chan struct{}
+	logger    *logrus.Logger
+}
+
+// NewPurgeJob - create new job which purges deleted products
+func NewPurgeJob(us businessConnService.IUsecase, retention, interval time.Duration,
+	stopCh chan struct{}, logger *logrus.Logger) *purgeJob {
+	return &purgeJob{
+		usecase:   us,
+		retention: retention,
+		interval:  interval,
+		stopCh:    stopCh,
+		logger:    logger,
+	}
+}
+
+// PurgeJob - every interval physically delete products which were deleted
+// more than retention ago, zero retention or interval disables job
+func (pj *purgeJob) PurgeJob() {
+	if pj.retention <= 0 || pj.interval <= 0 {
+		pj.logger.Info("PurgeJob disabled, deleted products are kept")
+		return
+	}
+
+	ticker := time.NewTicker(pj.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pj.purge(time.Now())
+		case <-pj.stopCh:
+			pj.logger.Info("Stop PurgeJob")
+			return
+		}
+	}
+}
+
+// purge - delete products which were deleted before now minus retention
+func (pj *purgeJob) purge(now time.Time) {
+	ctx, span := tracing.Tracer().Start(context.Background(), "job.purge")
+	defer span.End()
+
+	deletedBefore := now.Add(-pj.retention)
+
+	purged, err := pj.usecase.PurgeDeletedProducts(ctx, deletedBefore)
+	if err != nil {
+		tracing.RecordError(span, err)
+		pj.logger.WithError(err).Error("Failed to purge deleted products")
+		return
+	}
+
+	pj.logger.WithFields(logrus.Fields{
+		"deleted_before":  deletedBefore,
+		"products_purged": purged,
+	}).Info("Deleted products purged")
+}
@@ -0,0 +1,64 @@
+package purgeJob
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPurge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	job := NewPurgeJob(usecase, 24*time.Hour, time.Hour, make(chan struct{}), logrus.New())
+
+	now := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	// test expect behavior
+
+	usecase.EXPECT().PurgeDeletedProducts(gomock.Any(), time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)).
+		Return(int64(3), nil)
+
+	job.purge(now)
+
+	// test db error is only logged
+
+	usecase.EXPECT().PurgeDeletedProducts(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db error"))
+
+	job.purge(now)
+}
+
+func TestPurgeJobStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	usecase := businessConnService.NewMockIUsecase(ctrl)
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+
+	job := NewPurgeJob(usecase, 24*time.Hour, time.Hour, stopCh, logrus.New())
+
+	go func() {
+		job.PurgeJob()
+		close(done)
+	}()
+
+	stopCh <- struct{}{}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("PurgeJob is not stopped")
+	}
+
+	// test disabled job returns at once
+
+	NewPurgeJob(usecase, 0, time.Hour, stopCh, logrus.New()).PurgeJob()
+}
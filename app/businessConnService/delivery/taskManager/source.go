@@ -0,0 +1,353 @@
+package taskManager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errSourceTooLarge - source is larger than MaxSourceSize
+var errSourceTooLarge = errors.New("source is larger than max size")
+
+// errPrivateSource - host of source is resolved to address which is not
+// reachable from internet, such sources are downloaded only from SourceHosts
+var errPrivateSource = errors.New("source address is not public")
+
+// maxSourceRedirects - max redirects followed while source is downloaded
+const maxSourceRedirects = 10
+
+// privateNetworks - networks of addresses which are not public besides
+// loopback, link-local and multicast ones
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8",     // this network
+	"10.0.0.0/8",    // private
+	"100.64.0.0/10", // carrier-grade nat
+	"172.16.0.0/12", // private
+	"192.168.0.0/16",
+	"198.18.0.0/15", // benchmarking
+	"fc00::/7",      // unique local
+)
+
+// parseNetworks - networks of cidrs, cidrs are constant, so they are valid
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// isPublicIP - ip is reachable from internet, so it can not be address of
+// service of internal network
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSourceHost - host is in SourceHosts
+func (config Config) isSourceHost(host string) bool {
+	for _, sourceHost := range config.SourceHosts {
+		if strings.EqualFold(host, sourceHost) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkSourceURL - url of http source or of its redirect can be downloaded:
+// it is http(s) url of host in SourceHosts, if they are set
+func (config Config) checkSourceURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q of source url", u.Scheme)
+	}
+
+	if len(config.SourceHosts) > 0 && !config.isSourceHost(u.Hostname()) {
+		return fmt.Errorf("host %s of source is not in sourceHosts", u.Hostname())
+	}
+
+	return nil
+}
+
+// newSourceClient - client which downloads http sources. Hosts which are not
+// in SourceHosts must be resolved to public addresses, address is checked
+// on every connection after resolution, so neither redirect nor dns answer
+// leads to internal network. Proxy is not used, it would connect to source
+// instead of checked dialer
+func newSourceClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	publicDialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errPrivateSource
+			}
+
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+
+			if config.isSourceHost(host) {
+				return dialer.DialContext(ctx, network, address)
+			}
+
+			return publicDialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxSourceRedirects {
+				return errors.New("too many redirects of source")
+			}
+
+			return config.checkSourceURL(request.URL)
+		},
+	}
+}
+
+// source - temporary copy of source of task, content of copy is checked,
+// so source changed after check is not uploaded. Copy is removed when task
+// is processed
+type source struct {
+	// name is last element of path of source url, it is name of file in
+	// logs and history of products
+	name string
+	path string
+}
+
+// open - open file of source for reading
+func (s *source) open() (io.ReadCloser, error) {
+	return os.Open(s.path)
+}
+
+// remove - remove copy of source
+func (s *source) remove() {
+	os.Remove(s.path)
+}
+
+// contextReader - reader which stops reading when context is done, so
+// timeout is applied to reading of local file too
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
+
+// fetchSource - copy http(s) source of task or file source in SourceDir to
+// temporary file and check its size and sha256 checksum, if checksum is
+// set. Source url is not logged, it can contain secrets
+func (tm *taskManager) fetchSource(ctx context.Context, sourceURL, checksum string) (*source, error) {
+	if tm.config.SourceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tm.config.SourceTimeout)
+		defer cancel()
+	}
+
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		// error of parse contains url
+		return nil, errors.New("invalid source url")
+	}
+
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = u.Host
+	}
+
+	switch u.Scheme {
+	case "file":
+		filePath, err := tm.sourcePath(u)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return tm.saveSource(ctx, f, name, checksum)
+	case "http", "https":
+		return tm.downloadSource(ctx, u, name, checksum)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of source url", u.Scheme)
+	}
+}
+
+// downloadSource - download http(s) source to temporary file
+func (tm *taskManager) downloadSource(ctx context.Context, u *url.URL, name, checksum string) (*source, error) {
+	if err := tm.config.checkSourceURL(u); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := tm.client.Do(request)
+	if err != nil {
+		// error of client contains url, only its cause is kept
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to download source from %s: %w", u.Host, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source responded with %s", response.Status)
+	}
+
+	if tm.config.MaxSourceSize > 0 && response.ContentLength > tm.config.MaxSourceSize {
+		return nil, errSourceTooLarge
+	}
+
+	return tm.saveSource(ctx, response.Body, name, checksum)
+}
+
+// saveSource - copy content of source to temporary file, file is removed
+// if content is not valid
+func (tm *taskManager) saveSource(ctx context.Context, src io.Reader, name, checksum string) (*source, error) {
+	f, err := ioutil.TempFile("", "source-*.xlsx")
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &source{name: name, path: f.Name()}
+
+	err = tm.copySource(ctx, f, src, checksum)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		saved.remove()
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// copySource - copy source to dst with limit of MaxSourceSize and compare
+// its sha256 with checksum, if it is set
+func (tm *taskManager) copySource(ctx context.Context, dst io.Writer, src io.Reader, checksum string) error {
+	maxSize := tm.config.MaxSourceSize
+	if maxSize > 0 {
+		// one more byte is read to know source is larger than limit
+		src = io.LimitReader(src, maxSize+1)
+	}
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(dst, hash), contextReader{ctx: ctx, reader: src})
+	if err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+
+	if maxSize > 0 && size > maxSize {
+		return errSourceTooLarge
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); checksum != "" && sum != checksum {
+		return fmt.Errorf("checksum of source is %s, expected %s", sum, checksum)
+	}
+
+	return nil
+}
+
+// sourcePath - path of file of file url, file must be regular file in
+// SourceDir, symlinks are resolved before check, so they can not lead out
+// of it
+func (tm *taskManager) sourcePath(u *url.URL) (string, error) {
+	if tm.config.SourceDir == "" {
+		return "", errors.New("file sources are not allowed, sourceDir is not set")
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file source must be local, host is %q", u.Host)
+	}
+
+	dir, err := filepath.Abs(tm.config.SourceDir)
+	if err != nil {
+		return "", err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", err
+	}
+
+	filePath, err := filepath.EvalSymlinks(filepath.Clean(filepath.FromSlash(u.Path)))
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(dir, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.New("file source is not in sourceDir")
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.New("file source is not regular file")
+	}
+
+	return filePath, nil
+}
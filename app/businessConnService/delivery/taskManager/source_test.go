@@ -0,0 +1,286 @@
+package taskManager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/repository"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/usecase"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256Hex - checksum of content as it is passed in task
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetchSourceHTTP(t *testing.T) {
+	content := []byte("content of source")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/products.xlsx":
+			w.Write(content)
+		case "/slow.xlsx":
+			time.Sleep(200 * time.Millisecond)
+			w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tm := NewTaskManager(nil, nil, nil, nil, Config{MaxSourceSize: int64(len(content)),
+		SourceTimeout: 100 * time.Millisecond, SourceHosts: []string{"127.0.0.1"}}, logrus.New())
+
+	ctx := context.Background()
+
+	// test source is downloaded to temporary file and removed after task
+
+	source, err := tm.fetchSource(ctx, server.URL+"/products.xlsx", sha256Hex(content))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "products.xlsx", source.name)
+
+		downloaded, err := ioutil.ReadFile(source.path)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+
+		source.remove()
+		_, err = os.Stat(source.path)
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	// test checksum, size, status and timeout are checked
+
+	_, err = tm.fetchSource(ctx, server.URL+"/products.xlsx", sha256Hex([]byte("other")))
+	assert.Error(t, err)
+
+	_, err = tm.fetchSource(ctx, server.URL+"/missing.xlsx", "")
+	assert.Error(t, err)
+
+	_, err = tm.fetchSource(ctx, server.URL+"/slow.xlsx", "")
+	assert.Error(t, err)
+
+	tm.config.MaxSourceSize = int64(len(content)) - 1
+
+	_, err = tm.fetchSource(ctx, server.URL+"/products.xlsx", "")
+	assert.Equal(t, errSourceTooLarge, err)
+
+	_, err = tm.fetchSource(ctx, "ftp://example.com/products.xlsx", "")
+	assert.Error(t, err)
+}
+
+func TestFetchSourcePrivate(t *testing.T) {
+	content := []byte("content of source")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/products.xlsx":
+			w.Write(content)
+		case "/redirect.xlsx":
+			// localhost is not in sourceHosts, though it is the same server
+			http.Redirect(w, r, strings.Replace(r.URL.Query().Get("to"), "127.0.0.1", "localhost", 1),
+				http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// test source at loopback address is not downloaded without sourceHosts
+
+	tm := NewTaskManager(nil, nil, nil, nil, Config{}, logrus.New())
+
+	_, err := tm.fetchSource(ctx, server.URL+"/products.xlsx", "")
+	assert.True(t, errors.Is(err, errPrivateSource))
+
+	// test only sourceHosts are downloaded and redirects are checked
+
+	tm = NewTaskManager(nil, nil, nil, nil, Config{SourceHosts: []string{"127.0.0.1"}}, logrus.New())
+
+	source, err := tm.fetchSource(ctx, server.URL+"/products.xlsx", "")
+	if assert.NoError(t, err) {
+		source.remove()
+	}
+
+	_, err = tm.fetchSource(ctx, server.URL+"/redirect.xlsx?to="+url.QueryEscape(server.URL+"/products.xlsx"), "")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not in sourceHosts")
+	}
+
+	_, err = tm.fetchSource(ctx, "http://example.com/products.xlsx", "")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not in sourceHosts")
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.20.0.5":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		assert.Equal(t, public, isPublicIP(net.ParseIP(address)), address)
+	}
+}
+
+func TestFetchSourceFile(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+
+	content := []byte("content of source")
+	for _, path := range []string{filepath.Join(dir, "products.xlsx"), filepath.Join(outside, "secret.xlsx")} {
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			t.Fatalf("Can`t write file: %s", err)
+		}
+	}
+
+	if err := os.Symlink(filepath.Join(outside, "secret.xlsx"), filepath.Join(dir, "link.xlsx")); err != nil {
+		t.Fatalf("Can`t create symlink: %s", err)
+	}
+
+	tm := NewTaskManager(nil, nil, nil, nil, Config{SourceDir: dir}, logrus.New())
+
+	ctx := context.Background()
+
+	// test file of source dir is copied, copy is removed and file is kept
+
+	source, err := tm.fetchSource(ctx, "file://"+filepath.ToSlash(filepath.Join(dir, "products.xlsx")),
+		sha256Hex(content))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "products.xlsx", source.name)
+		assert.NotEqual(t, filepath.Join(dir, "products.xlsx"), source.path)
+
+		// file changed after check does not change source
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "products.xlsx"), []byte("changed"), 0600))
+
+		copied, err := ioutil.ReadFile(source.path)
+		assert.NoError(t, err)
+		assert.Equal(t, content, copied)
+
+		source.remove()
+		_, err = os.Stat(source.path)
+		assert.True(t, os.IsNotExist(err))
+
+		_, err = os.Stat(filepath.Join(dir, "products.xlsx"))
+		assert.NoError(t, err)
+	}
+
+	// test files out of source dir are forbidden
+
+	for _, path := range []string{
+		filepath.Join(outside, "secret.xlsx"),
+		filepath.Join(dir, "..", filepath.Base(outside), "secret.xlsx"),
+		filepath.Join(dir, "link.xlsx"),
+		dir,
+	} {
+		_, err := tm.fetchSource(ctx, "file://"+filepath.ToSlash(path), "")
+		assert.Error(t, err, path)
+	}
+
+	tm.config.SourceDir = ""
+
+	_, err = tm.fetchSource(ctx, "file://"+filepath.ToSlash(filepath.Join(dir, "products.xlsx")), "")
+	assert.Error(t, err)
+}
+
+func TestTaskManagerUploadSource(t *testing.T) {
+	us := usecase.NewUsecase(repository.NewInMemoryRepository())
+
+	xlsx := newXlsx(t, [][]interface{}{
+		{1, "телефон", "100.25", 10, true},
+		{2, "телевизор", "57.6", 15, true},
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(xlsx)
+	}))
+	defer server.Close()
+
+	taskQueue := make(chan models.Task)
+	stopCh := make(chan struct{})
+
+	tm := NewTaskManager(us, taskQueue, make(chan models.TaskStats, 10), stopCh,
+		Config{SourceHosts: []string{"127.0.0.1"}}, logrus.New())
+
+	go tm.TaskManager()
+	defer func() { stopCh <- struct{}{} }()
+
+	ctx := context.Background()
+
+	// test source is uploaded as file
+
+	taskID, err := us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{
+		TaskID:       taskID,
+		SellerID:     1,
+		Currency:     models.DefaultCurrency,
+		SourceURL:    server.URL + "/products.xlsx",
+		SourceSHA256: sha256Hex(xlsx),
+	}
+
+	stats := waitTaskStats(t, us, taskID)
+	assert.Equal(t, &models.TaskStats{TaskID: taskID, ProductsCreated: 2}, stats)
+
+	// test task with wrong checksum fails without changes
+
+	taskID, err = us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{
+		TaskID:       taskID,
+		SellerID:     2,
+		Currency:     models.DefaultCurrency,
+		SourceURL:    server.URL + "/products.xlsx",
+		SourceSHA256: sha256Hex([]byte("other")),
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if state, err := us.SelectTaskState(ctx, taskID); err == nil && state.State == "FAILED" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	state, err := us.SelectTaskState(ctx, taskID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "FAILED", state.State)
+	}
+
+	products, err := us.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 2})
+	if assert.NoError(t, err) {
+		assert.Empty(t, products)
+	}
+}
@@ -1,75 +1,190 @@
 package taskManager
 
 import (
-	"database/sql"
-	"mime/multipart"
+	"context"
+	"errors"
+	"io"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/360EntSecGroup-Skylar/excelize/v2"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
 	"github.com/Toringol/avito-mx-backend-test-task/tools"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// cancelStateTimeout - time to save CANCELLED state of task after task manager stopped
+const cancelStateTimeout = 5 * time.Second
+
 type taskManager struct {
 	usecase    businessConnService.IUsecase
 	taskQueue  chan models.Task
 	statsQueue chan models.TaskStats
 	stopCh     chan struct{}
 	logger     *logrus.Logger
+	// ctx is parent of all tasks contexts, it is cancelled on stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	// workers limits sheets processed at the same time
+	workers *workerPool
+	config  Config
+	// client downloads http sources of tasks
+	client *http.Client
+}
+
+// Config - settings of task manager
+type Config struct {
+	// SourceDir - directory of files which can be uploaded by file:// source
+	// url, empty - file urls are forbidden
+	SourceDir string
+	// MaxSourceSize - max size of source of task in bytes, 0 - without limit
+	MaxSourceSize int64
+	// SourceTimeout - max time of download of source, 0 - without limit
+	SourceTimeout time.Duration
+	// SourceHosts - hosts of http sources, empty - any host with public
+	// address. Listed hosts are trusted, their addresses can be private
+	SourceHosts []string
 }
 
 // NewTaskManager - create new task manager
 func NewTaskManager(us businessConnService.IUsecase, taskQueue chan models.Task,
-	statsQueue chan models.TaskStats, stopCh chan struct{}, logger *logrus.Logger) *taskManager {
+	statsQueue chan models.TaskStats, stopCh chan struct{}, config Config, logger *logrus.Logger) *taskManager {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &taskManager{
 		usecase:    us,
 		taskQueue:  taskQueue,
 		statsQueue: statsQueue,
 		stopCh:     stopCh,
 		logger:     logger,
+		ctx:        ctx,
+		cancel:     cancel,
+		workers:    newWorkerPool(0),
+		config:     config,
+		client:     newSourceClient(config),
 	}
 }
 
+// SetWorkers - change max number of sheets processed at the same time by
+// all tasks, zero means without limit. It is safe to call while tasks are
+// running, new limit is applied to sheets which are not started yet
+func (tm *taskManager) SetWorkers(workers int) {
+	tm.workers.setSize(workers)
+}
+
 // TaskManager - manages events like new task added, new stats added or stop taskManager
 func (tm *taskManager) TaskManager() {
 	for {
 		select {
 		case taskInfo := <-tm.taskQueue:
+			if taskInfo.RollbackTaskID != 0 {
+				go tm.rollbackProducer(&taskInfo, tm.statsQueue)
+				continue
+			}
 			go tm.uploadUserFilesPackProducer(&taskInfo, tm.statsQueue)
 		case stats := <-tm.statsQueue:
 			go tm.uploadStatsProducer(stats)
 		case <-tm.stopCh:
 			tm.logger.Info("Stop TaskManager")
+			// running tasks and their queries to DB are cancelled
+			tm.cancel()
 			return
 		}
 	}
 }
 
-// uploadUserFilesPackProducer - get task and concurrently processing every file
+// uploadUserFilesPackProducer - get task and concurrently processing every
+// file and batch of products. Result of synchronous task is sent when its
+// stats are saved
 func (tm *taskManager) uploadUserFilesPackProducer(taskInfo *models.Task, statsQueue chan models.TaskStats) {
-	_, err := tm.usecase.UpdateTaskState(taskInfo.TaskID, "IN PROGRESS")
+	// result channel is closed without result if task is not done, its
+	// saved state is returned to client then
+	if taskInfo.Result != nil {
+		defer close(taskInfo.Result)
+	}
+
+	// task is processed after request is finished, so its span is not child
+	// of request span, but linked with it
+	ctx, span := tracing.Tracer().Start(tm.ctx, "task.upload",
+		trace.WithLinks(tracing.LinkFromCarrier(taskInfo.TraceCarrier)),
+		trace.WithAttributes(
+			tracing.TaskIDKey.Int64(taskInfo.TaskID),
+			tracing.SellerIDKey.Int64(taskInfo.SellerID),
+		),
+	)
+	defer span.End()
+
+	log := tm.logger.WithFields(logrus.Fields{
+		logging.RequestIDField: taskInfo.RequestID,
+		logging.TraceIDField:   span.SpanContext().TraceID().String(),
+		logging.TaskIDField:    taskInfo.TaskID,
+		logging.SellerIDField:  taskInfo.SellerID,
+	})
+
+	_, err := tm.usecase.UpdateTaskState(ctx, taskInfo.TaskID, "IN PROGRESS")
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to update task state")
 		return
 	}
 
+	log.WithField("dry_run", taskInfo.DryRun).Info("Task started")
+
 	taskStats := new(models.TaskStats)
 	taskStats.TaskID = taskInfo.TaskID
 
 	fileStatsQueue := make(chan models.TaskStats, 100)
 	endFileStats := make(chan struct{})
 	var wg sync.WaitGroup
+	var rowErrors []models.RowError
+	var batchErr error
+
+	// source is fetched before files are processed, task without its source
+	// is FAILED and changes nothing
+	if taskInfo.SourceURL != "" {
+		source, err := tm.fetchSource(ctx, taskInfo.SourceURL, taskInfo.SourceSHA256)
+		if err != nil {
+			tracing.RecordError(span, err)
+
+			if ctx.Err() != nil {
+				log.WithError(err).Warn("Task cancelled")
+				tm.cancelTask(taskInfo.TaskID, log)
+				return
+			}
+
+			log.WithError(err).Error("Failed to fetch source")
+			tm.failTask(ctx, taskInfo.TaskID, log)
+			return
+		}
+		defer source.remove()
+
+		wg.Add(1)
+		go tm.uploadFileProducer(ctx, source.name, source.open, taskInfo, fileStatsQueue, &wg,
+			log.WithField(logging.FileField, source.name))
+	}
 
 	for _, fheaders := range taskInfo.Files {
 		for _, hdr := range fheaders {
+			hdr := hdr
+
 			wg.Add(1)
 			// for every file launch goroutine
-			go tm.uploadFileProducer(hdr, taskInfo, fileStatsQueue, &wg)
+			go tm.uploadFileProducer(ctx, hdr.Filename, func() (io.ReadCloser, error) { return hdr.Open() },
+				taskInfo, fileStatsQueue, &wg, log.WithField(logging.FileField, hdr.Filename))
 		}
 	}
 
+	if len(taskInfo.Products) > 0 {
+		wg.Add(1)
+		go tm.uploadBatchProducer(ctx, taskInfo, fileStatsQueue, &rowErrors, &batchErr, &wg, log)
+	}
+
 	// concurrently processing stats of every file
 	go func() {
 		for fileStats := range fileStatsQueue {
@@ -88,26 +203,95 @@ func (tm *taskManager) uploadUserFilesPackProducer(taskInfo *models.Task, statsQ
 	// wait until all statistics saves after all file uploads
 	<-endFileStats
 
-	statsQueue <- *taskStats
+	if err := ctx.Err(); err != nil {
+		tracing.RecordError(span, err)
+		log.WithError(err).Warn("Task cancelled")
+		tm.cancelTask(taskInfo.TaskID, log)
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"products_created": taskStats.ProductsCreated,
+		"products_updated": taskStats.ProductsUpdated,
+		"products_deleted": taskStats.ProductsDeleted,
+		"rows_with_errors": taskStats.RowsWithErrors,
+	}).Info("Task files processed")
+
+	// errors of rows are saved before task is finished, so they are
+	// available with its stats
+	if len(rowErrors) > 0 {
+		if _, err := tm.usecase.CreateTaskErrors(ctx, taskInfo.TaskID, rowErrors); err != nil {
+			tracing.RecordError(span, err)
+			if batchErr == nil {
+				batchErr = err
+			}
+		}
+	}
+
+	// products changed before error of batch are kept, so task is FAILED
+	// with their stats
+	if batchErr != nil {
+		log.WithError(batchErr).Error("Failed to upload batch")
+		tm.savePartialStats(taskStats, log)
+		tm.failTask(ctx, taskInfo.TaskID, log)
+
+		if taskInfo.Result != nil {
+			taskInfo.Result <- models.BatchResult{
+				TaskID: taskInfo.TaskID,
+				State:  "FAILED",
+				Stats:  *taskStats,
+				Errors: rowErrors,
+			}
+		}
+		return
+	}
+
+	if taskInfo.Result == nil {
+		statsQueue <- *taskStats
+		return
+	}
+
+	// stats of synchronous task are saved before result is sent, so task is
+	// DONE for client which got result
+	if err := tm.uploadStatsProducer(*taskStats); err != nil {
+		return
+	}
+
+	taskInfo.Result <- models.BatchResult{
+		TaskID: taskInfo.TaskID,
+		State:  "DONE",
+		Stats:  *taskStats,
+		Errors: rowErrors,
+	}
 }
 
-// uploadFileProducer - get file and concurrently upload all info of every sheet in file
-func (tm *taskManager) uploadFileProducer(hdr *multipart.FileHeader, taskInfo *models.Task,
-	fileStatsQueue chan models.TaskStats, wg *sync.WaitGroup) {
+// uploadFileProducer - get file and concurrently upload all info of every
+// sheet in file, file is uploaded file of form or source of task
+func (tm *taskManager) uploadFileProducer(ctx context.Context, file string, open func() (io.ReadCloser, error),
+	taskInfo *models.Task, fileStatsQueue chan models.TaskStats, wg *sync.WaitGroup, log *logrus.Entry) {
 
 	defer wg.Done()
 
+	ctx, span := tracing.Tracer().Start(ctx, "task.file",
+		trace.WithAttributes(tracing.FileKey.String(file)))
+	defer span.End()
+
 	var sheetWG sync.WaitGroup
 
-	fd, err := hdr.Open()
+	fd, err := open()
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to open file")
 		return
 	}
+	defer fd.Close()
 
+	_, parseSpan := tracing.Tracer().Start(ctx, "xlsx.open")
 	f, err := excelize.OpenReader(fd)
+	parseSpan.End()
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to read xlsx file")
 		return
 	}
 
@@ -115,95 +299,429 @@ func (tm *taskManager) uploadFileProducer(hdr *multipart.FileHeader, taskInfo *m
 	for _, sheet := range sheets {
 		sheetWG.Add(1)
 		// for every sheet launch goroutine
-		go tm.uploadFileSheetProducer(f, taskInfo, sheet, fileStatsQueue, &sheetWG)
+		go tm.uploadFileSheetProducer(ctx, f, taskInfo, file, sheet, fileStatsQueue, &sheetWG,
+			log.WithField(logging.SheetField, sheet))
 	}
 
 	sheetWG.Wait()
 }
 
 // uploadFileSheetProducer - process upload data in sheet
-func (tm *taskManager) uploadFileSheetProducer(f *excelize.File, taskInfo *models.Task, sheet string,
-	fileStatsQueue chan models.TaskStats, sheetWG *sync.WaitGroup) {
+func (tm *taskManager) uploadFileSheetProducer(ctx context.Context, f *excelize.File, taskInfo *models.Task,
+	file, sheet string, fileStatsQueue chan models.TaskStats, sheetWG *sync.WaitGroup, log *logrus.Entry) {
 
 	defer sheetWG.Done()
 
-	fileStats := new(models.TaskStats)
+	// sheet waits for worker before its rows are read
+	if err := tm.workers.acquire(ctx); err != nil {
+		return
+	}
+	defer tm.workers.release()
+
+	ctx, span := tracing.Tracer().Start(ctx, "task.sheet",
+		trace.WithAttributes(tracing.SheetKey.String(sheet)))
+	defer span.End()
 
+	_, parseSpan := tracing.Tracer().Start(ctx, "xlsx.getRows")
 	rows, err := f.GetRows(sheet)
+	parseSpan.End()
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to read sheet rows")
 		return
 	}
 
-	for _, row := range rows {
+	// columns of sheet with header are found by names, otherwise they are
+	// in order of tools.XlsxColumns
+	mapping, withHeader, start := tools.DefaultXlsxMapping(), false, 0
+	if len(rows) > 0 {
+		if header, ok := tools.ParseXlsxHeader(rows[0]); ok {
+			mapping, withHeader, start = header, true, 1
+		}
+	}
+
+	// rows after first empty one are not uploaded
+	end := len(rows)
+	for i, row := range rows {
 		if len(row) == 0 {
+			end = i
 			break
 		}
+	}
+
+	fileStats, _, err := tm.uploadRows(ctx, taskInfo, file, sheet, start, end,
+		func(i int) (*models.ProductInfo, error) {
+			if withHeader {
+				return tools.ConvertMappedXlsxRowToProductInfo(mapping, rows[i], taskInfo.SellerID, taskInfo.Currency)
+			}
+			return tools.ConvertXlsxRowToProductInfo(rows[i], taskInfo.SellerID, taskInfo.Currency)
+		}, log)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return
+	}
+
+	fileStatsQueue <- *fileStats
+}
+
+// uploadBatchProducer - process products of batch upload, they are rows of
+// sheet without file and sheet name. Errors of rows are saved to rowErrors,
+// error which stopped batch is saved to batchErr, stats of products changed
+// before it are sent anyway
+func (tm *taskManager) uploadBatchProducer(ctx context.Context, taskInfo *models.Task,
+	fileStatsQueue chan models.TaskStats, rowErrors *[]models.RowError, batchErr *error, wg *sync.WaitGroup,
+	log *logrus.Entry) {
 
-		productInfo, err := tools.ConvertXlsxRowToProductInfo(row, taskInfo.SellerID)
+	defer wg.Done()
+
+	// batch waits for worker as one sheet
+	if err := tm.workers.acquire(ctx); err != nil {
+		return
+	}
+	defer tm.workers.release()
+
+	ctx, span := tracing.Tracer().Start(ctx, "task.batch")
+	defer span.End()
+
+	batchStats, errs, err := tm.uploadRows(ctx, taskInfo, "", "", 0, len(taskInfo.Products),
+		func(i int) (*models.ProductInfo, error) {
+			return tools.ConvertJSONToProductInfo(taskInfo.Products[i], taskInfo.SellerID, taskInfo.Currency)
+		}, log)
+	*rowErrors = errs
+	if err != nil {
+		tracing.RecordError(span, err)
+		*batchErr = err
+	}
+
+	fileStatsQueue <- *batchStats
+}
+
+// uploadRows - create, update or delete products of rows from start to end,
+// row i is converted to product by convert and has number i+1. Invalid rows
+// are counted in stats and returned as errors of rows with offer id of
+// product, if convert returned it with error, in dry run preview of
+// rows is saved instead of changes. Error is returned if product can not be
+// changed or preview can not be saved, rest of rows are not uploaded then
+// and stats of rows uploaded before error are returned with it
+func (tm *taskManager) uploadRows(ctx context.Context, taskInfo *models.Task, file, sheet string, start, end int,
+	convert func(i int) (*models.ProductInfo, error), log *logrus.Entry) (*models.TaskStats, []models.RowError, error) {
+
+	fileStats := new(models.TaskStats)
+	preview := []*models.PreviewItem{}
+	rowErrors := []models.RowError{}
+
+	for i := start; i < end && ctx.Err() == nil; i++ {
+		row := int64(i + 1)
+		rowLog := log.WithField(logging.RowField, row)
+
+		// changes of products are saved in history with row they came from
+		rowCtx := businessConnService.WithChangeSource(ctx, models.ChangeSource{
+			TaskID:    taskInfo.TaskID,
+			File:      file,
+			Sheet:     sheet,
+			Row:       row,
+			RequestID: taskInfo.RequestID,
+		})
+
+		productInfo, err := convert(i)
 		if err != nil {
 			fileStats.RowsWithErrors++
 
-			tm.logger.WithField("ErrInfo", err.Error()).Info("InternalError")
+			var offerID int64
+			if productInfo != nil {
+				offerID = productInfo.OfferID
+			}
+
+			rowLog.WithError(err).Info("Invalid row")
+
+			rowErrors = append(rowErrors, newRowError(row, offerID, err))
+
+			if taskInfo.DryRun {
+				preview = append(preview, &models.PreviewItem{
+					TaskID:   taskInfo.TaskID,
+					SellerID: taskInfo.SellerID,
+					OfferID:  offerID,
+					Action:   models.PreviewError,
+					Error:    err.Error(),
+					File:     file,
+					Sheet:    sheet,
+					Row:      row,
+				})
+			}
 			continue
 		}
 
-		productRecord, err := tm.usecase.SelectProduct(productInfo.SellerID, productInfo.OfferID)
-		switch {
-		case err == sql.ErrNoRows && productInfo.Available:
-			rowsAffected, err := tm.usecase.CreateProduct(productInfo)
+		rowLog = rowLog.WithField(logging.OfferIDField, productInfo.OfferID)
+
+		if taskInfo.DryRun {
+			item, err := tm.previewProduct(ctx, productInfo, fileStats)
 			if err != nil {
-				tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-				return
+				rowLog.WithError(err).Error("Failed to select product")
+				return fileStats, rowErrors, err
 			}
 
-			fileStats.ProductsCreated += rowsAffected
-			continue
-		case err == sql.ErrNoRows && !productInfo.Available:
-			tm.logger.WithField("ErrInfo err", "No such products to delete").Error("InternalError")
+			item.TaskID = taskInfo.TaskID
+			item.File = file
+			item.Sheet = sheet
+			item.Row = row
+
+			preview = append(preview, item)
 			continue
-		case err != nil:
-			tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-			return
 		}
 
-		if !productInfo.Available {
-			rowsAffected, err := tm.usecase.DeleteProduct(productInfo.SellerID, productInfo.OfferID)
-			if err != nil {
-				tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-				return
-			}
+		if err := tm.uploadProduct(ctx, rowCtx, productInfo, fileStats, rowLog); err != nil {
+			return fileStats, rowErrors, err
+		}
+	}
 
-			fileStats.ProductsDeleted += rowsAffected
-		} else {
-			productRecord.Name = productInfo.Name
-			productRecord.Price = productInfo.Price
-			productRecord.Quantity = productInfo.Quantity
+	if len(preview) > 0 {
+		if _, err := tm.usecase.CreateTaskPreview(ctx, preview); err != nil {
+			log.WithError(err).Error("Failed to save preview")
+			return fileStats, rowErrors, err
+		}
+	}
 
-			rowsAffected, err := tm.usecase.UpdateProduct(productRecord)
-			if err != nil {
-				tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
-				return
-			}
+	return fileStats, rowErrors, nil
+}
+
+// uploadProduct - create, update or delete product from row and count it in
+// stats, changes are saved in history with source of rowCtx
+func (tm *taskManager) uploadProduct(ctx, rowCtx context.Context, productInfo *models.ProductInfo,
+	fileStats *models.TaskStats, rowLog *logrus.Entry) error {
+
+	productRecord, err := tm.usecase.SelectProduct(ctx, productInfo.SellerID, productInfo.OfferID)
+	if err != nil && !errors.Is(err, domainErrors.ErrNotFound) {
+		rowLog.WithError(err).Error("Failed to select product")
+		return err
+	}
+
+	switch {
+	case isMissing(productRecord, err) && productInfo.Available:
+		// deleted product which is not purged yet is revived by create
+		rowsAffected, err := tm.usecase.CreateProduct(rowCtx, productInfo)
+		if err != nil {
+			rowLog.WithError(err).Error("Failed to create product")
+			return err
+		}
 
-			fileStats.ProductsUpdated += rowsAffected
+		fileStats.ProductsCreated += rowsAffected
+		return nil
+	case isMissing(productRecord, err):
+		rowLog.Info("No such product to delete")
+		return nil
+	case !productInfo.Available:
+		rowsAffected, err := tm.usecase.DeleteProduct(rowCtx, productInfo.SellerID, productInfo.OfferID, 0)
+		if err != nil {
+			rowLog.WithError(err).Error("Failed to delete product")
+			return err
 		}
+
+		fileStats.ProductsDeleted += rowsAffected
+		return nil
 	}
 
-	fileStatsQueue <- *fileStats
+	tools.MergeProductInfo(productRecord, productInfo)
+
+	rowsAffected, err := tm.usecase.UpdateProduct(rowCtx, productRecord)
+	if err != nil {
+		rowLog.WithError(err).Error("Failed to update product")
+		return err
+	}
+
+	fileStats.ProductsUpdated += rowsAffected
+	return nil
 }
 
-// uploadStatsProducer - upload stats in DB and change task state to DONE
-func (tm *taskManager) uploadStatsProducer(stats models.TaskStats) {
-	_, err := tm.usecase.UpdateTaskState(stats.TaskID, "DONE")
+// isMissing - product selected for row does not exist or is deleted, then
+// row creates it or is skipped if it deletes product
+func isMissing(productRecord *models.ProductInfo, err error) bool {
+	return errors.Is(err, domainErrors.ErrNotFound) || productRecord.DeletedAt != nil
+}
+
+// newRowError - error of invalid row, invalid fields of validation error
+// are described in details
+func newRowError(row, offerID int64, err error) models.RowError {
+	rowError := models.RowError{
+		Row:     row,
+		OfferID: offerID,
+		Error:   err.Error(),
+	}
+
+	for _, field := range domainErrors.Fields(err) {
+		rowError.Details = append(rowError.Details, models.ErrorDetail{Field: field.Field, Message: field.Message})
+	}
+
+	return rowError
+}
+
+// previewProduct - find what upload would do with product from row without
+// changing it, stats are counted as for real upload
+func (tm *taskManager) previewProduct(ctx context.Context, productInfo *models.ProductInfo,
+	fileStats *models.TaskStats) (*models.PreviewItem, error) {
+
+	item := &models.PreviewItem{
+		SellerID: productInfo.SellerID,
+		OfferID:  productInfo.OfferID,
+	}
+
+	productRecord, err := tm.usecase.SelectProduct(ctx, productInfo.SellerID, productInfo.OfferID)
+	if err != nil && !errors.Is(err, domainErrors.ErrNotFound) {
+		return nil, err
+	}
+
+	switch {
+	case isMissing(productRecord, err) && productInfo.Available:
+		item.Action = models.ProductCreated
+		item.NewValue = productInfo
+		fileStats.ProductsCreated++
+	case isMissing(productRecord, err):
+		item.Action = models.PreviewSkipped
+	case !productInfo.Available:
+		item.Action = models.ProductDeleted
+		item.OldValue = productRecord
+		fileStats.ProductsDeleted++
+	default:
+		// the same fields are changed by update of real upload
+		updated := *productRecord
+		tools.MergeProductInfo(&updated, productInfo)
+
+		item.Action = models.ProductUpdated
+		item.OldValue = productRecord
+		item.NewValue = &updated
+		item.Changes = tools.DiffProductInfo(productRecord, &updated)
+		fileStats.ProductsUpdated++
+	}
+
+	return item, nil
+}
+
+// rollbackProducer - revert changes of products made by other task, changes
+// which conflict with later changes of product are skipped
+func (tm *taskManager) rollbackProducer(taskInfo *models.Task, statsQueue chan models.TaskStats) {
+	ctx, span := tracing.Tracer().Start(tm.ctx, "task.rollback",
+		trace.WithLinks(tracing.LinkFromCarrier(taskInfo.TraceCarrier)),
+		trace.WithAttributes(
+			tracing.TaskIDKey.Int64(taskInfo.TaskID),
+			tracing.RollbackTaskIDKey.Int64(taskInfo.RollbackTaskID),
+		),
+	)
+	defer span.End()
+
+	log := tm.logger.WithFields(logrus.Fields{
+		logging.RequestIDField:      taskInfo.RequestID,
+		logging.TraceIDField:        span.SpanContext().TraceID().String(),
+		logging.TaskIDField:         taskInfo.TaskID,
+		logging.RollbackTaskIDField: taskInfo.RollbackTaskID,
+	})
+
+	_, err := tm.usecase.UpdateTaskState(ctx, taskInfo.TaskID, "IN PROGRESS")
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to update task state")
 		return
 	}
 
-	_, err = tm.usecase.CreateTaskStats(&stats)
+	log.Info("Rollback started")
+
+	// reverting changes are saved in history as changes of this task
+	ctx = businessConnService.WithChangeSource(ctx, models.ChangeSource{
+		TaskID:    taskInfo.TaskID,
+		RequestID: taskInfo.RequestID,
+	})
+
+	taskStats, conflicts, err := tm.usecase.RollbackTask(ctx, taskInfo.RollbackTaskID)
+
+	for _, conflict := range conflicts {
+		log.WithFields(logrus.Fields{
+			logging.SellerIDField: conflict.SellerID,
+			logging.OfferIDField:  conflict.OfferID,
+			"action":              conflict.Action,
+		}).Warn("Product was changed after task, change is not reverted")
+	}
+
 	if err != nil {
-		tm.logger.WithField("ErrInfo", err.Error()).Error("InternalError")
+		tracing.RecordError(span, err)
+
+		// changes reverted before error are kept, so their stats are saved
+		if taskStats != nil {
+			taskStats.TaskID = taskInfo.TaskID
+			tm.savePartialStats(taskStats, log)
+		}
+
+		if ctx.Err() != nil {
+			log.WithError(err).Warn("Task cancelled")
+			tm.cancelTask(taskInfo.TaskID, log)
+			return
+		}
+
+		log.WithError(err).Error("Failed to rollback task")
+		tm.failTask(ctx, taskInfo.TaskID, log)
 		return
 	}
+
+	taskStats.TaskID = taskInfo.TaskID
+
+	log.WithFields(logrus.Fields{
+		"products_created": taskStats.ProductsCreated,
+		"products_updated": taskStats.ProductsUpdated,
+		"products_deleted": taskStats.ProductsDeleted,
+		"rows_with_errors": taskStats.RowsWithErrors,
+	}).Info("Task changes reverted")
+
+	statsQueue <- *taskStats
+}
+
+// uploadStatsProducer - upload stats in DB and change task state to DONE
+func (tm *taskManager) uploadStatsProducer(stats models.TaskStats) error {
+	ctx, span := tracing.Tracer().Start(tm.ctx, "task.stats",
+		trace.WithAttributes(tracing.TaskIDKey.Int64(stats.TaskID)))
+	defer span.End()
+
+	log := tm.logger.WithField(logging.TaskIDField, stats.TaskID)
+
+	_, err := tm.usecase.UpdateTaskState(ctx, stats.TaskID, "DONE")
+	if err != nil {
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to update task state")
+		return err
+	}
+
+	_, err = tm.usecase.CreateTaskStats(ctx, &stats)
+	if err != nil {
+		tracing.RecordError(span, err)
+		log.WithError(err).Error("Failed to save task stats")
+		return err
+	}
+
+	log.Info("Task done")
+	return nil
+}
+
+// failTask - save FAILED state of task which can not be processed
+func (tm *taskManager) failTask(ctx context.Context, taskID int64, log *logrus.Entry) {
+	if _, err := tm.usecase.UpdateTaskState(ctx, taskID, "FAILED"); err != nil {
+		log.WithError(err).Error("Failed to update task state")
+	}
+}
+
+// savePartialStats - save stats of task which is not done, context of task
+// can be cancelled, so stats are saved with new one
+func (tm *taskManager) savePartialStats(stats *models.TaskStats, log *logrus.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelStateTimeout)
+	defer cancel()
+
+	if _, err := tm.usecase.CreateTaskStats(ctx, stats); err != nil {
+		log.WithError(err).Error("Failed to save task stats")
+	}
+}
+
+// cancelTask - save CANCELLED state of task, context of task is already
+// cancelled, so state is saved with new one
+func (tm *taskManager) cancelTask(taskID int64, log *logrus.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelStateTimeout)
+	defer cancel()
+
+	if _, err := tm.usecase.UpdateTaskState(ctx, taskID, "CANCELLED"); err != nil {
+		log.WithError(err).Error("Failed to update task state")
+	}
 }
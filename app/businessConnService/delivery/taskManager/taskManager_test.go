@@ -0,0 +1,363 @@
+package taskManager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/repository"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/usecase"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newXlsx - xlsx file having rows on its first sheet
+func newXlsx(t *testing.T, rows [][]interface{}) []byte {
+	f := excelize.NewFile()
+	for i, row := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &row); err != nil {
+			t.Fatalf("Can`t write xlsx: %s", err)
+		}
+	}
+
+	xlsx, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("Can`t write xlsx: %s", err)
+	}
+
+	return xlsx.Bytes()
+}
+
+// newXlsxFiles - files of upload form with one xlsx file having rows on
+// its first sheet
+func newXlsxFiles(t *testing.T, rows [][]interface{}) map[string][]*multipart.FileHeader {
+	xlsx := newXlsx(t, rows)
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("products", "products.xlsx")
+	if err != nil {
+		t.Fatalf("Can`t create form: %s", err)
+	}
+	part.Write(xlsx)
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("Can`t read form: %s", err)
+	}
+
+	return form.File
+}
+
+// waitTaskStats - wait until stats of task are saved
+func waitTaskStats(t *testing.T, us businessConnService.IUsecase, taskID int64) *models.TaskStats {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if stats, err := us.SelectTaskStatsByTaskID(context.Background(), taskID); err == nil {
+			return stats
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("Task %d is not finished", taskID)
+	return nil
+}
+
+func TestTaskManagerUpload(t *testing.T) {
+	us := usecase.NewUsecase(repository.NewInMemoryRepository())
+
+	taskQueue := make(chan models.Task)
+	stopCh := make(chan struct{})
+
+	tm := NewTaskManager(us, taskQueue, make(chan models.TaskStats, 10), stopCh, Config{}, logrus.New())
+
+	go tm.TaskManager()
+	defer func() { stopCh <- struct{}{} }()
+
+	ctx := context.Background()
+
+	if _, err := us.CreateProduct(ctx, &models.ProductInfo{SellerID: 1, OfferID: 3, Name: "утюг",
+		Currency: "RUB", Quantity: 1, Available: true}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// test upload creates, updates and deletes products
+
+	taskID, err := us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{
+		TaskID:   taskID,
+		SellerID: 1,
+		Currency: models.DefaultCurrency,
+		Files: newXlsxFiles(t, [][]interface{}{
+			{1, "телефон", "100.25", 10, true},
+			{2, "телевизор", "57.6", 15, true},
+			{3, "утюг", "10", 1, false},
+			{4, "", "10", 1, true},
+		}),
+	}
+
+	stats := waitTaskStats(t, us, taskID)
+	assert.Equal(t, &models.TaskStats{TaskID: taskID, ProductsCreated: 2, ProductsDeleted: 1, RowsWithErrors: 1},
+		stats)
+
+	products, err := us.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	if assert.NoError(t, err) && assert.Len(t, products, 2) {
+		assert.Equal(t, "телефон", products[0].Name)
+		assert.Equal(t, "100.25", products[0].Price.String())
+		assert.Equal(t, "телевизор", products[1].Name)
+	}
+
+	// test rollback reverts changes of upload
+
+	rollbackTaskID, err := us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{TaskID: rollbackTaskID, SellerID: 1, RollbackTaskID: taskID}
+
+	stats = waitTaskStats(t, us, rollbackTaskID)
+	assert.Equal(t, int64(0), stats.RowsWithErrors)
+
+	products, err = us.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	if assert.NoError(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "утюг", products[0].Name)
+	}
+}
+
+func TestTaskManagerUploadBatch(t *testing.T) {
+	us := usecase.NewUsecase(repository.NewInMemoryRepository())
+
+	taskQueue := make(chan models.Task)
+	stopCh := make(chan struct{})
+
+	tm := NewTaskManager(us, taskQueue, make(chan models.TaskStats, 10), stopCh, Config{}, logrus.New())
+
+	go tm.TaskManager()
+	defer func() { stopCh <- struct{}{} }()
+
+	ctx := context.Background()
+
+	if _, err := us.CreateProduct(ctx, &models.ProductInfo{SellerID: 1, OfferID: 3, Name: "утюг",
+		Currency: "RUB", Quantity: 1, Available: true}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskID, err := us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// test result of synchronous batch is sent when task is done
+
+	result := make(chan models.BatchResult, 1)
+
+	taskQueue <- models.Task{
+		TaskID:   taskID,
+		SellerID: 1,
+		Currency: models.DefaultCurrency,
+		Products: []json.RawMessage{
+			json.RawMessage(`{"offer_id":1,"name":"телефон","price":"100.25","quantity":10}`),
+			json.RawMessage(`{"offer_id":3,"name":"утюг","price":"10","available":false}`),
+			json.RawMessage(`{"offer_id":4,"price":"10"}`),
+			json.RawMessage(`{"offer_id":`),
+		},
+		Result: result,
+	}
+
+	select {
+	case res, ok := <-result:
+		if assert.True(t, ok) {
+			assert.Equal(t, "DONE", res.State)
+			assert.Equal(t, models.TaskStats{TaskID: taskID, ProductsCreated: 1, ProductsDeleted: 1, RowsWithErrors: 2},
+				res.Stats)
+			if assert.Len(t, res.Errors, 2) {
+				assert.Equal(t, int64(3), res.Errors[0].Row)
+				assert.Equal(t, int64(4), res.Errors[0].OfferID)
+				assert.Equal(t, []models.ErrorDetail{{Field: "name", Message: "must not be empty"}},
+					res.Errors[0].Details)
+				assert.Equal(t, int64(4), res.Errors[1].Row)
+				assert.Equal(t, int64(0), res.Errors[1].OfferID)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Task %d is not finished", taskID)
+	}
+
+	state, err := us.SelectTaskState(ctx, taskID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "DONE", state.State)
+	}
+
+	products, err := us.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	if assert.NoError(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "телефон", products[0].Name)
+	}
+
+	// test errors of rows are saved with task
+
+	rowErrors, err := us.SelectTaskErrors(ctx, taskID)
+	if assert.NoError(t, err) && assert.Len(t, rowErrors, 2) {
+		assert.Equal(t, int64(3), rowErrors[0].Row)
+		assert.Equal(t, int64(4), rowErrors[1].Row)
+	}
+}
+
+func TestTaskManagerUploadDeletedProduct(t *testing.T) {
+	us := usecase.NewUsecase(repository.NewInMemoryRepository())
+
+	taskQueue := make(chan models.Task)
+	stopCh := make(chan struct{})
+
+	tm := NewTaskManager(us, taskQueue, make(chan models.TaskStats, 10), stopCh, Config{}, logrus.New())
+
+	go tm.TaskManager()
+	defer func() { stopCh <- struct{}{} }()
+
+	ctx := context.Background()
+
+	for _, offerID := range []int64{1, 2} {
+		if _, err := us.CreateProduct(ctx, &models.ProductInfo{SellerID: 1, OfferID: offerID, Name: "утюг",
+			Currency: "RUB", Quantity: 1, Available: true}); err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+		if _, err := us.DeleteProduct(ctx, 1, offerID, 0); err != nil {
+			t.Fatalf("unexpected err: %s", err)
+		}
+	}
+
+	rows := [][]interface{}{
+		{1, "телефон", "100", 10, true},
+		{2, "утюг", "10", 1, false},
+	}
+
+	// test preview reports deleted product as created and its delete as skipped
+
+	taskID, err := us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{TaskID: taskID, SellerID: 1, Currency: models.DefaultCurrency,
+		Files: newXlsxFiles(t, rows), DryRun: true}
+
+	stats := waitTaskStats(t, us, taskID)
+	assert.Equal(t, &models.TaskStats{TaskID: taskID, ProductsCreated: 1}, stats)
+
+	preview, err := us.SelectTaskPreview(ctx, taskID)
+	if assert.NoError(t, err) && assert.Len(t, preview, 2) {
+		assert.Equal(t, models.ProductCreated, preview[0].Action)
+		assert.Equal(t, models.PreviewSkipped, preview[1].Action)
+	}
+
+	// test re-upload of deleted product creates it again
+
+	taskID, err = us.CreateTask(ctx)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	taskQueue <- models.Task{TaskID: taskID, SellerID: 1, Currency: models.DefaultCurrency,
+		Files: newXlsxFiles(t, rows)}
+
+	stats = waitTaskStats(t, us, taskID)
+	assert.Equal(t, &models.TaskStats{TaskID: taskID, ProductsCreated: 1}, stats)
+
+	products, err := us.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	if assert.NoError(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "телефон", products[0].Name)
+		assert.Equal(t, int64(3), products[0].Version)
+	}
+}
+
+func TestRollbackProducerFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	us := businessConnService.NewMockIUsecase(ctrl)
+
+	statsQueue := make(chan models.TaskStats, 1)
+
+	tm := NewTaskManager(us, nil, statsQueue, nil, Config{}, logrus.New())
+
+	// test stats of changes reverted before error are saved and task is FAILED
+
+	gomock.InOrder(
+		us.EXPECT().UpdateTaskState(gomock.Any(), int64(2), "IN PROGRESS").Return(int64(1), nil),
+		us.EXPECT().RollbackTask(gomock.Any(), int64(1)).
+			Return(&models.TaskStats{ProductsUpdated: 1}, []*models.ProductChange{}, errors.New("db error")),
+		us.EXPECT().CreateTaskStats(gomock.Any(), &models.TaskStats{TaskID: 2, ProductsUpdated: 1}).
+			Return(int64(1), nil),
+		us.EXPECT().UpdateTaskState(gomock.Any(), int64(2), "FAILED").Return(int64(1), nil),
+	)
+
+	tm.rollbackProducer(&models.Task{TaskID: 2, RollbackTaskID: 1}, statsQueue)
+
+	assert.Empty(t, statsQueue)
+}
+
+func TestUploadBatchFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	us := businessConnService.NewMockIUsecase(ctrl)
+
+	tm := NewTaskManager(us, nil, make(chan models.TaskStats, 1), nil, Config{}, logrus.New())
+
+	// test task is FAILED with stats of products created before error
+
+	gomock.InOrder(
+		us.EXPECT().UpdateTaskState(gomock.Any(), int64(2), "IN PROGRESS").Return(int64(1), nil),
+		us.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(1)).
+			Return(nil, domainErrors.NotFound("product not found")),
+		us.EXPECT().CreateProduct(gomock.Any(), gomock.Any()).Return(int64(1), nil),
+		us.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).
+			Return(nil, domainErrors.NotFound("product not found")),
+		us.EXPECT().CreateProduct(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db error")),
+		us.EXPECT().CreateTaskStats(gomock.Any(), &models.TaskStats{TaskID: 2, ProductsCreated: 1}).
+			Return(int64(1), nil),
+		us.EXPECT().UpdateTaskState(gomock.Any(), int64(2), "FAILED").Return(int64(1), nil),
+	)
+
+	result := make(chan models.BatchResult, 1)
+
+	tm.uploadUserFilesPackProducer(&models.Task{
+		TaskID:   2,
+		SellerID: 1,
+		Currency: models.DefaultCurrency,
+		Products: []json.RawMessage{
+			json.RawMessage(`{"offer_id":1,"name":"телефон","price":"100.25","quantity":10}`),
+			json.RawMessage(`{"offer_id":2,"name":"телевизор","price":"57.6","quantity":15}`),
+		},
+		Result: result,
+	}, tm.statsQueue)
+
+	res, ok := <-result
+	if assert.True(t, ok) {
+		assert.Equal(t, models.BatchResult{
+			TaskID: 2,
+			State:  "FAILED",
+			Stats:  models.TaskStats{TaskID: 2, ProductsCreated: 1},
+			Errors: []models.RowError{},
+		}, res)
+	}
+
+	assert.Empty(t, tm.statsQueue)
+}
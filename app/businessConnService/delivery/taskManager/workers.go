@@ -0,0 +1,67 @@
+package taskManager
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool - limit of sheets processed at the same time by all tasks, its
+// size can be changed while tasks are running. Sheets which are running
+// when size is decreased are finished, new ones wait for free worker
+type workerPool struct {
+	mu sync.Mutex
+	// size - max running sheets, zero means without limit
+	size    int
+	running int
+	// free is closed and replaced when worker is released or size is
+	// changed, so that waiting sheets check limit again
+	free chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: size, free: make(chan struct{})}
+}
+
+// acquire - wait for free worker, error is returned if ctx is done before
+func (pool *workerPool) acquire(ctx context.Context) error {
+	for {
+		pool.mu.Lock()
+		if pool.size <= 0 || pool.running < pool.size {
+			pool.running++
+			pool.mu.Unlock()
+			return nil
+		}
+		free := pool.free
+		pool.mu.Unlock()
+
+		select {
+		case <-free:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release - return worker taken by acquire
+func (pool *workerPool) release() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.running--
+	pool.notify()
+}
+
+// setSize - change max running sheets, zero means without limit
+func (pool *workerPool) setSize(size int) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.size = size
+	pool.notify()
+}
+
+// notify - wake up waiting sheets, mu must be locked
+func (pool *workerPool) notify() {
+	close(pool.free)
+	pool.free = make(chan struct{})
+}
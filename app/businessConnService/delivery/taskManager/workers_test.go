@@ -0,0 +1,58 @@
+package taskManager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(1)
+	ctx := context.Background()
+
+	assert.NoError(t, pool.acquire(ctx))
+
+	// test second worker waits until first one is released
+
+	acquired := make(chan struct{})
+	go func() {
+		if assert.NoError(t, pool.acquire(ctx)) {
+			close(acquired)
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("Worker is acquired over limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pool.release()
+	<-acquired
+
+	// test increased size lets waiting worker in without release
+
+	acquired = make(chan struct{})
+	go func() {
+		if assert.NoError(t, pool.acquire(ctx)) {
+			close(acquired)
+		}
+	}()
+
+	pool.setSize(2)
+	<-acquired
+
+	// test waiting is stopped by context
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	assert.Error(t, pool.acquire(ctx))
+
+	// test zero size means without limit
+
+	pool.setSize(0)
+	assert.NoError(t, pool.acquire(context.Background()))
+}
@@ -5,22 +5,62 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
 )
 
+// RequestIDHeader - header used to receive request id from client and
+// return it back in response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - longer ids from clients are replaced by generated one
+const maxRequestIDLength = 128
+
+// statusRecorder - wrapper of ResponseWriter that remembers status code
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
 // LogRequestMiddleware - middleware to log every request to server with information
-// about method, uri, duration and user agent
+// about method, uri, status, duration and user agent. It also takes request id
+// from X-Request-ID header (or generates new one), returns it in response and
+// saves it with request scoped log entry in request context
 func LogRequestMiddleware(logger *logrus.Logger, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now().UTC()
 
-		next(w, r)
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		entry := logger.WithField(logging.RequestIDField, requestID)
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.IsValid() {
+			entry = entry.WithField(logging.TraceIDField, spanCtx.TraceID().String())
+		}
+
+		ctx := logging.WithRequestID(r.Context(), requestID)
+		ctx = logging.NewContext(ctx, entry)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(rec, r.WithContext(ctx))
 
 		end := time.Now().UTC()
 		latency := end.Sub(start)
-		logger.WithFields(logrus.Fields{
+		entry.WithFields(logrus.Fields{
 			"method":     r.Method,
 			"request":    r.RequestURI,
 			"remote":     r.RemoteAddr,
+			"status":     rec.status,
 			"duration":   latency,
 			"user-agent": r.UserAgent(),
 		}).Info("Request info")
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket which limits rate of requests of process, its
+// limit can be changed at runtime
+type RateLimiter struct {
+	mu sync.Mutex
+	// rate - tokens added per second, zero means without limit
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now func() time.Time
+}
+
+// NewRateLimiter - create limiter of rate requests per second with bursts
+// of at most burst requests, zero rate means without limit
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	limiter := &RateLimiter{now: time.Now}
+	limiter.SetLimit(rate, burst)
+
+	return limiter
+}
+
+// SetLimit - change limit, bucket is full after change so that requests
+// are not rejected because of previous limit
+func (limiter *RateLimiter) SetLimit(rate float64, burst int) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	// burst less than one token would reject every request
+	limiter.rate = rate
+	limiter.burst = math.Max(float64(burst), 1)
+	limiter.tokens = limiter.burst
+	limiter.last = limiter.now()
+}
+
+// Allow - take token for request, if there is no token it returns time
+// after which it will be
+func (limiter *RateLimiter) Allow() (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if limiter.rate <= 0 {
+		return true, 0
+	}
+
+	now := limiter.now()
+	limiter.tokens = math.Min(limiter.burst, limiter.tokens+now.Sub(limiter.last).Seconds()*limiter.rate)
+	limiter.last = now
+
+	if limiter.tokens < 1 {
+		return false, time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+	}
+
+	limiter.tokens--
+
+	return true, 0
+}
@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// cacheMetrics - hits, misses and evictions of all product caches of
+// process, they are published in /debug/vars
+var cacheMetrics = expvar.NewMap("productCache")
+
+// listKey - key of cached result of SelectProductsBySpecificProductInfo,
+// request is json of UserListRequest
+type listKey struct {
+	sellerID int64
+	request  string
+}
+
+// cacheEntry - element of lru list, nil value of productKey is cached
+// not found product
+type cacheEntry struct {
+	key       interface{}
+	value     interface{}
+	expiresAt time.Time
+}
+
+// replicatedRepository - repository which reads lists from replicas, rows
+// written to it can be stale on replicas for replicaStaleness
+type replicatedRepository interface {
+	replicaStaleness() time.Duration
+}
+
+// cachedRepository - decorator of IRepository which caches products and
+// lists of products in process. Writes go to repository and invalidate
+// cached values, values written by other processes are seen after ttl
+type cachedRepository struct {
+	businessConnService.IRepository
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[interface{}]*list.Element
+	// lists - entries of listKey, they are checked on every write
+	lists map[listKey]*list.Element
+	lru   *list.List
+	// generation is changed by every invalidation, so value read before
+	// invalidation is not cached after it
+	generation uint64
+
+	// staleness - how long rows written to repository can be stale on its
+	// replicas, lists of written seller are read from primary for it, so
+	// stale lists of replica are not cached for ttl
+	staleness time.Duration
+	// writtenAt - time of last write of seller, zero seller is any seller
+	writtenAt map[int64]time.Time
+	// purgedAt - time of last write of all sellers
+	purgedAt time.Time
+
+	now func() time.Time
+}
+
+// NewCachedRepository - create decorator of repo which keeps at most size
+// least recently used products and lists of products for ttl
+func NewCachedRepository(repo businessConnService.IRepository, size int, ttl time.Duration) businessConnService.IRepository {
+	cached := &cachedRepository{
+		IRepository: repo,
+		size:        size,
+		ttl:         ttl,
+		entries:     map[interface{}]*list.Element{},
+		lists:       map[listKey]*list.Element{},
+		lru:         list.New(),
+		writtenAt:   map[int64]time.Time{},
+		now:         time.Now,
+	}
+
+	if replicated, ok := repo.(replicatedRepository); ok {
+		cached.staleness = replicated.replicaStaleness()
+	}
+
+	return cached
+}
+
+// get - cached value of key, expired value is removed
+func (repo *cachedRepository) get(key interface{}) (interface{}, bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	element, ok := repo.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !repo.now().Before(entry.expiresAt) {
+		repo.removeElement(element)
+		return nil, false
+	}
+
+	repo.lru.MoveToFront(element)
+
+	return entry.value, true
+}
+
+// put - cache value of key if nothing was invalidated since generation,
+// least recently used value is evicted if cache is full
+func (repo *cachedRepository) put(key, value interface{}, generation uint64) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if generation != repo.generation {
+		return
+	}
+
+	entry := &cacheEntry{key: key, value: value, expiresAt: repo.now().Add(repo.ttl)}
+
+	if element, ok := repo.entries[key]; ok {
+		element.Value = entry
+		repo.lru.MoveToFront(element)
+		return
+	}
+
+	element := repo.lru.PushFront(entry)
+	repo.entries[key] = element
+	if key, ok := key.(listKey); ok {
+		repo.lists[key] = element
+	}
+
+	for repo.lru.Len() > repo.size {
+		repo.removeElement(repo.lru.Back())
+		cacheMetrics.Add("evictions", 1)
+	}
+}
+
+// currentGeneration - generation of cache before read from repository
+func (repo *cachedRepository) currentGeneration() uint64 {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.generation
+}
+
+// invalidate - remove product and lists which can contain products of
+// seller, zero seller means all products and lists
+func (repo *cachedRepository) invalidate(sellerID, offerID int64) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.generation++
+	repo.markWritten(sellerID)
+
+	if sellerID == 0 {
+		repo.entries = map[interface{}]*list.Element{}
+		repo.lists = map[listKey]*list.Element{}
+		repo.lru.Init()
+		return
+	}
+
+	if element, ok := repo.entries[productKey{sellerID: sellerID, offerID: offerID}]; ok {
+		repo.removeElement(element)
+	}
+
+	for key, element := range repo.lists {
+		if key.sellerID == 0 || key.sellerID == sellerID {
+			repo.removeElement(element)
+		}
+	}
+}
+
+// markWritten - remember write of seller, zero seller means all sellers,
+// writes older than staleness are forgotten. mu must be locked
+func (repo *cachedRepository) markWritten(sellerID int64) {
+	if repo.staleness <= 0 {
+		return
+	}
+
+	now := repo.now()
+
+	for seller, writtenAt := range repo.writtenAt {
+		if now.Sub(writtenAt) >= repo.staleness {
+			delete(repo.writtenAt, seller)
+		}
+	}
+
+	if sellerID == 0 {
+		repo.purgedAt = now
+	}
+	repo.writtenAt[0] = now
+	repo.writtenAt[sellerID] = now
+}
+
+// isRecentlyWritten - rows of seller can be stale on replicas, zero seller
+// is lists of all sellers
+func (repo *cachedRepository) isRecentlyWritten(sellerID int64) bool {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.staleness <= 0 {
+		return false
+	}
+
+	now := repo.now()
+	if now.Sub(repo.purgedAt) < repo.staleness {
+		return true
+	}
+
+	writtenAt, ok := repo.writtenAt[sellerID]
+	return ok && now.Sub(writtenAt) < repo.staleness
+}
+
+// removeElement - remove element from lru list and entries, mu must be locked
+func (repo *cachedRepository) removeElement(element *list.Element) {
+	key := element.Value.(*cacheEntry).key
+
+	repo.lru.Remove(element)
+	delete(repo.entries, key)
+	if key, ok := key.(listKey); ok {
+		delete(repo.lists, key)
+	}
+}
+
+// copyProducts - deep copy of products, callers change returned products, so
+// cached ones are never returned
+func copyProducts(products []*models.ProductInfo) ([]*models.ProductInfo, error) {
+	copied := make([]*models.ProductInfo, 0, len(products))
+	for _, product := range products {
+		product, err := copyProduct(product)
+		if err != nil {
+			return nil, err
+		}
+		copied = append(copied, product)
+	}
+
+	return copied, nil
+}
+
+func (repo *cachedRepository) SelectProduct(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	key := productKey{sellerID: sellerID, offerID: offerID}
+
+	if value, ok := repo.get(key); ok {
+		cacheMetrics.Add("productHits", 1)
+
+		product := value.(*models.ProductInfo)
+		if product == nil {
+			return nil, domainErrors.NotFound("product not found")
+		}
+
+		return copyProduct(product)
+	}
+	cacheMetrics.Add("productMisses", 1)
+
+	generation := repo.currentGeneration()
+
+	product, err := repo.IRepository.SelectProduct(ctx, sellerID, offerID)
+	if errors.Is(err, domainErrors.ErrNotFound) {
+		// rows of upload mostly are new products, so absence is cached too
+		repo.put(key, (*models.ProductInfo)(nil), generation)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := copyProduct(product)
+	if err != nil {
+		return nil, err
+	}
+	repo.put(key, cached, generation)
+
+	return product, nil
+}
+
+func (repo *cachedRepository) SelectProductsBySpecificProductInfo(ctx context.Context, userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+	request, err := json.Marshal(userListRequest)
+	if err != nil {
+		return nil, err
+	}
+	key := listKey{sellerID: userListRequest.SellerID, request: string(request)}
+
+	if value, ok := repo.get(key); ok {
+		cacheMetrics.Add("listHits", 1)
+		return copyProducts(value.([]*models.ProductInfo))
+	}
+	cacheMetrics.Add("listMisses", 1)
+
+	generation := repo.currentGeneration()
+
+	// list read from lagging replica after write would be cached stale
+	if repo.isRecentlyWritten(userListRequest.SellerID) {
+		ctx = withPrimaryRead(ctx)
+	}
+
+	products, err := repo.IRepository.SelectProductsBySpecificProductInfo(ctx, userListRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := copyProducts(products)
+	if err != nil {
+		return nil, err
+	}
+	repo.put(key, cached, generation)
+
+	return products, nil
+}
+
+// Writes of products invalidate cache even if they fail, failed write can
+// be applied or can mean that cached product is stale
+
+func (repo *cachedRepository) CreateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	defer repo.invalidate(productInfo.SellerID, productInfo.OfferID)
+
+	return repo.IRepository.CreateProduct(ctx, productInfo)
+}
+
+func (repo *cachedRepository) UpdateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	defer repo.invalidate(productInfo.SellerID, productInfo.OfferID)
+
+	return repo.IRepository.UpdateProduct(ctx, productInfo)
+}
+
+func (repo *cachedRepository) DeleteProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	defer repo.invalidate(sellerID, offerID)
+
+	return repo.IRepository.DeleteProduct(ctx, sellerID, offerID, version)
+}
+
+func (repo *cachedRepository) RestoreProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	defer repo.invalidate(sellerID, offerID)
+
+	return repo.IRepository.RestoreProduct(ctx, sellerID, offerID, version)
+}
+
+func (repo *cachedRepository) PurgeDeletedProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	// purged products are not known
+	defer repo.invalidate(0, 0)
+
+	return repo.IRepository.PurgeDeletedProducts(ctx, deletedBefore)
+}
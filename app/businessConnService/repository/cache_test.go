@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+func TestCachedRepositoryConformance(t *testing.T) {
+	testRepositoryConformance(t, func(t *testing.T) businessConnService.IRepository {
+		return NewCachedRepository(NewInMemoryRepository(), 100, time.Minute)
+	})
+}
+
+// cacheMetric - current value of counter of cacheMetrics
+func cacheMetric(name string) int64 {
+	if value, ok := cacheMetrics.Get(name).(*expvar.Int); ok {
+		return value.Value()
+	}
+
+	return 0
+}
+
+func TestCachedRepositorySelectProduct(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+	cache := NewCachedRepository(repo, 10, time.Minute).(*cachedRepository)
+
+	ctx := context.Background()
+	product := conformanceProduct(1, 2, "телефон")
+
+	hits, misses := cacheMetric("productHits"), cacheMetric("productMisses")
+
+	// test second select is served from cache with copy of product
+
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(product, nil)
+
+	selected, err := cache.SelectProduct(ctx, 1, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, conformanceProduct(1, 2, "телефон"), selected)
+	}
+	selected.Name = "телевизор"
+
+	for i := 0; i < 2; i++ {
+		selected, err = cache.SelectProduct(ctx, 1, 2)
+		if assert.NoError(t, err) {
+			assert.Equal(t, conformanceProduct(1, 2, "телефон"), selected)
+		}
+		selected.Name = "телевизор"
+	}
+
+	assert.Equal(t, hits+2, cacheMetric("productHits"))
+	assert.Equal(t, misses+1, cacheMetric("productMisses"))
+
+	// test not found product is cached too
+
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(3)).
+		Return(nil, domainErrors.NotFound("product not found"))
+
+	for i := 0; i < 2; i++ {
+		_, err = cache.SelectProduct(ctx, 1, 3)
+		assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+	}
+
+	// test write invalidates product
+
+	repo.EXPECT().CreateProduct(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(3)).Return(conformanceProduct(1, 3, "утюг"), nil)
+
+	_, err = cache.CreateProduct(ctx, conformanceProduct(1, 3, "утюг"))
+	assert.NoError(t, err)
+
+	selected, err = cache.SelectProduct(ctx, 1, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "утюг", selected.Name)
+	}
+
+	// test failed write invalidates product too
+
+	repo.EXPECT().UpdateProduct(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db_error"))
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(product, nil)
+
+	_, err = cache.UpdateProduct(ctx, conformanceProduct(1, 2, "телевизор"))
+	assert.Error(t, err)
+
+	_, err = cache.SelectProduct(ctx, 1, 2)
+	assert.NoError(t, err)
+
+	// test expired product is selected again
+
+	now := time.Now()
+	cache.now = func() time.Time { return now.Add(time.Minute) }
+
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(product, nil)
+
+	_, err = cache.SelectProduct(ctx, 1, 2)
+	assert.NoError(t, err)
+
+	// test product invalidated during select is not cached
+
+	repo.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(4), int64(0)).Return(int64(1), nil)
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(4)).
+		DoAndReturn(func(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+			cache.DeleteProduct(ctx, sellerID, offerID, 0)
+			return conformanceProduct(1, 4, "чайник"), nil
+		})
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(4)).
+		Return(nil, domainErrors.NotFound("product not found"))
+
+	_, err = cache.SelectProduct(ctx, 1, 4)
+	assert.NoError(t, err)
+
+	_, err = cache.SelectProduct(ctx, 1, 4)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+}
+
+func TestCachedRepositorySelectProductsBySpecificProductInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+	cache := NewCachedRepository(repo, 10, time.Minute)
+
+	ctx := context.Background()
+	sellerRequest := &models.UserListRequest{SellerID: 1}
+	otherSellerRequest := &models.UserListRequest{SellerID: 2}
+	allRequest := &models.UserListRequest{Name: "теле"}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), sellerRequest).
+		Return([]*models.ProductInfo{conformanceProduct(1, 1, "телефон")}, nil).Times(2)
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), otherSellerRequest).
+		Return([]*models.ProductInfo{conformanceProduct(2, 1, "утюг")}, nil).Times(1)
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), allRequest).
+		Return([]*models.ProductInfo{conformanceProduct(1, 1, "телефон")}, nil).Times(2)
+
+	// test lists are cached by request
+
+	for i := 0; i < 2; i++ {
+		for _, request := range []*models.UserListRequest{sellerRequest, otherSellerRequest, allRequest} {
+			products, err := cache.SelectProductsBySpecificProductInfo(ctx, request)
+			if assert.NoError(t, err) && assert.Len(t, products, 1) {
+				products[0].Name = "чайник"
+			}
+		}
+	}
+
+	products, err := cache.SelectProductsBySpecificProductInfo(ctx, sellerRequest)
+	if assert.NoError(t, err) && assert.Len(t, products, 1) {
+		assert.Equal(t, "телефон", products[0].Name)
+	}
+
+	// test write of product invalidates lists of its seller and of all sellers
+
+	repo.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(1), int64(0)).Return(int64(1), nil)
+
+	_, err = cache.RestoreProduct(ctx, 1, 1, 0)
+	assert.NoError(t, err)
+
+	for _, request := range []*models.UserListRequest{sellerRequest, otherSellerRequest, allRequest} {
+		_, err := cache.SelectProductsBySpecificProductInfo(ctx, request)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCachedRepositoryReplicaStaleness(t *testing.T) {
+	repo, primary, replica := newReplicaTestRepository(t)
+	cache := NewCachedRepository(repo, 10, time.Minute).(*cachedRepository)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	listQuery := "SELECT .+ FROM productsinfo"
+
+	expectList := func(mock sqlmock.Sqlmock, sellerID int64) {
+		mock.ExpectQuery(listQuery).WithArgs(sellerID).WillReturnRows(sqlmock.NewRows([]string{"seller_id"}))
+	}
+
+	// test list is read from replica before writes
+
+	expectReplicaLag(replica, 0)
+	expectList(replica, 1)
+
+	_, err := cache.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	assert.NoError(t, err)
+
+	// test lists of written seller and of all sellers are read from primary
+	// after write, lists of other sellers are read from replica
+
+	cache.invalidate(1, 1)
+
+	expectList(primary, 1)
+
+	_, err = cache.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	assert.NoError(t, err)
+
+	expectReplicaLag(replica, 0)
+	expectList(replica, 2)
+
+	_, err = cache.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 2})
+	assert.NoError(t, err)
+
+	primary.ExpectQuery(listQuery).WillReturnRows(sqlmock.NewRows([]string{"seller_id"}))
+
+	_, err = cache.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{})
+	assert.NoError(t, err)
+
+	// test list of written seller is read from replica after staleness
+
+	cache.invalidate(1, 1)
+	now = now.Add(5 * time.Second)
+
+	expectReplicaLag(replica, 0)
+	expectList(replica, 1)
+
+	_, err = cache.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, primary.ExpectationsWereMet())
+	assert.NoError(t, replica.ExpectationsWereMet())
+}
+
+func TestCachedRepositoryEviction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+	cache := NewCachedRepository(repo, 2, time.Minute)
+
+	ctx := context.Background()
+	evictions := cacheMetric("evictions")
+
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(1)).Return(conformanceProduct(1, 1, "a"), nil)
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(2)).Return(conformanceProduct(1, 2, "b"), nil).Times(2)
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(3)).Return(conformanceProduct(1, 3, "c"), nil)
+
+	// least recently used product 2 is evicted by product 3
+
+	for _, offerID := range []int64{1, 2, 1, 3, 1, 2} {
+		_, err := cache.SelectProduct(ctx, 1, offerID)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, evictions+2, cacheMetric("evictions"))
+
+	// test purge invalidates all products
+
+	repo.EXPECT().PurgeDeletedProducts(gomock.Any(), gomock.Any()).Return(int64(0), nil)
+	repo.EXPECT().SelectProduct(gomock.Any(), int64(1), int64(1)).Return(conformanceProduct(1, 1, "a"), nil)
+
+	_, err := cache.PurgeDeletedProducts(ctx, time.Now())
+	assert.NoError(t, err)
+
+	_, err = cache.SelectProduct(ctx, 1, 1)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,680 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// repositoryFactory - create empty repository for one test of conformance suite
+type repositoryFactory func(t *testing.T) businessConnService.IRepository
+
+func TestInMemoryRepositoryConformance(t *testing.T) {
+	testRepositoryConformance(t, func(t *testing.T) businessConnService.IRepository {
+		return NewInMemoryRepository()
+	})
+}
+
+// TestPostgresRepositoryConformance - conformance suite on real postgres, it
+// runs only if TEST_DB_DSN is set, for example
+// TEST_DB_DSN="host=localhost user=avito password=avito dbname=avito_test sslmode=disable".
+// Schema is recreated by build/postgres/init.sql before every test, so
+// never point it to database with data
+func TestPostgresRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	schema, err := ioutil.ReadFile("../../../build/postgres/init.sql")
+	if err != nil {
+		t.Fatalf("Can`t read schema: %s", err)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Can`t open DB: %s", err)
+	}
+	defer db.Close()
+
+	testRepositoryConformance(t, func(t *testing.T) businessConnService.IRepository {
+		if _, err := db.Exec(string(schema)); err != nil {
+			t.Fatalf("Can`t create schema: %s", err)
+		}
+
+		return &repository{DB: db}
+	})
+}
+
+// testRepositoryConformance - check that implementation of IRepository has
+// the same semantics as other ones
+func testRepositoryConformance(t *testing.T, newRepo repositoryFactory) {
+	t.Run("Products", func(t *testing.T) { testConformanceProducts(t, newRepo(t)) })
+	t.Run("ProductsFilter", func(t *testing.T) { testConformanceProductsFilter(t, newRepo(t)) })
+	t.Run("DeleteRestorePurge", func(t *testing.T) { testConformanceDeleteRestorePurge(t, newRepo(t)) })
+	t.Run("History", func(t *testing.T) { testConformanceHistory(t, newRepo(t)) })
+	t.Run("Tasks", func(t *testing.T) { testConformanceTasks(t, newRepo(t)) })
+	t.Run("TaskPreview", func(t *testing.T) { testConformanceTaskPreview(t, newRepo(t)) })
+	t.Run("TaskErrors", func(t *testing.T) { testConformanceTaskErrors(t, newRepo(t)) })
+	t.Run("ExchangeRates", func(t *testing.T) { testConformanceExchangeRates(t, newRepo(t)) })
+	t.Run("Search", func(t *testing.T) { testConformanceSearch(t, newRepo(t)) })
+	t.Run("CancelledContext", func(t *testing.T) { testConformanceCancelledContext(t, newRepo(t)) })
+}
+
+// conformanceProduct - valid product without optional attributes
+func conformanceProduct(sellerID, offerID int64, name string) *models.ProductInfo {
+	return &models.ProductInfo{
+		SellerID:  sellerID,
+		OfferID:   offerID,
+		Name:      name,
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+	}
+}
+
+// offerIDs - offer ids of products in order of slice
+func offerIDs(products []*models.ProductInfo) []int64 {
+	ids := []int64{}
+	for _, product := range products {
+		ids = append(ids, product.OfferID)
+	}
+
+	return ids
+}
+
+func testConformanceProducts(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	weight := decimal.RequireFromString("0.25")
+	product := conformanceProduct(1, 1, "телефон")
+	product.SKU = "PH-1"
+	product.Description = "смартфон"
+	product.Category = "электроника/телефоны"
+	product.Brand = "acme"
+	product.Images = []string{"http://img/1.png", "http://img/2.png"}
+	product.Weight = &weight
+	product.Dimensions = &models.Dimensions{
+		Length: decimal.RequireFromString("15"),
+		Width:  decimal.RequireFromString("7.5"),
+		Height: decimal.RequireFromString("0.8"),
+	}
+	product.Extras = map[string]interface{}{"color": "black"}
+
+	// test create and select
+
+	_, err := repo.SelectProduct(ctx, 1, 1)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+
+	rowsAffected, err := repo.CreateProduct(ctx, product)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), rowsAffected)
+
+	expectProduct := *product
+	expectProduct.Version = 1
+
+	stored, err := repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &expectProduct, stored)
+	}
+
+	// changes of returned product do not change stored one
+	stored.Images[0] = "http://img/3.png"
+	stored.Extras["color"] = "white"
+
+	stored, err = repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &expectProduct, stored)
+	}
+
+	_, err = repo.CreateProduct(ctx, conformanceProduct(1, 1, "телевизор"))
+	assert.True(t, errors.Is(err, domainErrors.ErrConflict))
+
+	// test update with version
+
+	updated := conformanceProduct(1, 1, "телевизор")
+	updated.Version = 2
+
+	rowsAffected, err = repo.UpdateProduct(ctx, updated)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	updated.Version = 1
+
+	rowsAffected, err = repo.UpdateProduct(ctx, updated)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	expectProduct = *conformanceProduct(1, 1, "телевизор")
+	expectProduct.Version = 2
+
+	stored, err = repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &expectProduct, stored)
+	}
+
+	// zero version matches any
+
+	updated.Version = 0
+	updated.Quantity = 3
+
+	rowsAffected, err = repo.UpdateProduct(ctx, updated)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	rowsAffected, err = repo.UpdateProduct(ctx, conformanceProduct(1, 2, "нет"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+}
+
+func testConformanceProductsFilter(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	products := []*models.ProductInfo{
+		conformanceProduct(1, 1, "телефон"),
+		conformanceProduct(1, 2, "телевизор"),
+		conformanceProduct(1, 3, "утюг"),
+		conformanceProduct(2, 1, "телефон"),
+		conformanceProduct(2, 2, "100%_sale"),
+	}
+	products[0].Category = "электроника/телефоны"
+	products[0].Brand = "acme"
+	products[1].Category = "электроника"
+	products[1].SKU = "TV-1"
+	products[2].Category = "электроника бытовая"
+	products[3].Category = "электроника/телефоны/смартфоны"
+	products[3].Brand = "acme"
+	products[4].Category = "100%_sale"
+
+	for _, product := range products {
+		if _, err := repo.CreateProduct(ctx, product); !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	for _, test := range []struct {
+		request  *models.UserListRequest
+		sellers  []int64
+		offerIDs []int64
+	}{
+		{&models.UserListRequest{SellerID: 1}, []int64{1, 1, 1}, []int64{1, 2, 3}},
+		{&models.UserListRequest{SellerID: 1, OfferID: 2}, []int64{1}, []int64{2}},
+		{&models.UserListRequest{Name: "теле"}, []int64{1, 1, 2}, []int64{1, 2, 1}},
+		{&models.UserListRequest{SKU: "TV-1"}, []int64{1}, []int64{2}},
+		{&models.UserListRequest{Brand: "acme", SellerID: 2}, []int64{2}, []int64{1}},
+		{&models.UserListRequest{Category: "электроника"}, []int64{1, 1, 2}, []int64{1, 2, 1}},
+		{&models.UserListRequest{Category: "электроника/телефоны"}, []int64{1, 2}, []int64{1, 1}},
+		{&models.UserListRequest{Category: "100%"}, []int64{}, []int64{}},
+		{&models.UserListRequest{Category: "100%_sale"}, []int64{2}, []int64{2}},
+		{&models.UserListRequest{SellerIDs: []int64{2, 3}}, []int64{2, 2}, []int64{1, 2}},
+		{&models.UserListRequest{OfferIDs: []int64{1, 3}}, []int64{1, 1, 2}, []int64{1, 3, 1}},
+		{&models.UserListRequest{SellerIDs: []int64{1, 2}, OfferIDs: []int64{2}}, []int64{1, 2}, []int64{2, 2}},
+		{&models.UserListRequest{SellerID: 1, OfferIDs: []int64{1, 2}, ExcludeOfferIDs: []int64{2}},
+			[]int64{1}, []int64{1}},
+		{&models.UserListRequest{ExcludeSellerIDs: []int64{1}, ExcludeOfferIDs: []int64{2}}, []int64{2}, []int64{1}},
+	} {
+		found, err := repo.SelectProductsBySpecificProductInfo(ctx, test.request)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// order of products is not defined
+		sellers := []int64{}
+		for _, product := range found {
+			sellers = append(sellers, product.SellerID)
+		}
+		assert.ElementsMatch(t, test.sellers, sellers, "%+v", test.request)
+		assert.ElementsMatch(t, test.offerIDs, offerIDs(found), "%+v", test.request)
+	}
+}
+
+func testConformanceDeleteRestorePurge(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	if _, err := repo.CreateProduct(ctx, conformanceProduct(1, 1, "телефон")); !assert.NoError(t, err) {
+		return
+	}
+
+	// test delete with version
+
+	rowsAffected, err := repo.DeleteProduct(ctx, 1, 1, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	rowsAffected, err = repo.DeleteProduct(ctx, 1, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	rowsAffected, err = repo.DeleteProduct(ctx, 1, 1, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	// deleted product is found only by id or with IncludeDeleted
+
+	deleted, err := repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, deleted.DeletedAt)
+		assert.Equal(t, int64(2), deleted.Version)
+	}
+
+	found, err := repo.SelectProductsBySpecificProductInfo(ctx, &models.UserListRequest{SellerID: 1})
+	if assert.NoError(t, err) {
+		assert.Empty(t, found)
+	}
+
+	found, err = repo.SelectProductsBySpecificProductInfo(ctx,
+		&models.UserListRequest{SellerID: 1, IncludeDeleted: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{1}, offerIDs(found))
+	}
+
+	rowsAffected, err = repo.UpdateProduct(ctx, conformanceProduct(1, 1, "телевизор"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	// test restore
+
+	rowsAffected, err = repo.RestoreProduct(ctx, 1, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	rowsAffected, err = repo.RestoreProduct(ctx, 1, 1, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	restored, err := repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Nil(t, restored.DeletedAt)
+		assert.Equal(t, int64(3), restored.Version)
+	}
+
+	rowsAffected, err = repo.RestoreProduct(ctx, 1, 1, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	// test create of deleted product gets next version
+
+	if _, err := repo.DeleteProduct(ctx, 1, 1, 0); !assert.NoError(t, err) {
+		return
+	}
+
+	rowsAffected, err = repo.CreateProduct(ctx, conformanceProduct(1, 1, "телевизор"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	created, err := repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Nil(t, created.DeletedAt)
+		assert.Equal(t, "телевизор", created.Name)
+		assert.Equal(t, int64(5), created.Version)
+	}
+
+	// test purge
+
+	if _, err := repo.DeleteProduct(ctx, 1, 1, 0); !assert.NoError(t, err) {
+		return
+	}
+
+	rowsAffected, err = repo.PurgeDeletedProducts(ctx, time.Now().Add(-time.Hour))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	rowsAffected, err = repo.PurgeDeletedProducts(ctx, time.Now().Add(time.Hour))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	_, err = repo.SelectProduct(ctx, 1, 1)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+}
+
+func testConformanceHistory(t *testing.T, repo businessConnService.IRepository) {
+	source := models.ChangeSource{TaskID: 7, File: "products.xlsx", Sheet: "Sheet1", Row: 2, RequestID: "req"}
+	taskCtx := businessConnService.WithChangeSource(context.Background(), source)
+	apiCtx := businessConnService.WithChangeSource(context.Background(), models.ChangeSource{RequestID: "api"})
+
+	if _, err := repo.CreateProduct(taskCtx, conformanceProduct(1, 1, "телефон")); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := repo.UpdateProduct(taskCtx, conformanceProduct(1, 1, "телевизор")); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := repo.DeleteProduct(apiCtx, 1, 1, 0); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := repo.RestoreProduct(apiCtx, 1, 1, 0); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := repo.CreateProduct(taskCtx, conformanceProduct(1, 2, "утюг")); !assert.NoError(t, err) {
+		return
+	}
+
+	history, err := repo.SelectProductHistory(context.Background(), 1, 1)
+	if !assert.NoError(t, err) || !assert.Len(t, history, 4) {
+		return
+	}
+
+	actions := []string{}
+	for _, change := range history {
+		actions = append(actions, change.Action)
+		assert.Equal(t, int64(1), change.SellerID)
+		assert.Equal(t, int64(1), change.OfferID)
+		assert.False(t, change.ChangedAt.IsZero())
+	}
+	assert.Equal(t, []string{models.ProductCreated, models.ProductUpdated, models.ProductDeleted,
+		models.ProductRestored}, actions)
+
+	assert.Nil(t, history[0].OldValue)
+	if assert.NotNil(t, history[0].NewValue) {
+		assert.Equal(t, "телефон", history[0].NewValue.Name)
+		assert.Equal(t, int64(1), history[0].NewValue.Version)
+	}
+	assert.Equal(t, source, history[0].Source)
+
+	if assert.NotNil(t, history[1].OldValue) && assert.NotNil(t, history[1].NewValue) {
+		assert.Equal(t, "телефон", history[1].OldValue.Name)
+		assert.Equal(t, "телевизор", history[1].NewValue.Name)
+		assert.Equal(t, int64(2), history[1].NewValue.Version)
+	}
+
+	assert.NotNil(t, history[2].OldValue)
+	assert.Nil(t, history[2].NewValue)
+	assert.Equal(t, models.ChangeSource{RequestID: "api"}, history[2].Source)
+
+	if assert.NotNil(t, history[3].NewValue) {
+		assert.Nil(t, history[3].NewValue.DeletedAt)
+		assert.Equal(t, int64(4), history[3].NewValue.Version)
+	}
+
+	// changes of task are returned newest first
+
+	changes, err := repo.SelectTaskChanges(context.Background(), 7)
+	if assert.NoError(t, err) && assert.Len(t, changes, 3) {
+		assert.Equal(t, []int64{2, 1, 1}, []int64{changes[0].OfferID, changes[1].OfferID, changes[2].OfferID})
+		assert.Equal(t, models.ProductCreated, changes[0].Action)
+		assert.Equal(t, models.ProductUpdated, changes[1].Action)
+	}
+
+	changes, err = repo.SelectTaskChanges(context.Background(), 8)
+	if assert.NoError(t, err) {
+		assert.Empty(t, changes)
+	}
+}
+
+func testConformanceTasks(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	firstID, err := repo.CreateTask(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	secondID, err := repo.CreateTask(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, secondID > firstID)
+
+	state, err := repo.SelectTaskState(ctx, firstID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &models.TaskState{TaskID: firstID, State: "CREATED"}, state)
+	}
+
+	rowsAffected, err := repo.UpdateTaskState(ctx, firstID, "DONE")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	state, err = repo.SelectTaskState(ctx, firstID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "DONE", state.State)
+	}
+
+	rowsAffected, err = repo.UpdateTaskState(ctx, secondID+1, "DONE")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	_, err = repo.SelectTaskState(ctx, secondID+1)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+
+	// test stats
+
+	stats := &models.TaskStats{TaskID: firstID, ProductsCreated: 1, ProductsUpdated: 2, ProductsDeleted: 3,
+		RowsWithErrors: 4}
+
+	_, err = repo.SelectTaskStatsByTaskID(ctx, firstID)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+
+	rowsAffected, err = repo.CreateTaskStats(ctx, stats)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	storedStats, err := repo.SelectTaskStatsByTaskID(ctx, firstID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, stats, storedStats)
+	}
+
+	_, err = repo.CreateTaskStats(ctx, stats)
+	assert.True(t, errors.Is(err, domainErrors.ErrConflict))
+}
+
+func testConformanceTaskPreview(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	items := []*models.PreviewItem{
+		{TaskID: 5, SellerID: 1, OfferID: 2, Action: models.ProductCreated,
+			NewValue: conformanceProduct(1, 2, "телевизор"), File: "b.xlsx", Sheet: "Sheet1", Row: 1},
+		{TaskID: 5, SellerID: 1, OfferID: 1, Action: models.ProductUpdated,
+			Changes: []models.FieldChange{{Field: "quantity", Old: 1, New: 2}},
+			File:    "a.xlsx", Sheet: "Sheet1", Row: 3},
+		{TaskID: 5, SellerID: 1, Action: models.PreviewError, Error: "Nil col value",
+			File: "a.xlsx", Sheet: "Sheet1", Row: 2},
+		{TaskID: 6, SellerID: 1, OfferID: 1, Action: models.PreviewSkipped, File: "a.xlsx", Sheet: "Sheet1", Row: 1},
+	}
+
+	rowsAffected, err := repo.CreateTaskPreview(ctx, items)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(4), rowsAffected)
+
+	preview, err := repo.SelectTaskPreview(ctx, 5)
+	if !assert.NoError(t, err) || !assert.Len(t, preview, 3) {
+		return
+	}
+
+	assert.Equal(t, []int64{2, 3, 1}, []int64{preview[0].Row, preview[1].Row, preview[2].Row})
+	assert.Equal(t, "Nil col value", preview[0].Error)
+	assert.Equal(t, []models.FieldChange{{Field: "quantity", Old: float64(1), New: float64(2)}}, preview[1].Changes)
+	if assert.NotNil(t, preview[2].NewValue) {
+		assert.Equal(t, "телевизор", preview[2].NewValue.Name)
+	}
+
+	preview, err = repo.SelectTaskPreview(ctx, 7)
+	if assert.NoError(t, err) {
+		assert.Empty(t, preview)
+	}
+}
+
+func testConformanceTaskErrors(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	rowErrors := []models.RowError{
+		{Row: 4, Error: "Row must be json ProductInfo"},
+		{Row: 2, OfferID: 3, Error: "Misrepresentation of values",
+			Details: []models.ErrorDetail{{Field: "name", Message: "must not be empty"}}},
+	}
+
+	rowsAffected, err := repo.CreateTaskErrors(ctx, 5, rowErrors)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), rowsAffected)
+
+	saved, err := repo.SelectTaskErrors(ctx, 5)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []models.RowError{rowErrors[1], rowErrors[0]}, saved)
+	}
+
+	saved, err = repo.SelectTaskErrors(ctx, 6)
+	if assert.NoError(t, err) {
+		assert.Empty(t, saved)
+	}
+}
+
+func testConformanceExchangeRates(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	for _, rate := range []*models.ExchangeRate{
+		{Currency: "USD", Rate: decimal.RequireFromString("90")},
+		{Currency: "EUR", Rate: decimal.RequireFromString("100")},
+		{Currency: "USD", Rate: decimal.RequireFromString("90.5")},
+	} {
+		rowsAffected, err := repo.UpdateExchangeRate(ctx, rate)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(1), rowsAffected)
+		}
+	}
+
+	_, err := repo.UpdateExchangeRate(ctx, &models.ExchangeRate{Currency: "KZT", Rate: decimal.Zero})
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+
+	rates, err := repo.SelectExchangeRates(ctx)
+	if assert.NoError(t, err) && assert.Len(t, rates, 2) {
+		assert.Equal(t, "EUR", rates[0].Currency)
+		assert.Equal(t, "USD", rates[1].Currency)
+		assert.Equal(t, "90.5", rates[1].Rate.String())
+		assert.False(t, rates[1].UpdatedAt.IsZero())
+	}
+
+	rowsAffected, err := repo.DeleteExchangeRate(ctx, "EUR")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), rowsAffected)
+	}
+
+	rowsAffected, err = repo.DeleteExchangeRate(ctx, "EUR")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), rowsAffected)
+	}
+
+	rates, err = repo.SelectExchangeRates(ctx)
+	if assert.NoError(t, err) && assert.Len(t, rates, 1) {
+		assert.Equal(t, "USD", rates[0].Currency)
+	}
+}
+
+func testConformanceSearch(t *testing.T, repo businessConnService.IRepository) {
+	ctx := context.Background()
+
+	products := []*models.ProductInfo{
+		conformanceProduct(1, 1, "Смартфон acme"),
+		conformanceProduct(1, 2, "Чехол"),
+		conformanceProduct(1, 3, "Смартфон старый"),
+		conformanceProduct(2, 1, "Смартфон acme"),
+	}
+	products[1].Description = "Чехол для смартфона acme"
+
+	for _, product := range products {
+		if _, err := repo.CreateProduct(ctx, product); !assert.NoError(t, err) {
+			return
+		}
+	}
+	if _, err := repo.DeleteProduct(ctx, 1, 3, 0); !assert.NoError(t, err) {
+		return
+	}
+
+	results, err := repo.SearchProducts(ctx, &models.SearchRequest{Query: "смартф AC", SellerID: 1, Limit: 10})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+
+	// words found in name rank higher than in description
+	assert.Equal(t, int64(1), results[0].Product.OfferID)
+	assert.Equal(t, int64(2), results[1].Product.OfferID)
+	assert.True(t, results[0].Rank > results[1].Rank)
+	assert.Contains(t, results[0].Snippet, "<b>Смартфон</b>")
+	assert.Contains(t, results[1].Snippet, "<b>acme</b>")
+
+	results, err = repo.SearchProducts(ctx, &models.SearchRequest{Query: "смартфон", Limit: 2, Offset: 1})
+	if assert.NoError(t, err) {
+		assert.Len(t, results, 2)
+	}
+
+	results, err = repo.SearchProducts(ctx, &models.SearchRequest{Query: "утюг", Limit: 10})
+	if assert.NoError(t, err) {
+		assert.Empty(t, results)
+	}
+
+	// test updated product is found by new words only
+
+	if _, err := repo.UpdateProduct(ctx, conformanceProduct(2, 1, "Утюг")); !assert.NoError(t, err) {
+		return
+	}
+
+	results, err = repo.SearchProducts(ctx, &models.SearchRequest{Query: "утюг", Limit: 10})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Equal(t, int64(2), results[0].Product.SellerID)
+	}
+
+	results, err = repo.SearchProducts(ctx, &models.SearchRequest{Query: "acme", SellerID: 2, Limit: 10})
+	if assert.NoError(t, err) {
+		assert.Empty(t, results)
+	}
+
+	// test markup of product is escaped in snippet
+
+	if _, err := repo.CreateProduct(ctx, conformanceProduct(3, 1,
+		`Фен <img src=x onerror="alert(1)">`)); !assert.NoError(t, err) {
+		return
+	}
+
+	results, err = repo.SearchProducts(ctx, &models.SearchRequest{Query: "фен", SellerID: 3, Limit: 10})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Contains(t, results[0].Snippet, "<b>Фен</b>")
+		assert.Contains(t, results[0].Snippet, "&lt;img")
+		assert.NotContains(t, results[0].Snippet, "<img")
+		assert.NotContains(t, results[0].Snippet, `"`)
+	}
+}
+
+func testConformanceCancelledContext(t *testing.T, repo businessConnService.IRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.CreateProduct(ctx, conformanceProduct(1, 1, "телефон"))
+	assert.Error(t, err)
+
+	_, err = repo.SelectProduct(ctx, 1, 1)
+	assert.Error(t, err)
+
+	_, err = repo.SelectProduct(context.Background(), 1, 1)
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+}
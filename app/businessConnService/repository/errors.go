@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+)
+
+// codes of postgres errors which are mapped to domain errors
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+	checkViolationCode      = "23514"
+)
+
+// mapError - translate error of database/sql or postgres driver to domain
+// error, entity is used in message for client
+func mapError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domainErrors.Wrap(domainErrors.ErrNotFound, err, entity+" not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolationCode:
+			return domainErrors.Wrap(domainErrors.ErrConflict, err, entity+" already exists")
+		case foreignKeyViolationCode, checkViolationCode:
+			return domainErrors.Wrap(domainErrors.ErrValidation, err, "invalid "+entity)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+)
+
+func TestMapError(t *testing.T) {
+	assert.NoError(t, mapError(nil, "product"))
+
+	err := mapError(sql.ErrNoRows, "product")
+	assert.True(t, errors.Is(err, domainErrors.ErrNotFound))
+	assert.Equal(t, "product not found", domainErrors.Message(err))
+
+	err = mapError(&pq.Error{Code: uniqueViolationCode}, "product")
+	assert.True(t, errors.Is(err, domainErrors.ErrConflict))
+	assert.Equal(t, "product already exists", domainErrors.Message(err))
+
+	err = mapError(&pq.Error{Code: checkViolationCode}, "product")
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+
+	dbErr := errors.New("db_error")
+	assert.Equal(t, dbErr, mapError(dbErr, "product"))
+}
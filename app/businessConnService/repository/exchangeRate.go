@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+func (repo *repository) SelectExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectExchangeRates")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT currency, rate, updated_at FROM exchangeRates ORDER BY currency")
+	if err != nil {
+		return nil, spanError(span, mapError(err, "exchange rate"))
+	}
+	defer rows.Close()
+
+	rates := []*models.ExchangeRate{}
+
+	for rows.Next() {
+		rate := new(models.ExchangeRate)
+
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, spanError(span, mapError(err, "exchange rate"))
+		}
+
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, spanError(span, mapError(err, "exchange rate"))
+	}
+
+	return rates, nil
+}
+
+func (repo *repository) UpdateExchangeRate(ctx context.Context, rate *models.ExchangeRate) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "UpdateExchangeRate")
+	defer span.End()
+
+	// rate of new currency is created
+	res, err := repo.DB.ExecContext(ctx,
+		"INSERT INTO exchangeRates (currency, rate, updated_at) VALUES ($1, $2, now()) "+
+			"ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at",
+		rate.Currency,
+		rate.Rate,
+	)
+	if err != nil {
+		return 0, spanError(span, mapError(err, "exchange rate"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapError(err, "exchange rate"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *repository) DeleteExchangeRate(ctx context.Context, currency string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "DeleteExchangeRate")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx, "DELETE FROM exchangeRates WHERE currency = $1", currency)
+	if err != nil {
+		return 0, spanError(span, mapError(err, "exchange rate"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapError(err, "exchange rate"))
+	}
+
+	return affectedRowsCounter, nil
+}
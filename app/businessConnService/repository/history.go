@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// execer - transaction where queries are executed, *sql.Tx for postgres or
+// sqliteTx for sqlite
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// inTx - run fn in transaction, it is committed if fn returns nil and
+// rolled back otherwise
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// selectProductForUpdate - select product and lock it until end of tx,
+// nil is returned if product does not exist
+func selectProductForUpdate(ctx context.Context, tx *sql.Tx, sellerID, offerID int64) (*models.ProductInfo, error) {
+	product, err := scanProduct(tx.QueryRowContext(ctx,
+		"SELECT "+productColumns+" FROM productsinfo WHERE seller_id = $1 AND offer_id = $2 FOR UPDATE",
+		sellerID, offerID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return product, err
+}
+
+// recordChange - save change of product to history with source of change
+// taken from ctx
+func recordChange(ctx context.Context, tx execer, action string, oldValue, newValue *models.ProductInfo) error {
+	product := newValue
+	if product == nil {
+		product = oldValue
+	}
+
+	oldJSON, err := marshalValue(oldValue)
+	if err != nil {
+		return err
+	}
+
+	newJSON, err := marshalValue(newValue)
+	if err != nil {
+		return err
+	}
+
+	source := businessConnService.ChangeSourceFromContext(ctx)
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO productHistory "+
+			"(seller_id, offer_id, action, old_value, new_value, task_id, file, sheet, row_num, request_id) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		product.SellerID,
+		product.OfferID,
+		action,
+		oldJSON,
+		newJSON,
+		sql.NullInt64{Int64: source.TaskID, Valid: source.TaskID != 0},
+		source.File,
+		source.Sheet,
+		sql.NullInt64{Int64: source.Row, Valid: source.Row != 0},
+		source.RequestID,
+	)
+
+	return err
+}
+
+// marshalValue - marshal product to jsonb column, nil product is NULL
+func marshalValue(product *models.ProductInfo) (interface{}, error) {
+	if product == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(product)
+}
+
+// historyColumns - columns of productHistory in order of scanChanges
+const historyColumns = "id, seller_id, offer_id, action, old_value, new_value, task_id, file, sheet, row_num, " +
+	"request_id, changed_at"
+
+// scanChanges - scan all rows with historyColumns to changes
+func scanChanges(rows *sql.Rows) ([]*models.ProductChange, error) {
+	changes := []*models.ProductChange{}
+
+	for rows.Next() {
+		change := new(models.ProductChange)
+
+		var oldJSON, newJSON []byte
+		var taskID, row sql.NullInt64
+
+		err := rows.Scan(&change.ID, &change.SellerID, &change.OfferID, &change.Action, &oldJSON, &newJSON,
+			&taskID, &change.Source.File, &change.Source.Sheet, &row, &change.Source.RequestID, &change.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		change.Source.TaskID = taskID.Int64
+		change.Source.Row = row.Int64
+
+		if change.OldValue, err = unmarshalValue(oldJSON); err != nil {
+			return nil, err
+		}
+		if change.NewValue, err = unmarshalValue(newJSON); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+func (repo *repository) SelectProductHistory(ctx context.Context, sellerID, offerID int64) ([]*models.ProductChange, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectProductHistory")
+	defer span.End()
+
+	var changes []*models.ProductChange
+
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx,
+			"SELECT "+historyColumns+" FROM productHistory WHERE seller_id = $1 AND offer_id = $2 ORDER BY id",
+			sellerID, offerID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		changes, err = scanChanges(rows)
+		return err
+	})
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product history"))
+	}
+
+	return changes, nil
+}
+
+// SelectTaskChanges - select all changes of products made by task, newest first
+func (repo *repository) SelectTaskChanges(ctx context.Context, taskID int64) ([]*models.ProductChange, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectTaskChanges")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT "+historyColumns+" FROM productHistory WHERE task_id = $1 ORDER BY id DESC",
+		taskID)
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product history"))
+	}
+	defer rows.Close()
+
+	changes, err := scanChanges(rows)
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product history"))
+	}
+
+	return changes, nil
+}
+
+// unmarshalValue - unmarshal product from jsonb column, NULL is nil product
+func unmarshalValue(data []byte) (*models.ProductInfo, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	product := new(models.ProductInfo)
+	if err := json.Unmarshal(data, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
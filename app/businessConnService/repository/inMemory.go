@@ -0,0 +1,805 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// productKey - primary key of product
+type productKey struct {
+	sellerID int64
+	offerID  int64
+}
+
+// inMemoryRepository - IRepository keeping everything in memory of process,
+// it has the same semantics as repository on postgres and is used for local
+// development and tests. Data is lost on restart
+type inMemoryRepository struct {
+	mu sync.RWMutex
+
+	products      map[productKey]*models.ProductInfo
+	history       []*models.ProductChange
+	tasks         map[int64]*models.TaskState
+	lastTaskID    int64
+	taskStats     map[int64]*models.TaskStats
+	taskPreviews  map[int64][]*models.PreviewItem
+	taskErrors    map[int64][]models.RowError
+	exchangeRates map[string]*models.ExchangeRate
+}
+
+// NewInMemoryRepository - create new empty repository in memory that
+// implement IRepository interface
+func NewInMemoryRepository() businessConnService.IRepository {
+	return &inMemoryRepository{
+		products:      map[productKey]*models.ProductInfo{},
+		tasks:         map[int64]*models.TaskState{},
+		taskStats:     map[int64]*models.TaskStats{},
+		taskPreviews:  map[int64][]*models.PreviewItem{},
+		taskErrors:    map[int64][]models.RowError{},
+		exchangeRates: map[string]*models.ExchangeRate{},
+	}
+}
+
+// copyProduct - deep copy of product made the same way as postgres saves it
+// to jsonb, so that repository and callers never share slices and maps
+func copyProduct(product *models.ProductInfo) (*models.ProductInfo, error) {
+	value, err := marshalValue(product)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	copied, err := unmarshalValue(value.([]byte))
+	if err != nil {
+		return nil, err
+	}
+
+	// converted price is not stored
+	copied.ConvertedPrice = nil
+
+	return copied, nil
+}
+
+// recordChange - save change of product to history with source of change
+// taken from ctx, values must be copies owned by repository
+func (repo *inMemoryRepository) recordChange(ctx context.Context, action string,
+	oldValue, newValue *models.ProductInfo) {
+
+	product := newValue
+	if product == nil {
+		product = oldValue
+	}
+
+	repo.history = append(repo.history, &models.ProductChange{
+		ID:        int64(len(repo.history) + 1),
+		SellerID:  product.SellerID,
+		OfferID:   product.OfferID,
+		Action:    action,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		Source:    businessConnService.ChangeSourceFromContext(ctx),
+		ChangedAt: time.Now(),
+	})
+}
+
+// copyChange - copy of change of history for caller
+func copyChange(change *models.ProductChange) (*models.ProductChange, error) {
+	copied := *change
+
+	var err error
+	if copied.OldValue, err = copyProduct(change.OldValue); err != nil {
+		return nil, err
+	}
+	if copied.NewValue, err = copyProduct(change.NewValue); err != nil {
+		return nil, err
+	}
+
+	return &copied, nil
+}
+
+func (repo *inMemoryRepository) SelectProduct(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	product, ok := repo.products[productKey{sellerID, offerID}]
+	if !ok {
+		return nil, domainErrors.NotFound("product not found")
+	}
+
+	return copyProduct(product)
+}
+
+func (repo *inMemoryRepository) SelectProductsBySpecificProductInfo(ctx context.Context, userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	products := []*models.ProductInfo{}
+
+	for _, product := range repo.sortedProducts() {
+		if userListRequest.SellerID > 0 && product.SellerID != userListRequest.SellerID ||
+			userListRequest.OfferID > 0 && product.OfferID != userListRequest.OfferID ||
+			userListRequest.SKU != "" && product.SKU != userListRequest.SKU ||
+			userListRequest.Brand != "" && product.Brand != userListRequest.Brand ||
+			!userListRequest.IncludeDeleted && product.DeletedAt != nil ||
+			!strings.Contains(product.Name, userListRequest.Name) {
+			continue
+		}
+
+		if !matchIDLists(userListRequest, product) {
+			continue
+		}
+
+		// subcategories are found by prefix of path
+		if userListRequest.Category != "" && product.Category != userListRequest.Category &&
+			!strings.HasPrefix(product.Category, userListRequest.Category+models.CategorySeparator) {
+			continue
+		}
+
+		copied, err := copyProduct(product)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, copied)
+	}
+
+	return products, nil
+}
+
+// matchIDLists - product has one of ids of every list of request and none
+// of ids of every exclude list
+func matchIDLists(userListRequest *models.UserListRequest, product *models.ProductInfo) bool {
+	for _, list := range idLists(userListRequest) {
+		id := product.SellerID
+		if list.column == "offer_id" {
+			id = product.OfferID
+		}
+
+		found := false
+		for _, listID := range list.ids {
+			if listID == id {
+				found = true
+				break
+			}
+		}
+
+		if found == list.exclude {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sortedProducts - all products ordered by seller_id and offer_id
+func (repo *inMemoryRepository) sortedProducts() []*models.ProductInfo {
+	products := make([]*models.ProductInfo, 0, len(repo.products))
+	for _, product := range repo.products {
+		products = append(products, product)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].SellerID != products[j].SellerID {
+			return products[i].SellerID < products[j].SellerID
+		}
+		return products[i].OfferID < products[j].OfferID
+	})
+
+	return products
+}
+
+func (repo *inMemoryRepository) CreateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := productKey{productInfo.SellerID, productInfo.OfferID}
+
+	old, exists := repo.products[key]
+	if exists && old.DeletedAt == nil {
+		return 0, domainErrors.Conflict("product already exists")
+	}
+
+	created, err := copyProduct(productInfo)
+	if err != nil {
+		return 0, err
+	}
+	created.DeletedAt = nil
+	created.Version = 1
+
+	// product which was deleted but not purged yet gets next version
+	if exists {
+		created.Version = old.Version + 1
+	}
+
+	repo.products[key] = created
+	repo.recordChange(ctx, models.ProductCreated, old, created)
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) UpdateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := productKey{productInfo.SellerID, productInfo.OfferID}
+
+	// product with other version is not updated, zero version matches any
+	old, ok := repo.products[key]
+	if !ok || old.DeletedAt != nil || (productInfo.Version != 0 && productInfo.Version != old.Version) {
+		return 0, nil
+	}
+
+	updated, err := copyProduct(productInfo)
+	if err != nil {
+		return 0, err
+	}
+	updated.DeletedAt = nil
+	updated.Version = old.Version + 1
+
+	repo.products[key] = updated
+	repo.recordChange(ctx, models.ProductUpdated, old, updated)
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) DeleteProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := productKey{sellerID, offerID}
+
+	// product with other version is not deleted, zero version matches any
+	old, ok := repo.products[key]
+	if !ok || old.DeletedAt != nil || (version != 0 && version != old.Version) {
+		return 0, nil
+	}
+
+	// product is only marked as deleted, so that it can be restored
+	// until PurgeDeletedProducts
+	deleted := *old
+	deletedAt := time.Now()
+	deleted.DeletedAt = &deletedAt
+	deleted.Version = old.Version + 1
+
+	repo.products[key] = &deleted
+	repo.recordChange(ctx, models.ProductDeleted, old, nil)
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) SelectProductHistory(ctx context.Context, sellerID, offerID int64) ([]*models.ProductChange, error) {
+	return repo.selectChanges(ctx, false, func(change *models.ProductChange) bool {
+		return change.SellerID == sellerID && change.OfferID == offerID
+	})
+}
+
+// SelectTaskChanges - select all changes of products made by task, newest first
+func (repo *inMemoryRepository) SelectTaskChanges(ctx context.Context, taskID int64) ([]*models.ProductChange, error) {
+	return repo.selectChanges(ctx, true, func(change *models.ProductChange) bool {
+		return change.Source.TaskID == taskID
+	})
+}
+
+// selectChanges - copies of changes of history matching filter in order of
+// recording or in reverse order
+func (repo *inMemoryRepository) selectChanges(ctx context.Context, newestFirst bool,
+	filter func(*models.ProductChange) bool) ([]*models.ProductChange, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	changes := []*models.ProductChange{}
+
+	for _, change := range repo.history {
+		if !filter(change) {
+			continue
+		}
+
+		copied, err := copyChange(change)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, copied)
+	}
+
+	if newestFirst {
+		for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+			changes[i], changes[j] = changes[j], changes[i]
+		}
+	}
+
+	return changes, nil
+}
+
+func (repo *inMemoryRepository) RestoreProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := productKey{sellerID, offerID}
+
+	// product with other version is not restored, zero version matches any
+	old, ok := repo.products[key]
+	if !ok || old.DeletedAt == nil || (version != 0 && version != old.Version) {
+		return 0, nil
+	}
+
+	restored := *old
+	restored.DeletedAt = nil
+	restored.Version = old.Version + 1
+
+	repo.products[key] = &restored
+	repo.recordChange(ctx, models.ProductRestored, old, &restored)
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) PurgeDeletedProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	affectedRowsCounter := int64(0)
+
+	for key, product := range repo.products {
+		if product.DeletedAt != nil && product.DeletedAt.Before(deletedBefore) {
+			delete(repo.products, key)
+			affectedRowsCounter++
+		}
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *inMemoryRepository) SelectTaskState(ctx context.Context, taskID int64) (*models.TaskState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	taskState, ok := repo.tasks[taskID]
+	if !ok {
+		return nil, domainErrors.NotFound("task not found")
+	}
+
+	copied := *taskState
+
+	return &copied, nil
+}
+
+func (repo *inMemoryRepository) CreateTask(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.lastTaskID++
+	repo.tasks[repo.lastTaskID] = &models.TaskState{TaskID: repo.lastTaskID, State: "CREATED"}
+
+	return repo.lastTaskID, nil
+}
+
+func (repo *inMemoryRepository) UpdateTaskState(ctx context.Context, taskID int64, state string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	taskState, ok := repo.tasks[taskID]
+	if !ok {
+		return 0, nil
+	}
+
+	taskState.State = state
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) SelectTaskStatsByTaskID(ctx context.Context, taskID int64) (*models.TaskStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	taskStats, ok := repo.taskStats[taskID]
+	if !ok {
+		return nil, domainErrors.NotFound("task stats not found")
+	}
+
+	copied := *taskStats
+
+	return &copied, nil
+}
+
+func (repo *inMemoryRepository) CreateTaskStats(ctx context.Context, taskStats *models.TaskStats) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.taskStats[taskStats.TaskID]; ok {
+		return 0, domainErrors.Conflict("task stats already exists")
+	}
+
+	copied := *taskStats
+	repo.taskStats[taskStats.TaskID] = &copied
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) CreateTaskPreview(ctx context.Context, items []*models.PreviewItem) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	copies := make([]*models.PreviewItem, 0, len(items))
+
+	// items of one sheet are saved together, so preview has no half of sheet
+	for _, item := range items {
+		copied, err := copyPreviewItem(item)
+		if err != nil {
+			return 0, err
+		}
+
+		copies = append(copies, copied)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, item := range copies {
+		repo.taskPreviews[item.TaskID] = append(repo.taskPreviews[item.TaskID], item)
+	}
+
+	return int64(len(copies)), nil
+}
+
+func (repo *inMemoryRepository) SelectTaskPreview(ctx context.Context, taskID int64) ([]*models.PreviewItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	items := []*models.PreviewItem{}
+
+	for _, item := range repo.taskPreviews[taskID] {
+		copied, err := copyPreviewItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, copied)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].File != items[j].File {
+			return items[i].File < items[j].File
+		}
+		if items[i].Sheet != items[j].Sheet {
+			return items[i].Sheet < items[j].Sheet
+		}
+		return items[i].Row < items[j].Row
+	})
+
+	return items, nil
+}
+
+// copyPreviewItem - deep copy of preview item made through json as in jsonb
+// columns of postgres
+func copyPreviewItem(item *models.PreviewItem) (*models.PreviewItem, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := new(models.PreviewItem)
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, err
+	}
+
+	return copied, nil
+}
+
+func (repo *inMemoryRepository) CreateTaskErrors(ctx context.Context, taskID int64,
+	rowErrors []models.RowError) (int64, error) {
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, rowError := range rowErrors {
+		repo.taskErrors[taskID] = append(repo.taskErrors[taskID], copyRowError(rowError))
+	}
+
+	return int64(len(rowErrors)), nil
+}
+
+func (repo *inMemoryRepository) SelectTaskErrors(ctx context.Context, taskID int64) ([]models.RowError, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	rowErrors := []models.RowError{}
+	for _, rowError := range repo.taskErrors[taskID] {
+		rowErrors = append(rowErrors, copyRowError(rowError))
+	}
+
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+
+	return rowErrors, nil
+}
+
+// copyRowError - copy of error of row which does not share its details
+func copyRowError(rowError models.RowError) models.RowError {
+	if rowError.Details != nil {
+		rowError.Details = append([]models.ErrorDetail{}, rowError.Details...)
+	}
+
+	return rowError
+}
+
+func (repo *inMemoryRepository) SelectExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	rates := []*models.ExchangeRate{}
+	for _, rate := range repo.exchangeRates {
+		copied := *rate
+		rates = append(rates, &copied)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+
+	return rates, nil
+}
+
+func (repo *inMemoryRepository) UpdateExchangeRate(ctx context.Context, rate *models.ExchangeRate) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if !rate.Rate.IsPositive() {
+		return 0, domainErrors.Validation("invalid exchange rate")
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	// rate of new currency is created
+	repo.exchangeRates[rate.Currency] = &models.ExchangeRate{
+		Currency:  rate.Currency,
+		Rate:      rate.Rate,
+		UpdatedAt: time.Now(),
+	}
+
+	return 1, nil
+}
+
+func (repo *inMemoryRepository) DeleteExchangeRate(ctx context.Context, currency string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.exchangeRates[currency]; !ok {
+		return 0, nil
+	}
+
+	delete(repo.exchangeRates, currency)
+
+	return 1, nil
+}
+
+// searchWeights - weights of fields of product in search rank, they are
+// default weights of ts_rank for A, B and C labels of search_vector
+var searchWeights = []struct {
+	weight float64
+	field  func(*models.ProductInfo) string
+}{
+	{1, func(p *models.ProductInfo) string { return p.Name + " " + p.SKU + " " + p.Brand }},
+	{0.4, func(p *models.ProductInfo) string { return p.Category }},
+	{0.2, func(p *models.ProductInfo) string { return p.Description }},
+}
+
+// SearchProducts - search products having all words of query by prefix,
+// unlike postgres words are not stemmed
+func (repo *inMemoryRepository) SearchProducts(ctx context.Context, searchRequest *models.SearchRequest) ([]*models.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	words := tools.SearchWords(searchRequest.Query)
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	results := []*models.SearchResult{}
+
+	for _, product := range repo.sortedProducts() {
+		if product.DeletedAt != nil || (searchRequest.SellerID > 0 && product.SellerID != searchRequest.SellerID) {
+			continue
+		}
+
+		rank, ok := searchRank(product, words)
+		if !ok {
+			continue
+		}
+
+		copied, err := copyProduct(product)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, &models.SearchResult{
+			Product: copied,
+			Rank:    rank,
+			Snippet: highlightWords(product.Name+" "+product.Description, words),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+
+	if searchRequest.Offset >= len(results) {
+		return []*models.SearchResult{}, nil
+	}
+	results = results[searchRequest.Offset:]
+
+	if len(results) > searchRequest.Limit {
+		results = results[:searchRequest.Limit]
+	}
+
+	return results, nil
+}
+
+// searchRank - rank of product having all words, it is mean of weights of
+// the most important fields where words are found
+func searchRank(product *models.ProductInfo, words []string) (float64, bool) {
+	if len(words) == 0 {
+		return 0, false
+	}
+
+	rank := 0.0
+
+	for _, word := range words {
+		found := false
+		for _, weight := range searchWeights {
+			if hasWordWithPrefix(weight.field(product), word) {
+				rank += weight.weight
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	return rank / float64(len(words)), true
+}
+
+// hasWordWithPrefix - text has word starting with prefix
+func hasWordWithPrefix(text, prefix string) bool {
+	for _, word := range tools.SearchWords(text) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlightWords - wrap words of text starting with any of prefixes by
+// <b></b>, text is html escaped, so only highlight is markup of snippet
+func highlightWords(text string, prefixes []string) string {
+	var snippet strings.Builder
+
+	isSeparator := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	for len(text) > 0 {
+		end := strings.IndexFunc(text, isSeparator)
+		if end == 0 {
+			end = strings.IndexFunc(text, func(r rune) bool { return !isSeparator(r) })
+			if end < 0 {
+				end = len(text)
+			}
+			snippet.WriteString(html.EscapeString(text[:end]))
+			text = text[end:]
+			continue
+		}
+		if end < 0 {
+			end = len(text)
+		}
+
+		word := text[:end]
+		if matchesAnyPrefix(strings.ToLower(word), prefixes) {
+			snippet.WriteString("<b>" + html.EscapeString(word) + "</b>")
+		} else {
+			snippet.WriteString(html.EscapeString(word))
+		}
+		text = text[end:]
+	}
+
+	return strings.TrimSpace(snippet.String())
+}
+
+// matchesAnyPrefix - word starts with any of prefixes
+func matchesAnyPrefix(word string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,230 +1,719 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
 type repository struct {
 	DB *sql.DB
+	// replicas - where read queries which tolerate lag are routed, nil
+	// means all queries go to DB
+	replicas *replicaSet
+	// timeouts of every query, zero means without timeout
+	readTimeout  time.Duration
+	writeTimeout time.Duration
 }
 
 // NewRepository - create new repository that implement IRepository interface
-func NewRepository() businessConnService.IRepository {
-	logger := logrus.New()
-
-	host := viper.GetString("DBHost")
-	port := viper.GetInt("DBPort")
-	user := viper.GetString("DBUser")
-	password := viper.GetString("DBPassword")
-	dbname := viper.GetString("DBName")
-
-	dbInfo := fmt.Sprintf("host=%s port=%d user=%s "+
-		"password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+func NewRepository(config PostgresConfig, logger *logrus.Logger) businessConnService.IRepository {
+	if err := config.validate(); err != nil {
+		logger.WithError(err).Error("DB config error")
+		return nil
+	}
 
-	db, err := sql.Open("postgres", dbInfo)
+	db, err := config.open("", 0)
 	if err != nil {
-		logger.WithField("Error info", err).Error("DB error")
+		logger.WithError(err).Error("DB error")
 		return nil
 	}
-	db.SetMaxOpenConns(10)
 
 	err = db.Ping()
 	if err != nil {
-		logger.WithField("Error info", err).Error("DB error")
+		logger.WithError(err).Error("DB error")
 		return nil
 	}
 
-	return &repository{
-		DB: db,
+	// replica which is down at start is not an error, it is used when it is up
+	replicas := map[string]*sql.DB{}
+	for _, address := range config.Replicas {
+		host, port, err := splitHostPort(address, config.Port)
+		if err != nil {
+			logger.WithError(err).WithField("replica", address).Error("DB config error")
+			return nil
+		}
+
+		replica, err := config.open(host, port)
+		if err != nil {
+			logger.WithError(err).WithField("replica", address).Error("DB error")
+			return nil
+		}
+		replicas[address] = replica
+	}
+
+	repo := &repository{
+		DB:           db,
+		readTimeout:  config.ReadTimeout,
+		writeTimeout: config.WriteTimeout,
+	}
+	if len(replicas) > 0 {
+		repo.replicas = newReplicaSet(replicas, config.ReplicaMaxLag, config.ReplicaCheckInterval, logger)
+	}
+
+	return repo
+}
+
+// splitHostPort - host and port of address "host[:port]", defaultPort is
+// used if address has no port
+func splitHostPort(address string, defaultPort int) (string, int, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		// address without port
+		return address, defaultPort, nil
+	}
+
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port of %q: %w", address, err)
 	}
+
+	return host, portNumber, nil
 }
 
-func (repo *repository) SelectProduct(sellerID, offerID int64) (*models.ProductInfo, error) {
-	productInfo := new(models.ProductInfo)
+// withTimeout - return ctx with timeout of operation if it is set
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
 
-	err := repo.DB.
-		QueryRow("SELECT * FROM productsinfo WHERE seller_id = $1 AND offer_id = $2", sellerID, offerID).
-		Scan(&productInfo.SellerID, &productInfo.OfferID, &productInfo.Name, &productInfo.Price,
-			&productInfo.Quantity, &productInfo.Available)
+	return context.WithTimeout(ctx, timeout)
+}
+
+// productFields - columns of productsinfo which are set by create and update
+// of product, their values are returned by productFieldValues in this order
+var productFields = []string{"name", "price", "currency", "quantity", "available", "sku", "description",
+	"category", "brand", "images", "weight", "length", "width", "height", "extras"}
+
+// productColumns - columns of productsinfo in order of scanProduct
+var productColumns = "seller_id, offer_id, " + strings.Join(productFields, ", ") + ", version, deleted_at"
+
+// arrayColumn - value which stores images of product in column of array and
+// scans them from it, format of array depends on database
+type arrayColumn func(images *[]string) interface{}
+
+// postgresArray - images are kept in array of postgres
+func postgresArray(images *[]string) interface{} {
+	return pq.Array(images)
+}
+
+// productFieldValues - values of productFields of product for postgres
+func productFieldValues(product *models.ProductInfo) ([]interface{}, error) {
+	return productFieldValuesWith(product, postgresArray)
+}
+
+// productFieldValuesWith - values of productFields of product, images are
+// stored by array
+func productFieldValuesWith(product *models.ProductInfo, array arrayColumn) ([]interface{}, error) {
+	var length, width, height decimal.NullDecimal
+	if product.Dimensions != nil {
+		length = decimal.NewNullDecimal(product.Dimensions.Length)
+		width = decimal.NewNullDecimal(product.Dimensions.Width)
+		height = decimal.NewNullDecimal(product.Dimensions.Height)
+	}
+
+	var weight decimal.NullDecimal
+	if product.Weight != nil {
+		weight = decimal.NewNullDecimal(*product.Weight)
+	}
+
+	images := product.Images
+	if images == nil {
+		images = []string{}
+	}
+
+	extras := []byte("{}")
+	if len(product.Extras) > 0 {
+		var err error
+		if extras, err = json.Marshal(product.Extras); err != nil {
+			return nil, err
+		}
+	}
+
+	return []interface{}{product.Name, product.Price, product.Currency, product.Quantity, product.Available,
+		product.SKU, product.Description, product.Category, product.Brand, array(&images), weight,
+		length, width, height, extras}, nil
+}
+
+// placeholders - "$first, ..., $(first+n-1)"
+func placeholders(first, n int) string {
+	result := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, "$"+strconv.Itoa(first+i))
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// fieldAssignments - "field = $n" for every field of productFields, numbers
+// of placeholders start from first
+func fieldAssignments(first int) string {
+	assignments := make([]string, 0, len(productFields))
+	for i, field := range productFields {
+		assignments = append(assignments, field+" = $"+strconv.Itoa(first+i))
+	}
+
+	return strings.Join(assignments, ", ")
+}
+
+// likeEscaper - escape special characters of LIKE pattern
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// scanProduct - scan row of postgres with productColumns to product, empty
+// optional attributes are left nil. Columns selected after productColumns
+// are scanned to extra
+func scanProduct(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.ProductInfo, error) {
+	return scanProductWith(row, postgresArray, extra...)
+}
+
+// scanProductWith - scan row with productColumns to product, images are
+// scanned by array
+func scanProductWith(row interface{ Scan(...interface{}) error }, array arrayColumn,
+	extra ...interface{}) (*models.ProductInfo, error) {
+
+	product := new(models.ProductInfo)
+
+	var (
+		weight, length, width, height decimal.NullDecimal
+		extras                        []byte
+		deletedAt                     sql.NullTime
+	)
+
+	dest := []interface{}{&product.SellerID, &product.OfferID, &product.Name, &product.Price,
+		&product.Currency, &product.Quantity, &product.Available, &product.SKU, &product.Description,
+		&product.Category, &product.Brand, array(&product.Images), &weight, &length, &width, &height,
+		&extras, &product.Version, &deletedAt}
+
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(product.Images) == 0 {
+		product.Images = nil
+	}
+
+	if weight.Valid {
+		product.Weight = &weight.Decimal
+	}
+
+	if length.Valid && width.Valid && height.Valid {
+		product.Dimensions = &models.Dimensions{
+			Length: length.Decimal,
+			Width:  width.Decimal,
+			Height: height.Decimal,
+		}
+	}
+
+	if len(extras) > 0 {
+		if err := json.Unmarshal(extras, &product.Extras); err != nil {
+			return nil, err
+		}
+		if len(product.Extras) == 0 {
+			product.Extras = nil
+		}
+	}
+
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.Time
+	}
+
+	return product, nil
+}
+
+func (repo *repository) SelectProduct(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectProduct")
+	defer span.End()
+
+	productInfo, err := scanProduct(repo.DB.
+		QueryRowContext(ctx, "SELECT "+productColumns+" FROM productsinfo WHERE seller_id = $1 AND offer_id = $2",
+			sellerID, offerID))
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product"))
+	}
+
 	return productInfo, nil
 }
 
-func (repo *repository) SelectProductsBySpecificProductInfo(userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+// idList - not empty list of ids of UserListRequest and column it filters
+type idList struct {
+	column  string
+	ids     []int64
+	exclude bool
+}
+
+// idLists - not empty lists of ids of request
+func idLists(userListRequest *models.UserListRequest) []idList {
+	lists := []idList{}
+
+	for _, list := range []idList{
+		{column: "seller_id", ids: userListRequest.SellerIDs},
+		{column: "offer_id", ids: userListRequest.OfferIDs},
+		{column: "seller_id", ids: userListRequest.ExcludeSellerIDs, exclude: true},
+		{column: "offer_id", ids: userListRequest.ExcludeOfferIDs, exclude: true},
+	} {
+		if len(list.ids) > 0 {
+			lists = append(lists, list)
+		}
+	}
+
+	return lists
+}
+
+func (repo *repository) SelectProductsBySpecificProductInfo(ctx context.Context, userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectProductsBySpecificProductInfo")
+	defer span.End()
+
 	products := []*models.ProductInfo{}
 
-	rows := new(sql.Rows)
-	var err error
+	conditions := []string{}
+	args := []interface{}{}
 
-	switch {
-	case userListRequest.SellerID > 0 && userListRequest.OfferID > 0:
-		rows, err = repo.DB.Query("SELECT * FROM productsinfo WHERE seller_id = $1 AND offer_id = $2",
-			userListRequest.SellerID, userListRequest.OfferID)
-	case userListRequest.SellerID > 0:
-		rows, err = repo.DB.Query("SELECT * FROM productsinfo WHERE seller_id = $1", userListRequest.SellerID)
-	case userListRequest.OfferID > 0:
-		rows, err = repo.DB.Query("SELECT * FROM productsinfo WHERE offer_id = $1", userListRequest.OfferID)
-	default:
-		rows, err = repo.DB.Query("SELECT * FROM productsinfo")
+	if userListRequest.SellerID > 0 {
+		args = append(args, userListRequest.SellerID)
+		conditions = append(conditions, "seller_id = $"+strconv.Itoa(len(args)))
 	}
-	if err != nil {
-		return nil, err
+	if userListRequest.OfferID > 0 {
+		args = append(args, userListRequest.OfferID)
+		conditions = append(conditions, "offer_id = $"+strconv.Itoa(len(args)))
+	}
+	// every list is one array parameter, so query does not depend on length of list
+	for _, list := range idLists(userListRequest) {
+		args = append(args, pq.Int64Array(list.ids))
+		if list.exclude {
+			conditions = append(conditions, list.column+" <> ALL($"+strconv.Itoa(len(args))+")")
+		} else {
+			conditions = append(conditions, list.column+" = ANY($"+strconv.Itoa(len(args))+")")
+		}
+	}
+	if userListRequest.SKU != "" {
+		args = append(args, userListRequest.SKU)
+		conditions = append(conditions, "sku = $"+strconv.Itoa(len(args)))
+	}
+	if userListRequest.Brand != "" {
+		args = append(args, userListRequest.Brand)
+		conditions = append(conditions, "brand = $"+strconv.Itoa(len(args)))
+	}
+	if userListRequest.Category != "" {
+		// subcategories are found by prefix of path
+		args = append(args, userListRequest.Category, likeEscaper.Replace(userListRequest.Category)+
+			models.CategorySeparator+"%")
+		conditions = append(conditions, "(category = $"+strconv.Itoa(len(args)-1)+
+			" OR category LIKE $"+strconv.Itoa(len(args))+" ESCAPE '\\')")
+	}
+	if !userListRequest.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		product := new(models.ProductInfo)
+	query := "SELECT " + productColumns + " FROM productsinfo"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		products = products[:0]
 
-		err := rows.Scan(&product.SellerID, &product.OfferID, &product.Name, &product.Price,
-			&product.Quantity, &product.Available)
+		rows, err := db.QueryContext(ctx, query, args...)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		defer rows.Close()
 
-		if strings.Contains(product.Name, userListRequest.Name) {
-			products = append(products, product)
+		for rows.Next() {
+			product, err := scanProduct(rows)
+			if err != nil {
+				return err
+			}
+
+			if strings.Contains(product.Name, userListRequest.Name) {
+				products = append(products, product)
+			}
 		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product"))
 	}
 
 	return products, nil
 }
 
-func (repo *repository) CreateProduct(productInfo *models.ProductInfo) (int64, error) {
-	res, err := repo.DB.Exec(
-		"INSERT INTO productsinfo (seller_id, offer_id, name, price, quantity, available) "+
-			"VALUES ($1, $2, $3, $4, $5, $6)",
-		productInfo.SellerID,
-		productInfo.OfferID,
-		productInfo.Name,
-		productInfo.Price,
-		productInfo.Quantity,
-		productInfo.Available,
-	)
+func (repo *repository) CreateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "CreateProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		old, err := selectProductForUpdate(ctx, tx, productInfo.SellerID, productInfo.OfferID)
+		if err != nil {
+			return err
+		}
+
+		if old != nil {
+			return reviveProduct(ctx, tx, postgresArray, old, productInfo, &affectedRowsCounter)
+		}
+
+		values, err := productFieldValues(productInfo)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO productsinfo (seller_id, offer_id, "+strings.Join(productFields, ", ")+", version) "+
+				"VALUES ($1, $2, "+placeholders(3, len(productFields))+", 1)",
+			append([]interface{}{productInfo.SellerID, productInfo.OfferID}, values...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		created := *productInfo
+		created.Version = 1
+
+		return recordChange(ctx, tx, models.ProductCreated, nil, &created)
+	})
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "product"))
 	}
 
-	affectedRowsCounter, err := res.RowsAffected()
+	return affectedRowsCounter, nil
+}
+
+func (repo *repository) UpdateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "UpdateProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		old, err := selectProductForUpdate(ctx, tx, productInfo.SellerID, productInfo.OfferID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not updated, zero version matches any
+		if old == nil || old.DeletedAt != nil || (productInfo.Version != 0 && productInfo.Version != old.Version) {
+			return nil
+		}
+
+		values, err := productFieldValues(productInfo)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET "+fieldAssignments(1)+", version = version + 1 "+
+				"WHERE seller_id = $"+strconv.Itoa(len(values)+1)+" AND offer_id = $"+strconv.Itoa(len(values)+2),
+			append(values, productInfo.SellerID, productInfo.OfferID)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		updated := *productInfo
+		updated.Version = old.Version + 1
+
+		return recordChange(ctx, tx, models.ProductUpdated, old, &updated)
+	})
+	if err != nil {
+		return 0, spanError(span, mapError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *repository) DeleteProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "DeleteProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		old, err := selectProductForUpdate(ctx, tx, sellerID, offerID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not deleted, zero version matches any
+		if old == nil || old.DeletedAt != nil || (version != 0 && version != old.Version) {
+			return nil
+		}
+
+		// product is only marked as deleted, so that it can be restored
+		// until PurgeDeletedProducts
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET deleted_at = now(), version = version + 1 WHERE seller_id = $1 AND offer_id = $2",
+			sellerID,
+			offerID,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, models.ProductDeleted, old, nil)
+	})
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "product"))
 	}
 
 	return affectedRowsCounter, nil
 }
 
-func (repo *repository) UpdateProduct(productInfo *models.ProductInfo) (int64, error) {
-	res, err := repo.DB.Exec(
-		"UPDATE productsinfo SET name = $1, price = $2, quantity = $3 "+
-			"WHERE seller_id = $4 AND offer_id = $5",
-		productInfo.Name,
-		productInfo.Price,
-		productInfo.Quantity,
-		productInfo.SellerID,
-		productInfo.OfferID,
+// reviveProduct - create product which was deleted but not purged yet, it
+// gets new values and next version, product which is not deleted conflicts.
+// Images are stored by array of database of tx
+func reviveProduct(ctx context.Context, tx execer, array arrayColumn, old, productInfo *models.ProductInfo,
+	affectedRowsCounter *int64) error {
+
+	if old.DeletedAt == nil {
+		return domainErrors.Conflict("product already exists")
+	}
+
+	values, err := productFieldValuesWith(productInfo, array)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE productsinfo SET "+fieldAssignments(1)+", version = version + 1, deleted_at = NULL "+
+			"WHERE seller_id = $"+strconv.Itoa(len(values)+1)+" AND offer_id = $"+strconv.Itoa(len(values)+2),
+		append(values, productInfo.SellerID, productInfo.OfferID)...,
 	)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	affectedRowsCounter, err := res.RowsAffected()
+	*affectedRowsCounter, err = res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	created := *productInfo
+	created.Version = old.Version + 1
+
+	return recordChange(ctx, tx, models.ProductCreated, old, &created)
+}
+
+func (repo *repository) RestoreProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "RestoreProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		old, err := selectProductForUpdate(ctx, tx, sellerID, offerID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not restored, zero version matches any
+		if old == nil || old.DeletedAt == nil || (version != 0 && version != old.Version) {
+			return nil
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET deleted_at = NULL, version = version + 1 WHERE seller_id = $1 AND offer_id = $2",
+			sellerID,
+			offerID,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		restored := *old
+		restored.DeletedAt = nil
+		restored.Version = old.Version + 1
+
+		return recordChange(ctx, tx, models.ProductRestored, old, &restored)
+	})
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "product"))
 	}
 
 	return affectedRowsCounter, nil
 }
 
-func (repo *repository) DeleteProduct(sellerID, offerID int64) (int64, error) {
-	res, err := repo.DB.Exec(
-		"DELETE FROM productsinfo WHERE seller_id = $1 AND offer_id = $2",
-		sellerID,
-		offerID,
+func (repo *repository) PurgeDeletedProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "PurgeDeletedProducts")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
+		"DELETE FROM productsinfo WHERE deleted_at IS NOT NULL AND deleted_at < $1",
+		deletedBefore,
 	)
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "product"))
 	}
 
 	affectedRowsCounter, err := res.RowsAffected()
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "product"))
 	}
 
 	return affectedRowsCounter, nil
 }
 
-func (repo *repository) SelectTaskState(taskID int64) (*models.TaskState, error) {
+func (repo *repository) SelectTaskState(ctx context.Context, taskID int64) (*models.TaskState, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectTaskState")
+	defer span.End()
+
 	taskState := new(models.TaskState)
 
 	err := repo.DB.
-		QueryRow("SELECT * FROM productUploadsTask WHERE task_id = $1", taskID).
+		QueryRowContext(ctx, "SELECT * FROM productUploadsTask WHERE task_id = $1", taskID).
 		Scan(&taskState.TaskID, &taskState.State)
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, mapError(err, "task"))
 	}
 
 	return taskState, nil
 }
 
-func (repo *repository) CreateTask() (int64, error) {
+func (repo *repository) CreateTask(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "CreateTask")
+	defer span.End()
+
 	taskID := int64(0)
 	stateDefault := "CREATED"
 
-	err := repo.DB.QueryRow(
+	err := repo.DB.QueryRowContext(ctx,
 		"INSERT INTO productUploadsTask (state) VALUES ($1) RETURNING task_id",
 		stateDefault,
 	).Scan(&taskID)
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "task"))
 	}
 
 	return taskID, nil
 }
 
-func (repo *repository) UpdateTaskState(taskID int64, state string) (int64, error) {
-	res, err := repo.DB.Exec(
+func (repo *repository) UpdateTaskState(ctx context.Context, taskID int64, state string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "UpdateTaskState")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
 		"UPDATE productUploadsTask SET state = $1 WHERE task_id = $2",
 		state,
 		taskID,
 	)
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "task"))
 	}
 
 	affectedRowsCounter, err := res.RowsAffected()
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "task"))
 	}
 
 	return affectedRowsCounter, nil
 }
 
-func (repo *repository) SelectTaskStatsByTaskID(taskID int64) (*models.TaskStats, error) {
+func (repo *repository) SelectTaskStatsByTaskID(ctx context.Context, taskID int64) (*models.TaskStats, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectTaskStatsByTaskID")
+	defer span.End()
+
 	taskStats := new(models.TaskStats)
 
-	err := repo.DB.
-		QueryRow("SELECT * FROM productTaskStats WHERE task_id = $1", taskID).
-		Scan(&taskStats.TaskID, &taskStats.ProductsCreated, &taskStats.ProductsUpdated,
-			&taskStats.ProductsDeleted, &taskStats.RowsWithErrors)
+	// stats are written once at the end of task, so stats which are not
+	// replicated yet are looked for on primary
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		return db.
+			QueryRowContext(ctx, "SELECT * FROM productTaskStats WHERE task_id = $1", taskID).
+			Scan(&taskStats.TaskID, &taskStats.ProductsCreated, &taskStats.ProductsUpdated,
+				&taskStats.ProductsDeleted, &taskStats.RowsWithErrors)
+	})
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, mapError(err, "task stats"))
 	}
 
 	return taskStats, nil
 }
 
-func (repo *repository) CreateTaskStats(taskStats *models.TaskStats) (int64, error) {
-	res, err := repo.DB.Exec(
+func (repo *repository) CreateTaskStats(ctx context.Context, taskStats *models.TaskStats) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "CreateTaskStats")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
 		"INSERT INTO productTaskStats "+
 			"(task_id, products_created, products_updated, products_deleted, rows_with_errors) "+
 			"VALUES ($1, $2, $3, $4, $5)",
@@ -235,12 +724,12 @@ func (repo *repository) CreateTaskStats(taskStats *models.TaskStats) (int64, err
 		taskStats.RowsWithErrors,
 	)
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "task stats"))
 	}
 
 	affectedRowsCounter, err := res.RowsAffected()
 	if err != nil {
-		return 0, err
+		return 0, spanError(span, mapError(err, "task stats"))
 	}
 
 	return affectedRowsCounter, nil
@@ -1,14 +1,26 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
 )
 
+// productTestColumns - columns of productsinfo selected by repository
+var productTestColumns = []string{"seller_id", "offer_id", "name", "price", "currency", "quantity", "available",
+	"sku", "description", "category", "brand", "images", "weight", "length", "width", "height", "extras",
+	"version", "deleted_at"}
+
 func TestSelectProduct(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -17,14 +29,15 @@ func TestSelectProduct(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.
-		NewRows([]string{"seller_id", "offer_id", "name", "price", "quantity", "available"})
+		NewRows(productTestColumns)
 
 	preparedData := []*models.ProductInfo{
 		{
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -32,7 +45,8 @@ func TestSelectProduct(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
@@ -42,7 +56,8 @@ func TestSelectProduct(t *testing.T) {
 	testOfferID := int64(1)
 
 	for _, item := range preparedData {
-		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Quantity, item.Available)
+		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Currency,
+			item.Quantity, item.Available, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), item.Version, nil)
 	}
 
 	mock.
@@ -54,7 +69,7 @@ func TestSelectProduct(t *testing.T) {
 		DB: db,
 	}
 
-	item, err := repo.SelectProduct(testSellerID, testOfferID)
+	item, err := repo.SelectProduct(context.Background(), testSellerID, testOfferID)
 	if err != nil {
 		t.Errorf("unexpected err: %s", err)
 		return
@@ -74,7 +89,7 @@ func TestSelectProduct(t *testing.T) {
 		WithArgs(testSellerID, testOfferID).
 		WillReturnError(fmt.Errorf("db_error"))
 
-	_, err = repo.SelectProduct(testSellerID, testOfferID)
+	_, err = repo.SelectProduct(context.Background(), testSellerID, testOfferID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -93,7 +108,7 @@ func TestSelectProduct(t *testing.T) {
 		WithArgs(testSellerID, testOfferID).
 		WillReturnRows(rows)
 
-	_, err = repo.SelectProduct(testSellerID, testOfferID)
+	_, err = repo.SelectProduct(context.Background(), testSellerID, testOfferID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -112,14 +127,15 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.
-		NewRows([]string{"seller_id", "offer_id", "name", "price", "quantity", "available"})
+		NewRows(productTestColumns)
 
 	preparedData := []*models.ProductInfo{
 		{
 			SellerID:  1,
 			OfferID:   1,
 			Name:      "телефон",
-			Price:     100.25,
+			Price:     decimal.RequireFromString("100.25"),
+			Currency:  "RUB",
 			Quantity:  10,
 			Available: true,
 		},
@@ -127,7 +143,8 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 			SellerID:  1,
 			OfferID:   2,
 			Name:      "телевизор",
-			Price:     57.6,
+			Price:     decimal.RequireFromString("57.6"),
+			Currency:  "RUB",
 			Quantity:  15,
 			Available: true,
 		},
@@ -143,7 +160,8 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 	}
 
 	for _, item := range preparedData {
-		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Quantity, item.Available)
+		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Currency,
+			item.Quantity, item.Available, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), item.Version, nil)
 	}
 
 	mock.
@@ -155,7 +173,7 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 		DB: db,
 	}
 
-	item, err := repo.SelectProductsBySpecificProductInfo(userListRequest)
+	item, err := repo.SelectProductsBySpecificProductInfo(context.Background(), userListRequest)
 	if err != nil {
 		t.Errorf("unexpected err: %s", err)
 		return
@@ -175,7 +193,7 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 		WithArgs(testSellerID, testOfferID).
 		WillReturnError(fmt.Errorf("db_error"))
 
-	_, err = repo.SelectProductsBySpecificProductInfo(userListRequest)
+	_, err = repo.SelectProductsBySpecificProductInfo(context.Background(), userListRequest)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -194,7 +212,7 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 		WithArgs(testSellerID, testOfferID).
 		WillReturnRows(rows)
 
-	_, err = repo.SelectProductsBySpecificProductInfo(userListRequest)
+	_, err = repo.SelectProductsBySpecificProductInfo(context.Background(), userListRequest)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -205,6 +223,88 @@ func TestSelectProductsBySpecificProductInfo(t *testing.T) {
 	}
 }
 
+func TestSelectProductsByAttributes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	weight := decimal.RequireFromString("0.25")
+	expectData := &models.ProductInfo{
+		SellerID:    1,
+		OfferID:     1,
+		Name:        "телефон",
+		Price:       decimal.RequireFromString("100.25"),
+		Currency:    "RUB",
+		Quantity:    10,
+		Available:   true,
+		SKU:         "PH-1",
+		Description: "смартфон",
+		Category:    "электроника/телефоны",
+		Brand:       "acme",
+		Images:      []string{"http://img/1.png", "http://img/2.png"},
+		Weight:      &weight,
+		Dimensions: &models.Dimensions{
+			Length: decimal.RequireFromString("15"),
+			Width:  decimal.RequireFromString("7.5"),
+			Height: decimal.RequireFromString("0.8"),
+		},
+		Extras:  map[string]interface{}{"color": "black"},
+		Version: 1,
+	}
+
+	rows := sqlmock.NewRows(productTestColumns).
+		AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "PH-1", "смартфон", "электроника/телефоны", "acme",
+			"{http://img/1.png,http://img/2.png}", "0.25", "15", "7.5", "0.8", []byte(`{"color": "black"}`), 1, nil)
+
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE sku = \$1 AND brand = \$2 `+
+			`AND \(category = \$3 OR category LIKE \$4 ESCAPE '\\'\) AND deleted_at IS NULL`).
+		WithArgs("PH-1", "acme", "электроника", `электроника/%`).
+		WillReturnRows(rows)
+
+	repo := &repository{
+		DB: db,
+	}
+
+	items, err := repo.SelectProductsBySpecificProductInfo(context.Background(), &models.UserListRequest{
+		SKU:      "PH-1",
+		Brand:    "acme",
+		Category: "электроника",
+	})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+	if len(items) != 1 || !reflect.DeepEqual(items[0], expectData) {
+		t.Errorf("results not match, want %v, have %v", expectData, items)
+		return
+	}
+
+	// like wildcards in category are escaped
+	mock.
+		ExpectQuery("SELECT (.+) FROM productsinfo WHERE").
+		WithArgs("100%_sale", `100\%\_sale/%`).
+		WillReturnRows(sqlmock.NewRows(productTestColumns))
+
+	_, err = repo.SelectProductsBySpecificProductInfo(context.Background(), &models.UserListRequest{
+		Category: "100%_sale",
+	})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+}
+
 func TestSelectTaskState(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -229,7 +329,7 @@ func TestSelectTaskState(t *testing.T) {
 		DB: db,
 	}
 
-	item, err := repo.SelectTaskState(testTaskID)
+	item, err := repo.SelectTaskState(context.Background(), testTaskID)
 	if err != nil {
 		t.Errorf("unexpected err: %s", err)
 		return
@@ -249,7 +349,7 @@ func TestSelectTaskState(t *testing.T) {
 		WithArgs(testTaskID).
 		WillReturnError(fmt.Errorf("db_error"))
 
-	_, err = repo.SelectTaskState(testTaskID)
+	_, err = repo.SelectTaskState(context.Background(), testTaskID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -268,7 +368,7 @@ func TestSelectTaskState(t *testing.T) {
 		WithArgs(testTaskID).
 		WillReturnRows(rows)
 
-	_, err = repo.SelectTaskState(testTaskID)
+	_, err = repo.SelectTaskState(context.Background(), testTaskID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -329,7 +429,7 @@ func TestSelectTaskStatsByTaskID(t *testing.T) {
 		DB: db,
 	}
 
-	item, err := repo.SelectTaskStatsByTaskID(testTaskStats.TaskID)
+	item, err := repo.SelectTaskStatsByTaskID(context.Background(), testTaskStats.TaskID)
 	if err != nil {
 		t.Errorf("unexpected err: %s", err)
 		return
@@ -349,7 +449,7 @@ func TestSelectTaskStatsByTaskID(t *testing.T) {
 		WithArgs(testTaskStats.TaskID).
 		WillReturnError(fmt.Errorf("db_error"))
 
-	_, err = repo.SelectTaskStatsByTaskID(testTaskStats.TaskID)
+	_, err = repo.SelectTaskStatsByTaskID(context.Background(), testTaskStats.TaskID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -368,7 +468,7 @@ func TestSelectTaskStatsByTaskID(t *testing.T) {
 		WithArgs(testTaskStats.TaskID).
 		WillReturnRows(rows)
 
-	_, err = repo.SelectTaskStatsByTaskID(testTaskStats.TaskID)
+	_, err = repo.SelectTaskStatsByTaskID(context.Background(), testTaskStats.TaskID)
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 		return
@@ -379,64 +479,6 @@ func TestSelectTaskStatsByTaskID(t *testing.T) {
 	}
 }
 
-func TestCreateProduct(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("cant create mock: %s", err)
-	}
-	defer db.Close()
-
-	repo := &repository{
-		DB: db,
-	}
-
-	preparedProductInfo := &models.ProductInfo{
-		SellerID:  1,
-		OfferID:   1,
-		Name:      "tele",
-		Price:     37.5,
-		Quantity:  10,
-		Available: true,
-	}
-
-	mock.
-		ExpectExec("INSERT INTO productsinfo").
-		WithArgs(preparedProductInfo.SellerID, preparedProductInfo.OfferID, preparedProductInfo.Name,
-			preparedProductInfo.Price, preparedProductInfo.Quantity, preparedProductInfo.Available).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	rowsAffected, err := repo.CreateProduct(preparedProductInfo)
-	if rowsAffected != 1 {
-		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
-		return
-	}
-
-	// query error
-	mock.
-		ExpectExec(`INSERT INTO productsinfo`).
-		WithArgs(preparedProductInfo.SellerID, preparedProductInfo.OfferID).
-		WillReturnError(fmt.Errorf("bad query"))
-
-	_, err = repo.CreateProduct(preparedProductInfo)
-	if err == nil {
-		t.Errorf("expected error, got nil")
-		return
-	}
-
-	// result error
-	mock.
-		ExpectExec(`INSERT INTO productsinfo`).
-		WithArgs(preparedProductInfo.SellerID, preparedProductInfo.OfferID, preparedProductInfo.Name,
-			preparedProductInfo.Price, preparedProductInfo.Quantity, preparedProductInfo.Available).
-		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
-
-	_, err = repo.CreateProduct(preparedProductInfo)
-	if err == nil {
-		t.Errorf("expected error, got nil")
-		return
-	}
-}
-
 func TestCreateTask(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -456,7 +498,7 @@ func TestCreateTask(t *testing.T) {
 		WithArgs(testState).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	taskID, err := repo.CreateTask()
+	taskID, err := repo.CreateTask(context.Background())
 	if taskID != testTaskID {
 		t.Errorf("bad taskID: want %v, have %v", testTaskID, taskID)
 		return
@@ -468,7 +510,7 @@ func TestCreateTask(t *testing.T) {
 		WithArgs().
 		WillReturnError(fmt.Errorf("bad query"))
 
-	_, err = repo.CreateTask()
+	_, err = repo.CreateTask(context.Background())
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
@@ -480,7 +522,7 @@ func TestCreateTask(t *testing.T) {
 		WithArgs(testState).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
 
-	_, err = repo.CreateTask()
+	_, err = repo.CreateTask(context.Background())
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
@@ -512,7 +554,7 @@ func TestCreateTaskStats(t *testing.T) {
 			preparedProductInfo.ProductsDeleted, preparedProductInfo.RowsWithErrors).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	rowsAffected, err := repo.CreateTaskStats(preparedProductInfo)
+	rowsAffected, err := repo.CreateTaskStats(context.Background(), preparedProductInfo)
 	if rowsAffected != 1 {
 		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
 		return
@@ -524,7 +566,7 @@ func TestCreateTaskStats(t *testing.T) {
 		WithArgs(preparedProductInfo.ProductsCreated, preparedProductInfo.ProductsUpdated).
 		WillReturnError(fmt.Errorf("bad query"))
 
-	_, err = repo.CreateTaskStats(preparedProductInfo)
+	_, err = repo.CreateTaskStats(context.Background(), preparedProductInfo)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
@@ -537,14 +579,14 @@ func TestCreateTaskStats(t *testing.T) {
 			preparedProductInfo.ProductsDeleted, preparedProductInfo.RowsWithErrors).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
 
-	_, err = repo.CreateTaskStats(preparedProductInfo)
+	_, err = repo.CreateTaskStats(context.Background(), preparedProductInfo)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
 	}
 }
 
-func TestUpdateProduct(t *testing.T) {
+func TestUpdateTaskState(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Can`t create mock: %s", err)
@@ -552,51 +594,26 @@ func TestUpdateProduct(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.
-		NewRows([]string{"seller_id", "offer_id", "name", "price", "quantity", "available"})
+		NewRows([]string{"task_id", "state"})
 
-	preparedData := []*models.ProductInfo{
-		{
-			SellerID:  1,
-			OfferID:   1,
-			Name:      "телефон",
-			Price:     100.25,
-			Quantity:  10,
-			Available: true,
-		},
-		{
-			SellerID:  1,
-			OfferID:   2,
-			Name:      "телевизор",
-			Price:     57.6,
-			Quantity:  15,
-			Available: true,
-		},
-	}
+	testTaskID := int64(1)
+	testState := "CREATED"
 
-	expectData := &models.ProductInfo{
-		SellerID:  1,
-		OfferID:   1,
-		Name:      "телефон",
-		Price:     150.5,
-		Quantity:  15,
-		Available: true,
-	}
+	rows = rows.AddRow(testTaskID, testState)
 
-	for _, item := range preparedData {
-		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Quantity, item.Available)
-	}
+	expectTaskID := int64(1)
+	expectTestState := "IN PROGRESS"
 
 	mock.
-		ExpectExec(`UPDATE productsinfo SET`).
-		WithArgs(expectData.Name, expectData.Price, expectData.Quantity, expectData.Available,
-			expectData.SellerID, expectData.OfferID).
+		ExpectExec(`UPDATE productUploadsTask SET`).
+		WithArgs(expectTaskID, expectTestState).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	repo := &repository{
 		DB: db,
 	}
 
-	rowsAffected, err := repo.UpdateProduct(expectData)
+	rowsAffected, err := repo.UpdateTaskState(context.Background(), expectTaskID, expectTestState)
 	if rowsAffected != 0 {
 		t.Errorf("bad rowsAffected: want %v, have %v", rowsAffected, 1)
 		return
@@ -604,11 +621,11 @@ func TestUpdateProduct(t *testing.T) {
 
 	// query error
 	mock.
-		ExpectExec(`UPDATE productsinfo SET`).
-		WithArgs(expectData.Name, expectData.Price, expectData.Quantity, expectData.Available).
+		ExpectExec(`UPDATE productUploadsTask SET`).
+		WithArgs(expectTaskID).
 		WillReturnError(fmt.Errorf("bad query"))
 
-	_, err = repo.UpdateProduct(expectData)
+	_, err = repo.UpdateTaskState(context.Background(), expectTaskID, expectTestState)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
@@ -616,159 +633,400 @@ func TestUpdateProduct(t *testing.T) {
 
 	// result error
 	mock.
-		ExpectExec(`UPDATE productsinfo SET`).
-		WithArgs(expectData.Name, expectData.Price, expectData.Quantity, expectData.Available,
-			expectData.SellerID, expectData.OfferID).
+		ExpectExec(`UPDATE productUploadsTask SET`).
+		WithArgs(expectTaskID, expectTestState).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
 
-	_, err = repo.UpdateProduct(expectData)
+	_, err = repo.UpdateTaskState(context.Background(), expectTaskID, expectTestState)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
 	}
 }
 
-func TestUpdateTaskState(t *testing.T) {
+func TestCreateProduct(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
-		t.Fatalf("Can`t create mock: %s", err)
+		t.Fatalf("cant create mock: %s", err)
 	}
 	defer db.Close()
 
-	rows := sqlmock.
-		NewRows([]string{"task_id", "state"})
+	repo := &repository{
+		DB: db,
+	}
 
-	testTaskID := int64(1)
-	testState := "CREATED"
+	preparedProductInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "tele",
+		Price:     decimal.RequireFromString("37.5"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+	}
 
-	rows = rows.AddRow(testTaskID, testState)
+	ctx := businessConnService.WithChangeSource(context.Background(), models.ChangeSource{
+		TaskID: 5,
+		File:   "products.xlsx",
+		Sheet:  "Sheet1",
+		Row:    3,
+	})
 
-	expectTaskID := int64(1)
-	expectTestState := "IN PROGRESS"
+	columns := productTestColumns
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`UPDATE productUploadsTask SET`).
-		WithArgs(expectTaskID, expectTestState).
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(preparedProductInfo.SellerID, preparedProductInfo.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.
+		ExpectExec("INSERT INTO productsinfo").
+		WithArgs(preparedProductInfo.SellerID, preparedProductInfo.OfferID, preparedProductInfo.Name,
+			preparedProductInfo.Price, preparedProductInfo.Currency, preparedProductInfo.Quantity, preparedProductInfo.Available,
+			"", "", "", "", "{}", nil, nil, nil, nil, []byte("{}")).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WithArgs(int64(1), int64(1), models.ProductCreated, nil,
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"tele","price":"37.5","currency":"RUB","quantity":10,"available":true,"version":1}`),
+			int64(5), "products.xlsx", "Sheet1", int64(3), "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	repo := &repository{
-		DB: db,
+	rowsAffected, err := repo.CreateProduct(ctx, preparedProductInfo)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
 	}
-
-	rowsAffected, err := repo.UpdateTaskState(expectTaskID, expectTestState)
-	if rowsAffected != 0 {
-		t.Errorf("bad rowsAffected: want %v, have %v", rowsAffected, 1)
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
 		return
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
 
-	// query error
+	// query error, tx is rolled back
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`UPDATE productUploadsTask SET`).
-		WithArgs(expectTaskID).
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.
+		ExpectExec(`INSERT INTO productsinfo`).
 		WillReturnError(fmt.Errorf("bad query"))
+	mock.ExpectRollback()
 
-	_, err = repo.UpdateTaskState(expectTaskID, expectTestState)
+	_, err = repo.CreateProduct(ctx, preparedProductInfo)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
 
-	// result error
+	// history error, product is not created
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`UPDATE productUploadsTask SET`).
-		WithArgs(expectTaskID, expectTestState).
-		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.
+		ExpectExec(`INSERT INTO productsinfo`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WillReturnError(fmt.Errorf("bad query"))
+	mock.ExpectRollback()
 
-	_, err = repo.UpdateTaskState(expectTaskID, expectTestState)
+	_, err = repo.CreateProduct(ctx, preparedProductInfo)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
 }
 
-func TestDeleteProduct(t *testing.T) {
+func TestUpdateProduct(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Can`t create mock: %s", err)
 	}
 	defer db.Close()
 
-	rows := sqlmock.
-		NewRows([]string{"seller_id", "offer_id", "name", "price", "quantity", "available"})
-
-	preparedData := []*models.ProductInfo{
-		{
-			SellerID:  1,
-			OfferID:   1,
-			Name:      "телефон",
-			Price:     100.25,
-			Quantity:  10,
-			Available: true,
-		},
-		{
-			SellerID:  1,
-			OfferID:   2,
-			Name:      "телевизор",
-			Price:     57.6,
-			Quantity:  15,
-			Available: true,
-		},
-	}
+	columns := productTestColumns
 
-	testData := &models.ProductInfo{
-		SellerID: 1,
-		OfferID:  1,
+	expectData := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("150.5"),
+		Currency:  "RUB",
+		Quantity:  15,
+		Available: true,
+		Version:   2,
 	}
 
-	for _, item := range preparedData {
-		rows = rows.AddRow(item.SellerID, item.OfferID, item.Name, item.Price, item.Quantity, item.Available)
+	repo := &repository{
+		DB: db,
 	}
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`DELETE FROM productsinfo WHERE`).
-		WithArgs(testData.SellerID, testData.OfferID).
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(expectData.SellerID, expectData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 2, nil))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET`).
+		WithArgs(expectData.Name, expectData.Price, expectData.Currency, expectData.Quantity, expectData.Available,
+			"", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), expectData.SellerID, expectData.OfferID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WithArgs(int64(1), int64(1), models.ProductUpdated,
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,"version":2}`),
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"150.5","currency":"RUB","quantity":15,"available":true,"version":3}`),
+			nil, "", "", nil, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	repo := &repository{
-		DB: db,
-	}
-
-	rowsAffected, err := repo.DeleteProduct(testData.SellerID, testData.OfferID)
+	rowsAffected, err := repo.UpdateProduct(context.Background(), expectData)
 	if err != nil {
 		t.Errorf("unexpected err: %s", err)
 		return
 	}
 	if rowsAffected != 1 {
-		t.Errorf("bad rowsAffected: want %v, have %v", rowsAffected, 1)
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
 		return
 	}
-
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 
-	// query error
+	// other version, nothing is updated
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`DELETE FROM productsinfo WHERE`).
-		WithArgs().
-		WillReturnError(fmt.Errorf("bad query"))
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(expectData.SellerID, expectData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 3, nil))
+	mock.ExpectCommit()
 
-	_, err = repo.DeleteProduct(testData.SellerID, testData.OfferID)
-	if err == nil {
-		t.Errorf("expected error, got nil")
+	rowsAffected, err = repo.UpdateProduct(context.Background(), expectData)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 0 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 0, rowsAffected)
 		return
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 
-	// result error
+	// query error
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`DELETE FROM productsinfo WHERE`).
-		WithArgs(testData.SellerID, testData.OfferID).
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(expectData.SellerID, expectData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 2, nil))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET`).
+		WillReturnError(fmt.Errorf("bad query"))
+	mock.ExpectRollback()
+
+	_, err = repo.UpdateProduct(context.Background(), expectData)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	// result error
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(expectData.SellerID, expectData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 2, nil))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET`).
 		WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("bad_result")))
+	mock.ExpectRollback()
+
+	_, err = repo.UpdateProduct(context.Background(), expectData)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := productTestColumns
+
+	testData := &models.ProductInfo{
+		SellerID: 1,
+		OfferID:  1,
+	}
+
+	repo := &repository{
+		DB: db,
+	}
+
+	ctx := logging.WithRequestID(context.Background(), "request")
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(testData.SellerID, testData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 1, nil))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET deleted_at = now\(\)`).
+		WithArgs(testData.SellerID, testData.OfferID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WithArgs(int64(1), int64(1), models.ProductDeleted,
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,"version":1}`),
+			nil, nil, "", "", nil, "request").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rowsAffected, err := repo.DeleteProduct(ctx, testData.SellerID, testData.OfferID, testData.Version)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", rowsAffected, 1)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	// product does not exist
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(testData.SellerID, testData.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectCommit()
+
+	rowsAffected, err = repo.DeleteProduct(ctx, testData.SellerID, testData.OfferID, testData.Version)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 0 {
+		t.Errorf("bad rowsAffected: want %v, have %v", rowsAffected, 0)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	// query error
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(testData.SellerID, testData.OfferID).
+		WillReturnError(fmt.Errorf("bad query"))
+	mock.ExpectRollback()
+
+	_, err = repo.DeleteProduct(ctx, testData.SellerID, testData.OfferID, testData.Version)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectProductHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	changedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.
+		NewRows([]string{"id", "seller_id", "offer_id", "action", "old_value", "new_value", "task_id", "file",
+			"sheet", "row_num", "request_id", "changed_at"}).
+		AddRow(1, 1, 2, models.ProductCreated, nil,
+			[]byte(`{"seller_id":1,"offer_id":2,"name":"tele","price":"10","currency":"RUB","quantity":1,"available":true,"version":1}`),
+			5, "products.xlsx", "Sheet1", 3, "request", changedAt).
+		AddRow(2, 1, 2, models.ProductDeleted,
+			[]byte(`{"seller_id":1,"offer_id":2,"name":"tele","price":"10","currency":"RUB","quantity":1,"available":true,"version":1}`),
+			nil, nil, "", "", nil, "other", changedAt)
+
+	mock.
+		ExpectQuery("SELECT (.+) FROM productHistory WHERE").
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(rows)
+
+	repo := &repository{
+		DB: db,
+	}
+
+	product := &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "tele", Price: decimal.RequireFromString("10"), Currency: "RUB", Quantity: 1,
+		Available: true, Version: 1}
+
+	expectData := []*models.ProductChange{
+		{
+			ID:       1,
+			SellerID: 1,
+			OfferID:  2,
+			Action:   models.ProductCreated,
+			NewValue: product,
+			Source: models.ChangeSource{
+				TaskID:    5,
+				File:      "products.xlsx",
+				Sheet:     "Sheet1",
+				Row:       3,
+				RequestID: "request",
+			},
+			ChangedAt: changedAt,
+		},
+		{
+			ID:        2,
+			SellerID:  1,
+			OfferID:   2,
+			Action:    models.ProductDeleted,
+			OldValue:  product,
+			Source:    models.ChangeSource{RequestID: "other"},
+			ChangedAt: changedAt,
+		},
+	}
+
+	changes, err := repo.SelectProductHistory(context.Background(), 1, 2)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(changes, expectData) {
+		t.Errorf("results not match, want %v, have %v", expectData, changes)
+		return
+	}
 
-	_, err = repo.DeleteProduct(testData.SellerID, testData.OfferID)
+	// query error
+	mock.
+		ExpectQuery("SELECT (.+) FROM productHistory WHERE").
+		WithArgs(int64(1), int64(2)).
+		WillReturnError(fmt.Errorf("db_error"))
+
+	_, err = repo.SelectProductHistory(context.Background(), 1, 2)
 	if err == nil {
 		t.Errorf("expected error, got nil")
 		return
@@ -777,3 +1035,610 @@ func TestDeleteProduct(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestSelectProductsDeletedFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := productTestColumns
+
+	repo := &repository{
+		DB: db,
+	}
+
+	// deleted products are skipped by default
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE seller_id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.SelectProductsBySpecificProductInfo(context.Background(), &models.UserListRequest{SellerID: 1})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	// deleted products are returned with deleted_at
+	deletedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo$`).
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 2, deletedAt))
+
+	products, err := repo.SelectProductsBySpecificProductInfo(context.Background(),
+		&models.UserListRequest{IncludeDeleted: true})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(products) != 1 || products[0].DeletedAt == nil || !products[0].DeletedAt.Equal(deletedAt) {
+		t.Errorf("results not match, want deleted product, have %v", products)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectProductsByIDLists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := &repository{
+		DB: db,
+	}
+
+	// every list is passed as one array parameter
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE seller_id = ANY\(\$1\) AND offer_id = ANY\(\$2\) `+
+			`AND seller_id <> ALL\(\$3\) AND offer_id <> ALL\(\$4\) AND deleted_at IS NULL$`).
+		WithArgs("{1,2}", "{10,20,30}", "{3}", "{40}").
+		WillReturnRows(sqlmock.NewRows(productTestColumns).
+			AddRow(1, 10, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 1, nil))
+
+	products, err := repo.SelectProductsBySpecificProductInfo(context.Background(), &models.UserListRequest{
+		SellerIDs:        []int64{1, 2},
+		OfferIDs:         []int64{10, 20, 30},
+		ExcludeSellerIDs: []int64{3},
+		ExcludeOfferIDs:  []int64{40},
+	})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(products) != 1 || products[0].OfferID != 10 {
+		t.Errorf("results not match, want product 10, have %v", products)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateDeletedProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := productTestColumns
+
+	repo := &repository{
+		DB: db,
+	}
+
+	productInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("150.5"),
+		Currency:  "RUB",
+		Quantity:  15,
+		Available: true,
+	}
+
+	deletedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// deleted product is created again with next version
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(productInfo.SellerID, productInfo.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 4, deletedAt))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET (.+) deleted_at = NULL`).
+		WithArgs(productInfo.Name, productInfo.Price, productInfo.Currency, productInfo.Quantity, productInfo.Available,
+			"", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), productInfo.SellerID, productInfo.OfferID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WithArgs(int64(1), int64(1), models.ProductCreated,
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,`+
+				`"version":4,"deleted_at":"2021-01-01T12:00:00Z"}`),
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"150.5","currency":"RUB","quantity":15,"available":true,"version":5}`),
+			nil, "", "", nil, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rowsAffected, err := repo.CreateProduct(context.Background(), productInfo)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
+		return
+	}
+
+	// product which is not deleted already exists
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(productInfo.SellerID, productInfo.OfferID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 4, nil))
+	mock.ExpectRollback()
+
+	_, err = repo.CreateProduct(context.Background(), productInfo)
+	if !errors.Is(err, domainErrors.ErrConflict) {
+		t.Errorf("expected conflict, got %v", err)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRestoreProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := productTestColumns
+
+	repo := &repository{
+		DB: db,
+	}
+
+	deletedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(int64(1), int64(1)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 2, deletedAt))
+	mock.
+		ExpectExec(`UPDATE productsinfo SET deleted_at = NULL`).
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec("INSERT INTO productHistory").
+		WithArgs(int64(1), int64(1), models.ProductRestored,
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,`+
+				`"version":2,"deleted_at":"2021-01-01T12:00:00Z"}`),
+			[]byte(`{"seller_id":1,"offer_id":1,"name":"телефон","price":"100.25","currency":"RUB","quantity":10,"available":true,"version":3}`),
+			nil, "", "", nil, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rowsAffected, err := repo.RestoreProduct(context.Background(), 1, 1, 0)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
+		return
+	}
+
+	// product is not deleted
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productsinfo WHERE (.+) FOR UPDATE`).
+		WithArgs(int64(1), int64(1)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil, []byte("{}"), 3, nil))
+	mock.ExpectCommit()
+
+	rowsAffected, err = repo.RestoreProduct(context.Background(), 1, 1, 0)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 0 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 0, rowsAffected)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPurgeDeletedProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := &repository{
+		DB: db,
+	}
+
+	deletedBefore := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectExec(`DELETE FROM productsinfo WHERE deleted_at IS NOT NULL AND deleted_at < \$1`).
+		WithArgs(deletedBefore).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	rowsAffected, err := repo.PurgeDeletedProducts(context.Background(), deletedBefore)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 3 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 3, rowsAffected)
+		return
+	}
+
+	// query error
+	mock.
+		ExpectExec(`DELETE FROM productsinfo WHERE deleted_at IS NOT NULL`).
+		WillReturnError(fmt.Errorf("bad query"))
+
+	_, err = repo.PurgeDeletedProducts(context.Background(), deletedBefore)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSelectTaskChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	changedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.
+		NewRows([]string{"id", "seller_id", "offer_id", "action", "old_value", "new_value", "task_id", "file",
+			"sheet", "row_num", "request_id", "changed_at"}).
+		AddRow(2, 1, 3, models.ProductCreated, nil,
+			[]byte(`{"seller_id":1,"offer_id":3,"name":"tele","price":"10","currency":"RUB","quantity":1,"available":true,"version":1}`),
+			5, "products.xlsx", "Sheet1", 4, "", changedAt)
+
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productHistory WHERE task_id = \$1 ORDER BY id DESC`).
+		WithArgs(int64(5)).
+		WillReturnRows(rows)
+
+	repo := &repository{
+		DB: db,
+	}
+
+	changes, err := repo.SelectTaskChanges(context.Background(), 5)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(changes) != 1 || changes[0].OfferID != 3 || changes[0].NewValue.Version != 1 {
+		t.Errorf("results not match, have %v", changes)
+		return
+	}
+
+	// query error
+	mock.
+		ExpectQuery("SELECT (.+) FROM productHistory WHERE").
+		WithArgs(int64(5)).
+		WillReturnError(fmt.Errorf("db_error"))
+
+	_, err = repo.SelectTaskChanges(context.Background(), 5)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTaskPreview(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := &repository{
+		DB: db,
+	}
+
+	items := []*models.PreviewItem{
+		{
+			TaskID:   5,
+			SellerID: 1,
+			OfferID:  3,
+			Action:   models.ProductUpdated,
+			Changes:  []models.FieldChange{{Field: "quantity", Old: 1, New: 2}},
+			File:     "products.xlsx",
+			Sheet:    "Sheet1",
+			Row:      4,
+		},
+	}
+
+	// create ok
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("INSERT INTO productTaskPreview").
+		WithArgs(int64(5), int64(1), int64(3), models.ProductUpdated, nil, nil,
+			[]byte(`[{"field":"quantity","old":1,"new":2}]`), "", "products.xlsx", "Sheet1", int64(4)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	rowsAffected, err := repo.CreateTaskPreview(context.Background(), items)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
+		return
+	}
+
+	// create error
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("INSERT INTO productTaskPreview").
+		WillReturnError(fmt.Errorf("db_error"))
+	mock.ExpectRollback()
+
+	_, err = repo.CreateTaskPreview(context.Background(), items)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	// select ok
+	rows := sqlmock.
+		NewRows([]string{"task_id", "seller_id", "offer_id", "action", "old_value", "new_value", "changes",
+			"error", "file", "sheet", "row_num"}).
+		AddRow(5, 1, 3, models.ProductUpdated, nil, nil, []byte(`[{"field":"quantity","old":1,"new":2}]`),
+			"", "products.xlsx", "Sheet1", 4)
+
+	mock.
+		ExpectQuery(`SELECT (.+) FROM productTaskPreview WHERE task_id = \$1 ORDER BY file, sheet, row_num`).
+		WithArgs(int64(5)).
+		WillReturnRows(rows)
+
+	preview, err := repo.SelectTaskPreview(context.Background(), 5)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(preview) != 1 || preview[0].OfferID != 3 || len(preview[0].Changes) != 1 ||
+		preview[0].Changes[0].Field != "quantity" {
+		t.Errorf("results not match, have %v", preview)
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExchangeRates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	repo := &repository{
+		DB: db,
+	}
+
+	updatedAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// select ok
+	rows := sqlmock.
+		NewRows([]string{"currency", "rate", "updated_at"}).
+		AddRow("USD", "90.5", updatedAt)
+
+	mock.
+		ExpectQuery("SELECT currency, rate, updated_at FROM exchangeRates").
+		WillReturnRows(rows)
+
+	rates, err := repo.SelectExchangeRates(context.Background())
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if len(rates) != 1 || rates[0].Currency != "USD" || rates[0].Rate.String() != "90.5" {
+		t.Errorf("results not match, have %v", rates)
+		return
+	}
+
+	// update ok
+	rate := &models.ExchangeRate{Currency: "USD", Rate: decimal.RequireFromString("91")}
+
+	mock.
+		ExpectExec("INSERT INTO exchangeRates (.+) ON CONFLICT").
+		WithArgs("USD", rate.Rate).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rowsAffected, err := repo.UpdateExchangeRate(context.Background(), rate)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 1 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 1, rowsAffected)
+		return
+	}
+
+	// delete of missing rate
+	mock.
+		ExpectExec("DELETE FROM exchangeRates WHERE currency = ").
+		WithArgs("EUR").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rowsAffected, err = repo.DeleteExchangeRate(context.Background(), "EUR")
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if rowsAffected != 0 {
+		t.Errorf("bad rowsAffected: want %v, have %v", 0, rowsAffected)
+		return
+	}
+
+	// query error
+	mock.
+		ExpectQuery("SELECT currency, rate, updated_at FROM exchangeRates").
+		WillReturnError(fmt.Errorf("db_error"))
+
+	_, err = repo.SelectExchangeRates(context.Background())
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"task_id", "state"}).AddRow(1, "DONE")
+
+	mock.
+		ExpectQuery("SELECT (.+) FROM productUploadsTask WHERE").
+		WithArgs(1).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(rows)
+
+	repo := &repository{
+		DB:          db,
+		readTimeout: 10 * time.Millisecond,
+	}
+
+	_, err = repo.SelectTaskState(context.Background(), 1)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+
+	// cancelled context of request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = repo.CreateTask(ctx)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+}
+
+func TestSearchProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := append(append([]string{}, productTestColumns...), "rank", "ts_headline")
+
+	expectData := []*models.SearchResult{
+		{
+			Product: &models.ProductInfo{
+				SellerID:  1,
+				OfferID:   1,
+				Name:      "телефон",
+				Price:     decimal.RequireFromString("100.25"),
+				Currency:  "RUB",
+				Quantity:  10,
+				Available: true,
+				Version:   1,
+			},
+			Rank:    0.6,
+			Snippet: "<b>телефон</b>",
+		},
+	}
+
+	rows := sqlmock.NewRows(columns).
+		AddRow(1, 1, "телефон", "100.25", "RUB", 10, true, "", "", "", "", "{}", nil, nil, nil, nil,
+			[]byte("{}"), 1, nil, 0.6, "<b>телефон</b>")
+
+	mock.
+		ExpectQuery(`SELECT (.+), ts_rank\(search_vector, q\) AS rank, ts_headline\((.+)\) FROM productsinfo, `+
+			`to_tsquery\('russian', \$1\) \|\| to_tsquery\('english', \$1\) AS q `+
+			`WHERE search_vector @@ q AND deleted_at IS NULL AND seller_id = \$2 `+
+			`ORDER BY rank DESC, seller_id, offer_id LIMIT \$3 OFFSET \$4`).
+		WithArgs("телеф:* & 12:*", int64(1), 20, 40).
+		WillReturnRows(rows)
+
+	repo := &repository{
+		DB: db,
+	}
+
+	results, err := repo.SearchProducts(context.Background(), &models.SearchRequest{
+		Query:    "Телеф, 12!",
+		SellerID: 1,
+		Limit:    20,
+		Offset:   40,
+	})
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+	if !reflect.DeepEqual(results, expectData) {
+		t.Errorf("results not match, want %v, have %v", expectData, results)
+		return
+	}
+
+	// query error
+
+	mock.
+		ExpectQuery("SELECT (.+) FROM productsinfo").
+		WithArgs("телеф:*", 20, 0).
+		WillReturnError(fmt.Errorf("db_error"))
+
+	_, err = repo.SearchProducts(context.Background(), &models.SearchRequest{Query: "телеф", Limit: 20})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+		return
+	}
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+}
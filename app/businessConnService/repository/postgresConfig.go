@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// sslModes - values of sslmode supported by lib/pq
+var sslModes = map[string]bool{"disable": true, "require": true, "verify-ca": true, "verify-full": true}
+
+// PostgresConfig - connection, pool and replica settings of postgres, DSN
+// replaces host, port, credentials, database and ssl settings if it is set
+type PostgresConfig struct {
+	DSN string
+
+	Host        string
+	Port        int
+	User        string
+	Password    string
+	Name        string
+	SSLMode     string
+	SSLCert     string
+	SSLKey      string
+	SSLRootCert string
+
+	// StatementTimeout - limit of every statement on server side, zero
+	// means server default
+	StatementTimeout time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Replicas - addresses "host[:port]" of read replicas
+	Replicas             []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+
+	// timeouts of every query, zero means without timeout
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// validate - check settings before connecting, so misconfiguration is
+// reported at start and not on first query
+func (config PostgresConfig) validate() error {
+	if config.DSN != "" {
+		if _, err := config.baseDSN(); err != nil {
+			return fmt.Errorf("invalid DBDSN: %w", err)
+		}
+	} else {
+		if config.Host == "" {
+			return errors.New("DBHost or DBDSN must be set")
+		}
+		if config.Port <= 0 || config.Port > 65535 {
+			return fmt.Errorf("invalid DBPort %d", config.Port)
+		}
+		if !sslModes[config.SSLMode] {
+			return fmt.Errorf("invalid DBSSLMode %q, it must be one of disable, require, verify-ca, verify-full",
+				config.SSLMode)
+		}
+		if (config.SSLCert == "") != (config.SSLKey == "") {
+			return errors.New("DBSSLCert and DBSSLKey must be set together")
+		}
+		for _, file := range []string{config.SSLCert, config.SSLKey, config.SSLRootCert} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				return fmt.Errorf("invalid ssl file: %w", err)
+			}
+		}
+	}
+
+	if config.StatementTimeout < 0 {
+		return fmt.Errorf("invalid DBStatementTimeout %s", config.StatementTimeout)
+	}
+	if config.MaxOpenConns < 0 {
+		return fmt.Errorf("invalid DBMaxOpenConns %d", config.MaxOpenConns)
+	}
+	if config.MaxIdleConns < 0 {
+		return fmt.Errorf("invalid DBMaxIdleConns %d", config.MaxIdleConns)
+	}
+	if config.MaxOpenConns > 0 && config.MaxIdleConns > config.MaxOpenConns {
+		return fmt.Errorf("DBMaxIdleConns %d is greater than DBMaxOpenConns %d", config.MaxIdleConns,
+			config.MaxOpenConns)
+	}
+	if config.ConnMaxLifetime < 0 || config.ConnMaxIdleTime < 0 {
+		return errors.New("DBConnMaxLifetime and DBConnMaxIdleTime must not be negative")
+	}
+	for _, address := range config.Replicas {
+		if _, _, err := splitHostPort(address, config.Port); err != nil {
+			return fmt.Errorf("invalid DBReplicas: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// dsnValue - value of key/value connection string, it is quoted if it is
+// empty or contains spaces, quotes or backslashes
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// baseDSN - key/value connection string of primary, URL in DSN is
+// converted to key/value form
+func (config PostgresConfig) baseDSN() (string, error) {
+	if config.DSN != "" {
+		if strings.HasPrefix(config.DSN, "postgres://") || strings.HasPrefix(config.DSN, "postgresql://") {
+			return pq.ParseURL(config.DSN)
+		}
+
+		// key/value string is checked by parser of lib/pq
+		if _, err := pq.NewConnector(config.DSN); err != nil {
+			return "", err
+		}
+
+		return config.DSN, nil
+	}
+
+	options := []string{
+		"host=" + dsnValue(config.Host),
+		"port=" + strconv.Itoa(config.Port),
+		"user=" + dsnValue(config.User),
+		"password=" + dsnValue(config.Password),
+		"dbname=" + dsnValue(config.Name),
+		"sslmode=" + dsnValue(config.SSLMode),
+	}
+	if config.SSLCert != "" {
+		options = append(options, "sslcert="+dsnValue(config.SSLCert), "sslkey="+dsnValue(config.SSLKey))
+	}
+	if config.SSLRootCert != "" {
+		options = append(options, "sslrootcert="+dsnValue(config.SSLRootCert))
+	}
+
+	return strings.Join(options, " "), nil
+}
+
+// dsn - connection string of host and port, empty host means primary.
+// Later options of key/value string override earlier ones in lib/pq, so
+// replica differs from primary only by host and port
+func (config PostgresConfig) dsn(host string, port int) (string, error) {
+	dsn, err := config.baseDSN()
+	if err != nil {
+		return "", err
+	}
+
+	if host != "" {
+		dsn += " host=" + dsnValue(host) + " port=" + strconv.Itoa(port)
+	}
+
+	// unknown options are sent by lib/pq to server as run-time parameters
+	if config.StatementTimeout > 0 {
+		dsn += " statement_timeout=" + strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+	}
+
+	return dsn, nil
+}
+
+// open - open pool of connections to host and port, empty host means primary
+func (config PostgresConfig) open(host string, port int) (*sql.DB, error) {
+	dsn, err := config.dsn(host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	return db, nil
+}
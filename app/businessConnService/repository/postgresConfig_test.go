@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPostgresConfig - valid config of connection without DSN
+func testPostgresConfig() PostgresConfig {
+	return PostgresConfig{
+		Host:         "localhost",
+		Port:         5432,
+		User:         "avito",
+		Password:     "avito",
+		Name:         "avito",
+		SSLMode:      "disable",
+		MaxOpenConns: 10,
+		MaxIdleConns: 2,
+	}
+}
+
+func TestPostgresConfigValidate(t *testing.T) {
+	assert.NoError(t, testPostgresConfig().validate())
+
+	dsnConfig := testPostgresConfig()
+	dsnConfig.Host = ""
+	dsnConfig.DSN = "postgres://avito:avito@db:5432/avito?sslmode=require"
+	assert.NoError(t, dsnConfig.validate())
+
+	for name, change := range map[string]func(config *PostgresConfig){
+		"without host":           func(config *PostgresConfig) { config.Host = "" },
+		"invalid port":           func(config *PostgresConfig) { config.Port = 70000 },
+		"invalid sslmode":        func(config *PostgresConfig) { config.SSLMode = "prefer" },
+		"cert without key":       func(config *PostgresConfig) { config.SSLCert = "client.crt" },
+		"missing root cert":      func(config *PostgresConfig) { config.SSLRootCert = filepath.Join(t.TempDir(), "root.crt") },
+		"negative timeout":       func(config *PostgresConfig) { config.StatementTimeout = -time.Second },
+		"negative pool":          func(config *PostgresConfig) { config.MaxOpenConns = -1 },
+		"idle greater than open": func(config *PostgresConfig) { config.MaxIdleConns = 11 },
+		"negative lifetime":      func(config *PostgresConfig) { config.ConnMaxLifetime = -time.Second },
+		"invalid dsn":            func(config *PostgresConfig) { config.DSN = "host='db" },
+		"invalid url":            func(config *PostgresConfig) { config.DSN = "postgres://db:port/avito" },
+		"invalid replica":        func(config *PostgresConfig) { config.Replicas = []string{"replica:port"} },
+	} {
+		config := testPostgresConfig()
+		change(&config)
+		assert.Error(t, config.validate(), name)
+	}
+}
+
+func TestPostgresConfigDSN(t *testing.T) {
+	config := testPostgresConfig()
+	config.Password = "it's secret"
+	config.SSLMode = "verify-full"
+	config.SSLRootCert = "/etc/ssl/root.crt"
+	config.StatementTimeout = 30 * time.Second
+
+	dsn, err := config.dsn("", 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `host=localhost port=5432 user=avito password='it\'s secret' dbname=avito `+
+			`sslmode=verify-full sslrootcert=/etc/ssl/root.crt statement_timeout=30000`, dsn)
+	}
+
+	// replica has host and port of its own
+
+	dsn, err = config.dsn("replica", 5433)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `host=localhost port=5432 user=avito password='it\'s secret' dbname=avito `+
+			`sslmode=verify-full sslrootcert=/etc/ssl/root.crt host=replica port=5433 statement_timeout=30000`, dsn)
+	}
+
+	// url is converted to key/value string
+
+	config = PostgresConfig{DSN: "postgres://avito@db/avito?sslmode=disable"}
+
+	dsn, err = config.dsn("replica", 5432)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "dbname=avito host=db sslmode=disable user=avito host=replica port=5432", dsn)
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+func (repo *repository) CreateTaskPreview(ctx context.Context, items []*models.PreviewItem) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "CreateTaskPreview")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	// items of one sheet are saved together, so preview has no half of sheet
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		var err error
+		affectedRowsCounter, err = insertPreviewItems(ctx, tx, items)
+		return err
+	})
+	if err != nil {
+		return 0, spanError(span, mapError(err, "task preview"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *repository) SelectTaskPreview(ctx context.Context, taskID int64) ([]*models.PreviewItem, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectTaskPreview")
+	defer span.End()
+
+	var items []*models.PreviewItem
+
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx,
+			"SELECT "+previewColumns+" FROM productTaskPreview WHERE task_id = $1 ORDER BY file, sheet, row_num",
+			taskID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		items, err = scanPreviewItems(rows)
+		return err
+	})
+	if err != nil {
+		return nil, spanError(span, mapError(err, "task preview"))
+	}
+
+	return items, nil
+}
+
+// insertPreviewItems - insert items to productTaskPreview in tx, number of
+// inserted rows is returned
+func insertPreviewItems(ctx context.Context, tx execer, items []*models.PreviewItem) (int64, error) {
+	affectedRowsCounter := int64(0)
+
+	for _, item := range items {
+		oldJSON, err := marshalValue(item.OldValue)
+		if err != nil {
+			return 0, err
+		}
+
+		newJSON, err := marshalValue(item.NewValue)
+		if err != nil {
+			return 0, err
+		}
+
+		var changesJSON interface{}
+		if len(item.Changes) > 0 {
+			if changesJSON, err = json.Marshal(item.Changes); err != nil {
+				return 0, err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO productTaskPreview "+
+				"(task_id, seller_id, offer_id, action, old_value, new_value, changes, error, file, sheet, row_num) "+
+				"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+			item.TaskID,
+			item.SellerID,
+			item.OfferID,
+			item.Action,
+			oldJSON,
+			newJSON,
+			changesJSON,
+			item.Error,
+			item.File,
+			item.Sheet,
+			item.Row,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		affectedRowsCounter += rowsAffected
+	}
+
+	return affectedRowsCounter, nil
+}
+
+// previewColumns - columns of productTaskPreview in order of scanPreviewItems
+const previewColumns = "task_id, seller_id, offer_id, action, old_value, new_value, changes, error, file, sheet, " +
+	"row_num"
+
+// scanPreviewItems - scan all rows with previewColumns to items
+func scanPreviewItems(rows *sql.Rows) ([]*models.PreviewItem, error) {
+	items := []*models.PreviewItem{}
+
+	for rows.Next() {
+		item := new(models.PreviewItem)
+
+		var oldJSON, newJSON, changesJSON []byte
+
+		err := rows.Scan(&item.TaskID, &item.SellerID, &item.OfferID, &item.Action, &oldJSON, &newJSON,
+			&changesJSON, &item.Error, &item.File, &item.Sheet, &item.Row)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.OldValue, err = unmarshalValue(oldJSON); err != nil {
+			return nil, err
+		}
+		if item.NewValue, err = unmarshalValue(newJSON); err != nil {
+			return nil, err
+		}
+		if changesJSON != nil {
+			if err := json.Unmarshal(changesJSON, &item.Changes); err != nil {
+				return nil, err
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// replicaLagQuery - seconds replica is behind primary, replica which has
+// replayed everything it received is not behind even if primary is idle
+const replicaLagQuery = "SELECT COALESCE(CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 " +
+	"ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END, 0)"
+
+// replicaCheckTimeout - max time of check of replica lag
+const replicaCheckTimeout = time.Second
+
+// replica - read replica of postgres with cached result of last check
+type replica struct {
+	DB   *sql.DB
+	name string
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	available bool
+	// checking is set while lag is queried, then other readers get cached
+	// result instead of waiting for slow replica
+	checking bool
+}
+
+// replicaSet - replicas where read queries are routed in turn, replica which
+// is down or lags more than maxLag is skipped until next check
+type replicaSet struct {
+	replicas      []*replica
+	maxLag        time.Duration
+	checkInterval time.Duration
+	checkTimeout  time.Duration
+	logger        *logrus.Logger
+
+	next uint32
+	now  func() time.Time
+}
+
+// newReplicaSet - create set of replicas, dbs are keyed by name of replica
+// used in logs
+func newReplicaSet(dbs map[string]*sql.DB, maxLag, checkInterval time.Duration, logger *logrus.Logger) *replicaSet {
+	set := &replicaSet{
+		maxLag:        maxLag,
+		checkInterval: checkInterval,
+		checkTimeout:  replicaCheckTimeout,
+		logger:        logger,
+		now:           time.Now,
+	}
+
+	for name, db := range dbs {
+		set.replicas = append(set.replicas, &replica{DB: db, name: name})
+	}
+
+	return set
+}
+
+// pick - next available replica, nil if all replicas are unavailable
+func (set *replicaSet) pick() *replica {
+	if set == nil || len(set.replicas) == 0 {
+		return nil
+	}
+
+	first := int(atomic.AddUint32(&set.next, 1))
+
+	for i := range set.replicas {
+		replica := set.replicas[(first+i)%len(set.replicas)]
+		if set.isAvailable(replica) {
+			return replica
+		}
+	}
+
+	return nil
+}
+
+// isAvailable - replica is up and its lag is tolerated, result is checked
+// at most once in checkInterval by one reader, others get cached result.
+// Replica which was never checked is unavailable until its check is done
+func (set *replicaSet) isAvailable(replica *replica) bool {
+	replica.mu.Lock()
+
+	now := set.now()
+	if replica.checking || !replica.checkedAt.IsZero() && now.Sub(replica.checkedAt) < set.checkInterval {
+		available := replica.available
+		replica.mu.Unlock()
+		return available
+	}
+
+	replica.checking = true
+	replica.mu.Unlock()
+
+	// check is shared by readers, so it is not cancelled with query of one
+	// of them
+	ctx, cancel := context.WithTimeout(context.Background(), set.checkTimeout)
+	defer cancel()
+
+	lag := 0.0
+	err := replica.DB.QueryRowContext(ctx, replicaLagQuery).Scan(&lag)
+
+	available := err == nil && time.Duration(lag*float64(time.Second)) <= set.maxLag
+
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+
+	replica.checking = false
+
+	if available != replica.available || replica.checkedAt.IsZero() {
+		set.logger.WithError(err).WithFields(logrus.Fields{
+			"replica":   replica.name,
+			"lag":       lag,
+			"available": available,
+		}).Info("Replica state is changed")
+	}
+
+	replica.checkedAt = now
+	replica.available = available
+
+	return available
+}
+
+// markDown - replica is unavailable until next check
+func (set *replicaSet) markDown(replica *replica, err error) {
+	replica.mu.Lock()
+	defer replica.mu.Unlock()
+
+	set.logger.WithError(err).WithField("replica", replica.name).Warn("Replica query failed")
+
+	replica.checkedAt = set.now()
+	replica.available = false
+}
+
+// staleness - max time replica can be behind primary, lag of replica grows
+// between checks, so it is maxLag and checkInterval
+func (set *replicaSet) staleness() time.Duration {
+	if set == nil || len(set.replicas) == 0 {
+		return 0
+	}
+
+	return set.maxLag + set.checkInterval
+}
+
+// primaryReadKey - key of context of reads which must not go to replicas
+type primaryReadKey struct{}
+
+// withPrimaryRead - reads of ctx go to primary, rows written recently can be
+// stale on replicas
+func withPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadKey{}, true)
+}
+
+// isPrimaryRead - reads of ctx must go to primary
+func isPrimaryRead(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryReadKey{}).(bool)
+	return primary
+}
+
+// replicaStaleness - how long rows written to primary can be stale on
+// replicas, zero if repository has no replicas
+func (repo *repository) replicaStaleness() time.Duration {
+	return repo.replicas.staleness()
+}
+
+// onReplica - run read query fn on available replica, it is run on primary
+// if there are no available replicas, query fails on replica or ctx is of
+// primary read. Row which is not found on replica can be not replicated yet,
+// so it is looked for on primary too
+func (repo *repository) onReplica(ctx context.Context, fn func(db *sql.DB) error) error {
+	if isPrimaryRead(ctx) {
+		return fn(repo.DB)
+	}
+
+	replica := repo.replicas.pick()
+	if replica == nil {
+		return fn(repo.DB)
+	}
+
+	err := fn(replica.DB)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		repo.replicas.markDown(replica, err)
+	}
+
+	return fn(repo.DB)
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReplicaTestRepository - repository with mocked primary and one mocked
+// replica, state of replica is checked on every query
+func newReplicaTestRepository(t *testing.T) (*repository, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	primaryDB, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	t.Cleanup(func() { primaryDB.Close() })
+
+	replicaDB, replica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Can`t create mock: %s", err)
+	}
+	t.Cleanup(func() { replicaDB.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	return &repository{
+		DB:       primaryDB,
+		replicas: newReplicaSet(map[string]*sql.DB{"replica": replicaDB}, 5*time.Second, 0, logger),
+	}, primary, replica
+}
+
+// expectReplicaLag - replica reports lag in seconds
+func expectReplicaLag(mock sqlmock.Sqlmock, lag float64) {
+	mock.ExpectQuery(regexp.QuoteMeta(replicaLagQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(lag))
+}
+
+func TestReplicaRouting(t *testing.T) {
+	repo, primary, replica := newReplicaTestRepository(t)
+	ctx := context.Background()
+
+	statsQuery := "SELECT \\* FROM productTaskStats WHERE task_id = \\$1"
+	statsColumns := []string{"task_id", "products_created", "products_updated", "products_deleted", "rows_with_errors"}
+
+	// test read is routed to replica which is in sync
+
+	expectReplicaLag(replica, 0)
+	replica.ExpectQuery(statsQuery).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(statsColumns).AddRow(1, 2, 0, 0, 0))
+
+	stats, err := repo.SelectTaskStatsByTaskID(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), stats.ProductsCreated)
+	}
+
+	// test stats which are not replicated yet are read from primary
+
+	expectReplicaLag(replica, 0)
+	replica.ExpectQuery(statsQuery).WithArgs(2).WillReturnError(sql.ErrNoRows)
+	primary.ExpectQuery(statsQuery).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(statsColumns).AddRow(2, 3, 0, 0, 0))
+
+	stats, err = repo.SelectTaskStatsByTaskID(ctx, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), stats.ProductsCreated)
+	}
+
+	// test replica which lags too much is not used
+
+	expectReplicaLag(replica, 6)
+	primary.ExpectQuery(statsQuery).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(statsColumns).AddRow(3, 4, 0, 0, 0))
+
+	stats, err = repo.SelectTaskStatsByTaskID(ctx, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(4), stats.ProductsCreated)
+	}
+
+	// test query failed on replica is repeated on primary
+
+	expectReplicaLag(replica, 0)
+	replica.ExpectQuery(statsQuery).WithArgs(4).WillReturnError(errors.New("connection refused"))
+	primary.ExpectQuery(statsQuery).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows(statsColumns).AddRow(4, 5, 0, 0, 0))
+
+	stats, err = repo.SelectTaskStatsByTaskID(ctx, 4)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(5), stats.ProductsCreated)
+	}
+
+	// test task state goes to primary
+
+	primary.ExpectQuery("SELECT \\* FROM productUploadsTask WHERE task_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "state"}).AddRow(5, "DONE"))
+
+	_, err = repo.SelectTaskState(ctx, 5)
+	assert.NoError(t, err)
+
+	assert.NoError(t, primary.ExpectationsWereMet())
+	assert.NoError(t, replica.ExpectationsWereMet())
+}
+
+func TestReplicaSetCheckInterval(t *testing.T) {
+	repo, _, replica := newReplicaTestRepository(t)
+
+	now := time.Now()
+	repo.replicas.checkInterval = time.Minute
+	repo.replicas.now = func() time.Time { return now }
+
+	// test replica which is down is not checked again until interval passes
+
+	replica.ExpectQuery(regexp.QuoteMeta(replicaLagQuery)).WillReturnError(errors.New("connection refused"))
+
+	assert.Nil(t, repo.replicas.pick())
+	assert.Nil(t, repo.replicas.pick())
+
+	now = now.Add(time.Minute)
+	expectReplicaLag(replica, 1)
+
+	assert.NotNil(t, repo.replicas.pick())
+	assert.NotNil(t, repo.replicas.pick())
+
+	assert.NoError(t, replica.ExpectationsWereMet())
+}
+
+func TestSplitHostPort(t *testing.T) {
+	for address, expected := range map[string]struct {
+		host string
+		port int
+	}{
+		"replica":       {"replica", 5432},
+		"replica:5433":  {"replica", 5433},
+		"10.0.0.1:6432": {"10.0.0.1", 6432},
+		"[::1]:5434":    {"::1", 5434},
+		"replica.local": {"replica.local", 5432},
+	} {
+		host, port, err := splitHostPort(address, 5432)
+		if assert.NoError(t, err, address) {
+			assert.Equal(t, expected.host, host, address)
+			assert.Equal(t, expected.port, port, address)
+		}
+	}
+
+	_, _, err := splitHostPort("replica:port", 5432)
+	assert.Error(t, err)
+}
+
+func TestReplicaSetCheckInProgress(t *testing.T) {
+	repo, _, replicaMock := newReplicaTestRepository(t)
+
+	// test readers do not wait for check of slow replica, they get cached
+	// result while check is in progress
+
+	replicaMock.ExpectQuery(regexp.QuoteMeta(replicaLagQuery)).WillDelayFor(200 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+
+	checked := make(chan *replica)
+	go func() { checked <- repo.replicas.pick() }()
+
+	for {
+		repo.replicas.replicas[0].mu.Lock()
+		checking := repo.replicas.replicas[0].checking
+		repo.replicas.replicas[0].mu.Unlock()
+
+		if checking {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	assert.Nil(t, repo.replicas.pick())
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+
+	assert.NotNil(t, <-checked)
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+
+	// test check of replica which does not respond is bounded by timeout
+
+	repo.replicas.checkTimeout = 50 * time.Millisecond
+
+	replicaMock.ExpectQuery(regexp.QuoteMeta(replicaLagQuery)).WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(0))
+
+	start = time.Now()
+	assert.Nil(t, repo.replicas.pick())
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// searchHeadlineOptions - options of ts_headline for snippets of search results
+const searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=30, MinWords=10, MaxFragments=2"
+
+// searchHeadlineText - html escaped text of snippet, ts_headline does not
+// escape text, so only highlight is markup of snippet. Escaped characters
+// are entities for parser of ts_headline and they are not highlighted
+const searchHeadlineText = "replace(replace(replace(replace(replace(name || ' ' || description, " +
+	`'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+
+// prefixTSQuery - tsquery text where every word of query is matched by
+// prefix and all words must be found
+func prefixTSQuery(query string) string {
+	words := tools.SearchWords(query)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
+
+func (repo *repository) SearchProducts(ctx context.Context, searchRequest *models.SearchRequest) ([]*models.SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SearchProducts")
+	defer span.End()
+
+	// search_vector is built of russian and english lexemes, so query is
+	// parsed with both configurations
+	args := []interface{}{prefixTSQuery(searchRequest.Query)}
+	conditions := []string{"search_vector @@ q", "deleted_at IS NULL"}
+
+	if searchRequest.SellerID > 0 {
+		args = append(args, searchRequest.SellerID)
+		conditions = append(conditions, "seller_id = $"+strconv.Itoa(len(args)))
+	}
+
+	args = append(args, searchRequest.Limit, searchRequest.Offset)
+
+	query := "SELECT " + productColumns + ", ts_rank(search_vector, q) AS rank, " +
+		"ts_headline('russian', " + searchHeadlineText + ", q, '" + searchHeadlineOptions + "') " +
+		"FROM productsinfo, to_tsquery('russian', $1) || to_tsquery('english', $1) AS q " +
+		"WHERE " + strings.Join(conditions, " AND ") + " " +
+		"ORDER BY rank DESC, seller_id, offer_id " +
+		"LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	results := []*models.SearchResult{}
+
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		results = results[:0]
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			result := new(models.SearchResult)
+
+			result.Product, err = scanProduct(rows, &result.Rank, &result.Snippet)
+			if err != nil {
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, spanError(span, mapError(err, "product"))
+	}
+
+	return results, nil
+}
@@ -0,0 +1,926 @@
+//go:build cgo
+// +build cgo
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// sqliteDriverName - sqlite driver with functions of search
+const sqliteDriverName = "sqlite3_businessConn"
+
+func init() {
+	sql.Register(sqliteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("search_rank", sqliteSearchRank, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("pq_array_to_json", sqlitePostgresArrayToJSON, true); err != nil {
+				return err
+			}
+
+			return conn.RegisterFunc("search_headline", sqliteSearchHeadline, true)
+		},
+	})
+}
+
+// sqliteSearchRank - search_rank(query, name, sku, brand, category, description)
+// of sqlite, zero rank means that product has not all words of query
+func sqliteSearchRank(query, name, sku, brand, category, description string) float64 {
+	rank, _ := searchRank(&models.ProductInfo{Name: name, SKU: sku, Brand: brand, Category: category,
+		Description: description}, tools.SearchWords(query))
+
+	return rank
+}
+
+// sqliteSearchHeadline - search_headline(query, text) of sqlite
+func sqliteSearchHeadline(query, text string) string {
+	return highlightWords(text, tools.SearchWords(query))
+}
+
+type sqliteRepository struct {
+	DB *sql.DB
+	// timeouts of every query, zero means without timeout
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// NewSQLiteRepository - create new repository that implement IRepository
+// interface and keeps data in sqlite database file, schema of database is
+// migrated on start
+func NewSQLiteRepository(config SQLiteConfig, logger *logrus.Logger) businessConnService.IRepository {
+	db, err := openSQLite(config.Path)
+	if err != nil {
+		logger.WithError(err).Error("DB error")
+		return nil
+	}
+
+	return &sqliteRepository{
+		DB:           db,
+		readTimeout:  config.ReadTimeout,
+		writeTimeout: config.WriteTimeout,
+	}
+}
+
+// openSQLite - open sqlite database at path and migrate its schema
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open(sqliteDriverName, "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite has only one writer, so queries are serialized by one
+	// connection instead of waiting for lock of database
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(context.Background(), db, sqliteMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// mapSQLiteError - translate error of sqlite driver to domain error like
+// mapError does for postgres, entity is used in message for client
+func mapSQLiteError(err error, entity string) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return domainErrors.Wrap(domainErrors.ErrConflict, err, entity+" already exists")
+		case sqlite3.ErrConstraintForeignKey, sqlite3.ErrConstraintCheck:
+			return domainErrors.Wrap(domainErrors.ErrValidation, err, "invalid "+entity)
+		}
+	}
+
+	return mapError(err, entity)
+}
+
+// startSQLiteSpan - start span of one query to sqlite
+func startSQLiteSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return startDBSpan(ctx, semconv.DBSystemSqlite, operation)
+}
+
+// rebind - replace $n placeholders of postgres by ?n parameters of sqlite,
+// so queries are built by the same helpers as for postgres
+func rebind(query string) string {
+	return strings.ReplaceAll(query, "$", "?")
+}
+
+// jsonStrings - strings kept in json array of text column, images of
+// product are kept in this format in sqlite
+type jsonStrings struct {
+	values *[]string
+}
+
+// jsonArray - images are kept in json array of sqlite
+func jsonArray(images *[]string) interface{} {
+	return jsonStrings{values: images}
+}
+
+func (a jsonStrings) Value() (driver.Value, error) {
+	if *a.values == nil {
+		return "[]", nil
+	}
+
+	encoded, err := json.Marshal(*a.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(encoded), nil
+}
+
+func (a jsonStrings) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, a.values)
+	case string:
+		return json.Unmarshal([]byte(src), a.values)
+	case nil:
+		*a.values = nil
+		return nil
+	default:
+		return fmt.Errorf("can not scan %T to json array", src)
+	}
+}
+
+// sqlitePostgresArrayToJSON - json array of text of postgres array, images
+// were kept in this format before they were moved to json
+func sqlitePostgresArrayToJSON(array string) (string, error) {
+	var images pq.StringArray
+	if err := images.Scan(array); err != nil {
+		return "", err
+	}
+
+	value, err := jsonStrings{values: (*[]string)(&images)}.Value()
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+// sqliteTx - transaction of sqlite which rebinds placeholders of queries
+type sqliteTx struct {
+	*sql.Tx
+}
+
+func (tx sqliteTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, rebind(query), args...)
+}
+
+func (tx sqliteTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, rebind(query), args...)
+}
+
+// inTx - run fn in transaction of sqlite, all queries are serialized by
+// one connection, so rows selected in it are locked until its end
+func (repo *sqliteRepository) inTx(ctx context.Context, fn func(tx sqliteTx) error) error {
+	return inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		return fn(sqliteTx{tx})
+	})
+}
+
+// selectSQLiteProduct - select product in tx, nil is returned if product
+// does not exist
+func selectSQLiteProduct(ctx context.Context, tx sqliteTx, sellerID, offerID int64) (*models.ProductInfo, error) {
+	product, err := scanProductWith(tx.QueryRowContext(ctx,
+		"SELECT "+productColumns+" FROM productsinfo WHERE seller_id = $1 AND offer_id = $2",
+		sellerID, offerID), jsonArray)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return product, err
+}
+
+// sqliteNow - current time in UTC, timestamps of sqlite are compared as strings
+func sqliteNow() time.Time {
+	return time.Now().UTC()
+}
+
+func (repo *sqliteRepository) SelectProduct(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectProduct")
+	defer span.End()
+
+	productInfo, err := scanProductWith(repo.DB.
+		QueryRowContext(ctx, "SELECT "+productColumns+" FROM productsinfo WHERE seller_id = ?1 AND offer_id = ?2",
+			sellerID, offerID), jsonArray)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return productInfo, nil
+}
+
+func (repo *sqliteRepository) SelectProductsBySpecificProductInfo(ctx context.Context, userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectProductsBySpecificProductInfo")
+	defer span.End()
+
+	products := []*models.ProductInfo{}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	if userListRequest.SellerID > 0 {
+		args = append(args, userListRequest.SellerID)
+		conditions = append(conditions, "seller_id = ?"+strconv.Itoa(len(args)))
+	}
+	if userListRequest.OfferID > 0 {
+		args = append(args, userListRequest.OfferID)
+		conditions = append(conditions, "offer_id = ?"+strconv.Itoa(len(args)))
+	}
+	// sqlite has no arrays, so every list is one json array parameter
+	for _, list := range idLists(userListRequest) {
+		ids, err := json.Marshal(list.ids)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+
+		args = append(args, string(ids))
+		condition := list.column + " IN (SELECT value FROM json_each(?" + strconv.Itoa(len(args)) + "))"
+		if list.exclude {
+			condition = "NOT " + condition
+		}
+		conditions = append(conditions, condition)
+	}
+	if userListRequest.SKU != "" {
+		args = append(args, userListRequest.SKU)
+		conditions = append(conditions, "sku = ?"+strconv.Itoa(len(args)))
+	}
+	if userListRequest.Brand != "" {
+		args = append(args, userListRequest.Brand)
+		conditions = append(conditions, "brand = ?"+strconv.Itoa(len(args)))
+	}
+	if userListRequest.Category != "" {
+		// subcategories are found by prefix of path, LIKE of sqlite ignores
+		// case of ASCII letters, so prefix is compared as substring
+		args = append(args, userListRequest.Category, userListRequest.Category+models.CategorySeparator)
+		conditions = append(conditions, "(category = ?"+strconv.Itoa(len(args)-1)+
+			" OR substr(category, 1, length(?"+strconv.Itoa(len(args))+")) = ?"+strconv.Itoa(len(args))+")")
+	}
+	if !userListRequest.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	query := "SELECT " + productColumns + " FROM productsinfo"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product"))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product, err := scanProductWith(rows, jsonArray)
+		if err != nil {
+			return nil, spanError(span, mapSQLiteError(err, "product"))
+		}
+
+		if strings.Contains(product.Name, userListRequest.Name) {
+			products = append(products, product)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return products, nil
+}
+
+func (repo *sqliteRepository) CreateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "CreateProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		old, err := selectSQLiteProduct(ctx, tx, productInfo.SellerID, productInfo.OfferID)
+		if err != nil {
+			return err
+		}
+
+		if old != nil {
+			return reviveProduct(ctx, tx, jsonArray, old, productInfo, &affectedRowsCounter)
+		}
+
+		values, err := productFieldValuesWith(productInfo, jsonArray)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO productsinfo (seller_id, offer_id, "+strings.Join(productFields, ", ")+", version) "+
+				"VALUES ($1, $2, "+placeholders(3, len(productFields))+", 1)",
+			append([]interface{}{productInfo.SellerID, productInfo.OfferID}, values...)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		created := *productInfo
+		created.Version = 1
+
+		return recordChange(ctx, tx, models.ProductCreated, nil, &created)
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) UpdateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "UpdateProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		old, err := selectSQLiteProduct(ctx, tx, productInfo.SellerID, productInfo.OfferID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not updated, zero version matches any
+		if old == nil || old.DeletedAt != nil || (productInfo.Version != 0 && productInfo.Version != old.Version) {
+			return nil
+		}
+
+		values, err := productFieldValuesWith(productInfo, jsonArray)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET "+fieldAssignments(1)+", version = version + 1 "+
+				"WHERE seller_id = $"+strconv.Itoa(len(values)+1)+" AND offer_id = $"+strconv.Itoa(len(values)+2),
+			append(values, productInfo.SellerID, productInfo.OfferID)...,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		updated := *productInfo
+		updated.Version = old.Version + 1
+
+		return recordChange(ctx, tx, models.ProductUpdated, old, &updated)
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) DeleteProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "DeleteProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		old, err := selectSQLiteProduct(ctx, tx, sellerID, offerID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not deleted, zero version matches any
+		if old == nil || old.DeletedAt != nil || (version != 0 && version != old.Version) {
+			return nil
+		}
+
+		// product is only marked as deleted, so that it can be restored
+		// until PurgeDeletedProducts
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET deleted_at = ?1, version = version + 1 WHERE seller_id = ?2 AND offer_id = ?3",
+			sqliteNow(),
+			sellerID,
+			offerID,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, models.ProductDeleted, old, nil)
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) RestoreProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "RestoreProduct")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		old, err := selectSQLiteProduct(ctx, tx, sellerID, offerID)
+		if err != nil {
+			return err
+		}
+
+		// product with other version is not restored, zero version matches any
+		if old == nil || old.DeletedAt == nil || (version != 0 && version != old.Version) {
+			return nil
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"UPDATE productsinfo SET deleted_at = NULL, version = version + 1 WHERE seller_id = ?1 AND offer_id = ?2",
+			sellerID,
+			offerID,
+		)
+		if err != nil {
+			return err
+		}
+
+		affectedRowsCounter, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		restored := *old
+		restored.DeletedAt = nil
+		restored.Version = old.Version + 1
+
+		return recordChange(ctx, tx, models.ProductRestored, old, &restored)
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) PurgeDeletedProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "PurgeDeletedProducts")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
+		"DELETE FROM productsinfo WHERE deleted_at IS NOT NULL AND deleted_at < ?1",
+		deletedBefore.UTC(),
+	)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) SearchProducts(ctx context.Context, searchRequest *models.SearchRequest) ([]*models.SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SearchProducts")
+	defer span.End()
+
+	// productsSearch finds products having all words by prefix, they are
+	// ranked like in memory, words are not stemmed
+	words := tools.SearchWords(searchRequest.Query)
+	for i, word := range words {
+		words[i] = word + "*"
+	}
+
+	args := []interface{}{searchRequest.Query, strings.Join(words, " ")}
+	conditions := []string{"id IN (SELECT docid FROM productsSearch WHERE productsSearch MATCH ?2)", "rank > 0",
+		"deleted_at IS NULL"}
+
+	if searchRequest.SellerID > 0 {
+		args = append(args, searchRequest.SellerID)
+		conditions = append(conditions, "seller_id = ?"+strconv.Itoa(len(args)))
+	}
+
+	args = append(args, searchRequest.Limit, searchRequest.Offset)
+
+	query := "SELECT " + productColumns + ", " +
+		"search_rank(?1, name, sku, brand, category, description) AS rank, " +
+		"search_headline(?1, name || ' ' || description) " +
+		"FROM productsinfo " +
+		"WHERE " + strings.Join(conditions, " AND ") + " " +
+		"ORDER BY rank DESC, seller_id, offer_id " +
+		"LIMIT ?" + strconv.Itoa(len(args)-1) + " OFFSET ?" + strconv.Itoa(len(args))
+
+	rows, err := repo.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product"))
+	}
+	defer rows.Close()
+
+	results := []*models.SearchResult{}
+
+	for rows.Next() {
+		result := new(models.SearchResult)
+
+		result.Product, err = scanProductWith(rows, jsonArray, &result.Rank, &result.Snippet)
+		if err != nil {
+			return nil, spanError(span, mapSQLiteError(err, "product"))
+		}
+
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product"))
+	}
+
+	return results, nil
+}
+
+func (repo *sqliteRepository) SelectProductHistory(ctx context.Context, sellerID, offerID int64) ([]*models.ProductChange, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectProductHistory")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT "+historyColumns+" FROM productHistory WHERE seller_id = ?1 AND offer_id = ?2 ORDER BY id",
+		sellerID, offerID)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product history"))
+	}
+	defer rows.Close()
+
+	changes, err := scanChanges(rows)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product history"))
+	}
+
+	return changes, nil
+}
+
+// SelectTaskChanges - select all changes of products made by task, newest first
+func (repo *sqliteRepository) SelectTaskChanges(ctx context.Context, taskID int64) ([]*models.ProductChange, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectTaskChanges")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT "+historyColumns+" FROM productHistory WHERE task_id = ?1 ORDER BY id DESC",
+		taskID)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product history"))
+	}
+	defer rows.Close()
+
+	changes, err := scanChanges(rows)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "product history"))
+	}
+
+	return changes, nil
+}
+
+func (repo *sqliteRepository) SelectTaskState(ctx context.Context, taskID int64) (*models.TaskState, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectTaskState")
+	defer span.End()
+
+	taskState := new(models.TaskState)
+
+	err := repo.DB.
+		QueryRowContext(ctx, "SELECT task_id, state FROM productUploadsTask WHERE task_id = ?1", taskID).
+		Scan(&taskState.TaskID, &taskState.State)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task"))
+	}
+
+	return taskState, nil
+}
+
+func (repo *sqliteRepository) CreateTask(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "CreateTask")
+	defer span.End()
+
+	stateDefault := "CREATED"
+
+	res, err := repo.DB.ExecContext(ctx, "INSERT INTO productUploadsTask (state) VALUES (?1)", stateDefault)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task"))
+	}
+
+	taskID, err := res.LastInsertId()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task"))
+	}
+
+	return taskID, nil
+}
+
+func (repo *sqliteRepository) UpdateTaskState(ctx context.Context, taskID int64, state string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "UpdateTaskState")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
+		"UPDATE productUploadsTask SET state = ?1 WHERE task_id = ?2",
+		state,
+		taskID,
+	)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) SelectTaskStatsByTaskID(ctx context.Context, taskID int64) (*models.TaskStats, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectTaskStatsByTaskID")
+	defer span.End()
+
+	taskStats := new(models.TaskStats)
+
+	err := repo.DB.
+		QueryRowContext(ctx, "SELECT task_id, products_created, products_updated, products_deleted, "+
+			"rows_with_errors FROM productTaskStats WHERE task_id = ?1", taskID).
+		Scan(&taskStats.TaskID, &taskStats.ProductsCreated, &taskStats.ProductsUpdated,
+			&taskStats.ProductsDeleted, &taskStats.RowsWithErrors)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task stats"))
+	}
+
+	return taskStats, nil
+}
+
+func (repo *sqliteRepository) CreateTaskStats(ctx context.Context, taskStats *models.TaskStats) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "CreateTaskStats")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx,
+		"INSERT INTO productTaskStats "+
+			"(task_id, products_created, products_updated, products_deleted, rows_with_errors) "+
+			"VALUES (?1, ?2, ?3, ?4, ?5)",
+		taskStats.TaskID,
+		taskStats.ProductsCreated,
+		taskStats.ProductsUpdated,
+		taskStats.ProductsDeleted,
+		taskStats.RowsWithErrors,
+	)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task stats"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task stats"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) CreateTaskPreview(ctx context.Context, items []*models.PreviewItem) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "CreateTaskPreview")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	// items of one sheet are saved together, so preview has no half of sheet
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		var err error
+		affectedRowsCounter, err = insertPreviewItems(ctx, tx, items)
+		return err
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task preview"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) SelectTaskPreview(ctx context.Context, taskID int64) ([]*models.PreviewItem, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectTaskPreview")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT "+previewColumns+" FROM productTaskPreview WHERE task_id = ?1 ORDER BY file, sheet, row_num",
+		taskID)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task preview"))
+	}
+	defer rows.Close()
+
+	items, err := scanPreviewItems(rows)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task preview"))
+	}
+
+	return items, nil
+}
+
+func (repo *sqliteRepository) CreateTaskErrors(ctx context.Context, taskID int64,
+	rowErrors []models.RowError) (int64, error) {
+
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "CreateTaskErrors")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	// errors of task are saved together, so task has all of them or none
+	err := repo.inTx(ctx, func(tx sqliteTx) error {
+		var err error
+		affectedRowsCounter, err = insertTaskErrors(ctx, tx, taskID, rowErrors)
+		return err
+	})
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "task errors"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) SelectTaskErrors(ctx context.Context, taskID int64) ([]models.RowError, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectTaskErrors")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT "+taskErrorColumns+" FROM productTaskErrors WHERE task_id = ?1 ORDER BY row_num", taskID)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task errors"))
+	}
+	defer rows.Close()
+
+	rowErrors, err := scanTaskErrors(rows)
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "task errors"))
+	}
+
+	return rowErrors, nil
+}
+
+func (repo *sqliteRepository) SelectExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "SelectExchangeRates")
+	defer span.End()
+
+	rows, err := repo.DB.QueryContext(ctx,
+		"SELECT currency, rate, updated_at FROM exchangeRates ORDER BY currency")
+	if err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+	defer rows.Close()
+
+	rates := []*models.ExchangeRate{}
+
+	for rows.Next() {
+		rate := new(models.ExchangeRate)
+
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, spanError(span, mapSQLiteError(err, "exchange rate"))
+		}
+
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+
+	return rates, nil
+}
+
+func (repo *sqliteRepository) UpdateExchangeRate(ctx context.Context, rate *models.ExchangeRate) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "UpdateExchangeRate")
+	defer span.End()
+
+	// rate of new currency is created
+	res, err := repo.DB.ExecContext(ctx,
+		"INSERT INTO exchangeRates (currency, rate, updated_at) VALUES (?1, ?2, ?3) "+
+			"ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at",
+		rate.Currency,
+		rate.Rate,
+		sqliteNow(),
+	)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *sqliteRepository) DeleteExchangeRate(ctx context.Context, currency string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startSQLiteSpan(ctx, "DeleteExchangeRate")
+	defer span.End()
+
+	res, err := repo.DB.ExecContext(ctx, "DELETE FROM exchangeRates WHERE currency = ?1", currency)
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+
+	affectedRowsCounter, err := res.RowsAffected()
+	if err != nil {
+		return 0, spanError(span, mapSQLiteError(err, "exchange rate"))
+	}
+
+	return affectedRowsCounter, nil
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// SQLiteConfig - settings of sqlite storage, it is defined without cgo too,
+// so that service is configured the same way in any build
+type SQLiteConfig struct {
+	// Path - database file, it is created and migrated on start
+	Path string
+
+	// timeouts of every query, zero means without timeout
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqliteMigrations - migrations of sqlite schema, schema is kept equivalent
+// to build/postgres/init.sql. Number of applied migrations is stored in
+// user_version of database, so new migrations are only appended and applied
+// ones are never changed
+var sqliteMigrations = []string{
+	// decimals are kept as text to not lose precision of numeric, images are
+	// kept in text format of postgres arrays (json array since migration 3)
+	// and timestamps in UTC, so that they are compared as strings
+	`CREATE TABLE productsInfo (
+		-- rowid of productsSearch documents, it is not changed by VACUUM
+		id integer PRIMARY KEY,
+		seller_id bigint NOT NULL,
+		offer_id bigint NOT NULL,
+		name varchar(255) NOT NULL,
+		price text NOT NULL,
+		currency char(3) NOT NULL DEFAULT 'RUB',
+		quantity bigint NOT NULL,
+		available boolean NOT NULL,
+		sku varchar(64) NOT NULL DEFAULT '',
+		description text NOT NULL DEFAULT '',
+		category text NOT NULL DEFAULT '',
+		brand varchar(255) NOT NULL DEFAULT '',
+		images text NOT NULL DEFAULT '{}',
+		weight text,
+		length text,
+		width text,
+		height text,
+		extras text NOT NULL DEFAULT '{}',
+		version bigint NOT NULL DEFAULT 1,
+		deleted_at timestamp,
+		UNIQUE (seller_id, offer_id)
+	);
+	CREATE INDEX productsInfo_deleted_at_idx ON productsInfo (deleted_at) WHERE deleted_at IS NOT NULL;
+	CREATE INDEX productsInfo_sku_idx ON productsInfo (seller_id, sku) WHERE sku <> '';
+	CREATE INDEX productsInfo_category_idx ON productsInfo (category);
+	CREATE INDEX productsInfo_brand_idx ON productsInfo (brand) WHERE brand <> '';
+
+	-- full-text index of products, it is rebuilt on every write of product
+	CREATE VIRTUAL TABLE productsSearch USING fts4(
+		content="productsInfo", name, sku, brand, category, description,
+		tokenize=unicode61 "remove_diacritics=0"
+	);
+	CREATE TRIGGER productsInfo_search_bu BEFORE UPDATE ON productsInfo BEGIN
+		DELETE FROM productsSearch WHERE docid = old.id;
+	END;
+	CREATE TRIGGER productsInfo_search_bd BEFORE DELETE ON productsInfo BEGIN
+		DELETE FROM productsSearch WHERE docid = old.id;
+	END;
+	CREATE TRIGGER productsInfo_search_au AFTER UPDATE ON productsInfo BEGIN
+		INSERT INTO productsSearch (docid, name, sku, brand, category, description)
+		VALUES (new.id, new.name, new.sku, new.brand, new.category, new.description);
+	END;
+	CREATE TRIGGER productsInfo_search_ai AFTER INSERT ON productsInfo BEGIN
+		INSERT INTO productsSearch (docid, name, sku, brand, category, description)
+		VALUES (new.id, new.name, new.sku, new.brand, new.category, new.description);
+	END;
+
+	CREATE TABLE productUploadsTask (
+		task_id integer PRIMARY KEY AUTOINCREMENT,
+		state varchar(50) NOT NULL
+	);
+
+	CREATE TABLE productTaskStats (
+		task_id bigint NOT NULL PRIMARY KEY,
+		products_created bigint,
+		products_updated bigint,
+		products_deleted bigint,
+		rows_with_errors bigint
+	);
+
+	CREATE TABLE productHistory (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		seller_id bigint NOT NULL,
+		offer_id bigint NOT NULL,
+		action varchar(10) NOT NULL,
+		old_value text,
+		new_value text,
+		task_id bigint,
+		file text NOT NULL DEFAULT '',
+		sheet text NOT NULL DEFAULT '',
+		row_num bigint,
+		request_id text NOT NULL DEFAULT '',
+		changed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX productHistory_product_idx ON productHistory (seller_id, offer_id, id);
+	CREATE INDEX productHistory_task_idx ON productHistory (task_id, id) WHERE task_id IS NOT NULL;
+
+	CREATE TABLE productTaskPreview (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		task_id bigint NOT NULL,
+		seller_id bigint NOT NULL,
+		offer_id bigint NOT NULL,
+		action varchar(10) NOT NULL,
+		old_value text,
+		new_value text,
+		changes text,
+		error text NOT NULL DEFAULT '',
+		file text NOT NULL,
+		sheet text NOT NULL,
+		row_num bigint NOT NULL
+	);
+	CREATE INDEX productTaskPreview_task_idx ON productTaskPreview (task_id);
+
+	CREATE TABLE exchangeRates (
+		currency char(3) PRIMARY KEY,
+		rate text NOT NULL CHECK (CAST(rate AS real) > 0),
+		updated_at timestamp NOT NULL
+	);`,
+	`CREATE TABLE productTaskErrors (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		task_id bigint NOT NULL,
+		row_num bigint NOT NULL,
+		offer_id bigint NOT NULL DEFAULT 0,
+		error text NOT NULL,
+		details text
+	);
+	CREATE INDEX productTaskErrors_task_idx ON productTaskErrors (task_id, row_num);`,
+	// images are moved from text of postgres arrays to json arrays, as extras
+	// are kept. Default of column can not be altered, so table is rebuilt with
+	// the same ids, which are docids of productsSearch, and its indexes and
+	// triggers are created again
+	`CREATE TABLE productsInfo_new (
+		id integer PRIMARY KEY,
+		seller_id bigint NOT NULL,
+		offer_id bigint NOT NULL,
+		name varchar(255) NOT NULL,
+		price text NOT NULL,
+		currency char(3) NOT NULL DEFAULT 'RUB',
+		quantity bigint NOT NULL,
+		available boolean NOT NULL,
+		sku varchar(64) NOT NULL DEFAULT '',
+		description text NOT NULL DEFAULT '',
+		category text NOT NULL DEFAULT '',
+		brand varchar(255) NOT NULL DEFAULT '',
+		images text NOT NULL DEFAULT '[]',
+		weight text,
+		length text,
+		width text,
+		height text,
+		extras text NOT NULL DEFAULT '{}',
+		version bigint NOT NULL DEFAULT 1,
+		deleted_at timestamp,
+		UNIQUE (seller_id, offer_id)
+	);
+	INSERT INTO productsInfo_new (id, seller_id, offer_id, name, price, currency, quantity, available, sku,
+		description, category, brand, images, weight, length, width, height, extras, version, deleted_at)
+	SELECT id, seller_id, offer_id, name, price, currency, quantity, available, sku,
+		description, category, brand, pq_array_to_json(images), weight, length, width, height, extras, version,
+		deleted_at
+	FROM productsInfo;
+	DROP TABLE productsInfo;
+	ALTER TABLE productsInfo_new RENAME TO productsInfo;
+
+	CREATE INDEX productsInfo_deleted_at_idx ON productsInfo (deleted_at) WHERE deleted_at IS NOT NULL;
+	CREATE INDEX productsInfo_sku_idx ON productsInfo (seller_id, sku) WHERE sku <> '';
+	CREATE INDEX productsInfo_category_idx ON productsInfo (category);
+	CREATE INDEX productsInfo_brand_idx ON productsInfo (brand) WHERE brand <> '';
+
+	CREATE TRIGGER productsInfo_search_bu BEFORE UPDATE ON productsInfo BEGIN
+		DELETE FROM productsSearch WHERE docid = old.id;
+	END;
+	CREATE TRIGGER productsInfo_search_bd BEFORE DELETE ON productsInfo BEGIN
+		DELETE FROM productsSearch WHERE docid = old.id;
+	END;
+	CREATE TRIGGER productsInfo_search_au AFTER UPDATE ON productsInfo BEGIN
+		INSERT INTO productsSearch (docid, name, sku, brand, category, description)
+		VALUES (new.id, new.name, new.sku, new.brand, new.category, new.description);
+	END;
+	CREATE TRIGGER productsInfo_search_ai AFTER INSERT ON productsInfo BEGIN
+		INSERT INTO productsSearch (docid, name, sku, brand, category, description)
+		VALUES (new.id, new.name, new.sku, new.brand, new.category, new.description);
+	END;`,
+}
+
+// migrateSQLite - apply migrations which are not applied to db yet, every
+// migration is applied in its own transaction
+func migrateSQLite(ctx context.Context, db *sql.DB, migrations []string) error {
+	version := 0
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than %d known migrations", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+				return fmt.Errorf("migration %d: %w", version+1, err)
+			}
+
+			// pragma does not accept parameters
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
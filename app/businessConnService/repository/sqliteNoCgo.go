@@ -0,0 +1,17 @@
+//go:build !cgo
+// +build !cgo
+
+package repository
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+)
+
+// NewSQLiteRepository - sqlite driver is written in C, so without cgo
+// repository is not created
+func NewSQLiteRepository(config SQLiteConfig, logger *logrus.Logger) businessConnService.IRepository {
+	logger.Error("DB error: sqlite storage requires build with cgo")
+	return nil
+}
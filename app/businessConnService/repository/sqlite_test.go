@@ -0,0 +1,153 @@
+//go:build cgo
+// +build cgo
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+func TestSQLiteRepositoryConformance(t *testing.T) {
+	testRepositoryConformance(t, func(t *testing.T) businessConnService.IRepository {
+		db, err := openSQLite(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("Can`t open DB: %s", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return &sqliteRepository{DB: db}
+	})
+}
+
+func TestMapSQLiteError(t *testing.T) {
+	err := mapSQLiteError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey},
+		"task stats")
+	assert.True(t, errors.Is(err, domainErrors.ErrConflict))
+	assert.Equal(t, "task stats already exists", domainErrors.Message(err))
+
+	err = mapSQLiteError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintCheck},
+		"exchange rate")
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+
+	dbErr := errors.New("db_error")
+	assert.Equal(t, dbErr, mapSQLiteError(dbErr, "product"))
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := openSQLite(path)
+	if err != nil {
+		t.Fatalf("Can`t open DB: %s", err)
+	}
+	defer db.Close()
+
+	version := 0
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	assert.Equal(t, len(sqliteMigrations), version)
+
+	// test applied migrations are skipped and new ones are applied
+
+	migrations := append(sqliteMigrations, "CREATE TABLE sellers (seller_id bigint PRIMARY KEY)")
+
+	assert.NoError(t, migrateSQLite(context.Background(), db, migrations))
+	assert.NoError(t, migrateSQLite(context.Background(), db, migrations))
+
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	assert.Equal(t, len(migrations), version)
+
+	// test failed migration is rolled back
+
+	migrations = append(migrations, "CREATE TABLE orders (order_id bigint PRIMARY KEY); SELECT * FROM nothing")
+
+	assert.Error(t, migrateSQLite(context.Background(), db, migrations))
+
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	assert.Equal(t, len(migrations)-1, version)
+
+	_, err = db.Exec("SELECT * FROM orders")
+	assert.Error(t, err)
+
+	// test schema newer than known migrations is not opened
+
+	assert.Error(t, migrateSQLite(context.Background(), db, sqliteMigrations))
+}
+
+func TestMigrateSQLiteImages(t *testing.T) {
+	db, err := sql.Open(sqliteDriverName, "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Can`t open DB: %s", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+
+	// images of products written before migration are text of postgres array
+
+	if err := migrateSQLite(ctx, db, sqliteMigrations[:2]); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO productsInfo (seller_id, offer_id, name, price, quantity, available, images)
+		VALUES (1, 1, 'телефон', '10', 1, true, '{a.png,"b c.png","d\"e.png"}'),
+		(1, 2, 'телевизор', '20', 1, true, '{}')`)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// test images are moved to json and search index is kept
+
+	assert.NoError(t, migrateSQLite(ctx, db, sqliteMigrations))
+
+	images := ""
+	if assert.NoError(t, db.QueryRow("SELECT images FROM productsInfo WHERE offer_id = 1").Scan(&images)) {
+		assert.Equal(t, `["a.png","b c.png","d\"e.png"]`, images)
+	}
+
+	repo := &sqliteRepository{DB: db}
+
+	product, err := repo.SelectProduct(ctx, 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"a.png", "b c.png", `d"e.png`}, product.Images)
+	}
+
+	product, err = repo.SelectProduct(ctx, 1, 2)
+	if assert.NoError(t, err) {
+		assert.Nil(t, product.Images)
+	}
+
+	results, err := repo.SearchProducts(ctx, &models.SearchRequest{Query: "телевизор", Limit: 10})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Equal(t, int64(2), results[0].Product.OfferID)
+	}
+
+	// test new products get json images
+
+	_, err = repo.UpdateProduct(ctx, &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "телевизор",
+		Price: decimal.RequireFromString("20"), Currency: "RUB", Quantity: 1, Available: true,
+		Images: []string{"f.png"}})
+	assert.NoError(t, err)
+
+	if assert.NoError(t, db.QueryRow("SELECT images FROM productsInfo WHERE offer_id = 2").Scan(&images)) {
+		assert.Equal(t, `["f.png"]`, images)
+	}
+}
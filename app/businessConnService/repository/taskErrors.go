@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+func (repo *repository) CreateTaskErrors(ctx context.Context, taskID int64, rowErrors []models.RowError) (int64, error) {
+	ctx, cancel := withTimeout(ctx, repo.writeTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "CreateTaskErrors")
+	defer span.End()
+
+	affectedRowsCounter := int64(0)
+
+	// errors of task are saved together, so task has all of them or none
+	err := inTx(ctx, repo.DB, func(tx *sql.Tx) error {
+		var err error
+		affectedRowsCounter, err = insertTaskErrors(ctx, tx, taskID, rowErrors)
+		return err
+	})
+	if err != nil {
+		return 0, spanError(span, mapError(err, "task errors"))
+	}
+
+	return affectedRowsCounter, nil
+}
+
+func (repo *repository) SelectTaskErrors(ctx context.Context, taskID int64) ([]models.RowError, error) {
+	ctx, cancel := withTimeout(ctx, repo.readTimeout)
+	defer cancel()
+
+	ctx, span := startQuerySpan(ctx, "SelectTaskErrors")
+	defer span.End()
+
+	var rowErrors []models.RowError
+
+	err := repo.onReplica(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx,
+			"SELECT "+taskErrorColumns+" FROM productTaskErrors WHERE task_id = $1 ORDER BY row_num", taskID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		rowErrors, err = scanTaskErrors(rows)
+		return err
+	})
+	if err != nil {
+		return nil, spanError(span, mapError(err, "task errors"))
+	}
+
+	return rowErrors, nil
+}
+
+// insertTaskErrors - insert errors of rows of task to productTaskErrors in
+// tx, number of inserted rows is returned
+func insertTaskErrors(ctx context.Context, tx execer, taskID int64, rowErrors []models.RowError) (int64, error) {
+	affectedRowsCounter := int64(0)
+
+	for _, rowError := range rowErrors {
+		var detailsJSON interface{}
+		if len(rowError.Details) > 0 {
+			var err error
+			if detailsJSON, err = json.Marshal(rowError.Details); err != nil {
+				return 0, err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx,
+			"INSERT INTO productTaskErrors (task_id, row_num, offer_id, error, details) VALUES ($1, $2, $3, $4, $5)",
+			taskID,
+			rowError.Row,
+			rowError.OfferID,
+			rowError.Error,
+			detailsJSON,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		affectedRowsCounter += rowsAffected
+	}
+
+	return affectedRowsCounter, nil
+}
+
+// taskErrorColumns - columns of productTaskErrors in order of scanTaskErrors
+const taskErrorColumns = "row_num, offer_id, error, details"
+
+// scanTaskErrors - scan all rows with taskErrorColumns to errors of rows
+func scanTaskErrors(rows *sql.Rows) ([]models.RowError, error) {
+	rowErrors := []models.RowError{}
+
+	for rows.Next() {
+		var rowError models.RowError
+		var detailsJSON []byte
+
+		if err := rows.Scan(&rowError.Row, &rowError.OfferID, &rowError.Error, &detailsJSON); err != nil {
+			return nil, err
+		}
+
+		if detailsJSON != nil {
+			if err := json.Unmarshal(detailsJSON, &rowError.Details); err != nil {
+				return nil, err
+			}
+		}
+
+		rowErrors = append(rowErrors, rowError)
+	}
+
+	return rowErrors, rows.Err()
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
+)
+
+// startQuerySpan - start span of one query to postgres
+func startQuerySpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return startDBSpan(ctx, semconv.DBSystemPostgreSQL, operation)
+}
+
+// startDBSpan - start span of one query to DB of system
+func startDBSpan(ctx context.Context, system attribute.KeyValue, operation string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "repository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system,
+			semconv.DBOperationKey.String(operation),
+		),
+	)
+}
+
+// spanError - mark span as failed and return err back, not found error is
+// expected result of query, so span is not marked
+func spanError(span trace.Span, err error) error {
+	if !errors.Is(err, domainErrors.ErrNotFound) {
+		tracing.RecordError(span, err)
+	}
+
+	return err
+}
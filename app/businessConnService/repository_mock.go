@@ -0,0 +1,366 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository.go
+
+// Package businessConnService is a generated GoMock package.
+package businessConnService
+
+import (
+	context "context"
+	models "github.com/Toringol/avito-mx-backend-test-task/app/models"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+	time "time"
+)
+
+// MockIRepository is a mock of IRepository interface
+type MockIRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIRepositoryMockRecorder
+}
+
+// MockIRepositoryMockRecorder is the mock recorder for MockIRepository
+type MockIRepositoryMockRecorder struct {
+	mock *MockIRepository
+}
+
+// NewMockIRepository creates a new mock instance
+func NewMockIRepository(ctrl *gomock.Controller) *MockIRepository {
+	mock := &MockIRepository{ctrl: ctrl}
+	mock.recorder = &MockIRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIRepository) EXPECT() *MockIRepositoryMockRecorder {
+	return m.recorder
+}
+
+// SelectProduct mocks base method
+func (m *MockIRepository) SelectProduct(arg0 context.Context, arg1, arg2 int64) (*models.ProductInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectProduct", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.ProductInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectProduct indicates an expected call of SelectProduct
+func (mr *MockIRepositoryMockRecorder) SelectProduct(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProduct", reflect.TypeOf((*MockIRepository)(nil).SelectProduct), arg0, arg1, arg2)
+}
+
+// SelectProductsBySpecificProductInfo mocks base method
+func (m *MockIRepository) SelectProductsBySpecificProductInfo(arg0 context.Context, arg1 *models.UserListRequest) ([]*models.ProductInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectProductsBySpecificProductInfo", arg0, arg1)
+	ret0, _ := ret[0].([]*models.ProductInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectProductsBySpecificProductInfo indicates an expected call of SelectProductsBySpecificProductInfo
+func (mr *MockIRepositoryMockRecorder) SelectProductsBySpecificProductInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProductsBySpecificProductInfo", reflect.TypeOf((*MockIRepository)(nil).SelectProductsBySpecificProductInfo), arg0, arg1)
+}
+
+// CreateProduct mocks base method
+func (m *MockIRepository) CreateProduct(arg0 context.Context, arg1 *models.ProductInfo) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProduct", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateProduct indicates an expected call of CreateProduct
+func (mr *MockIRepositoryMockRecorder) CreateProduct(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockIRepository)(nil).CreateProduct), arg0, arg1)
+}
+
+// UpdateProduct mocks base method
+func (m *MockIRepository) UpdateProduct(arg0 context.Context, arg1 *models.ProductInfo) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProduct", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProduct indicates an expected call of UpdateProduct
+func (mr *MockIRepositoryMockRecorder) UpdateProduct(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockIRepository)(nil).UpdateProduct), arg0, arg1)
+}
+
+// DeleteProduct mocks base method
+func (m *MockIRepository) DeleteProduct(arg0 context.Context, arg1, arg2, arg3 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct
+func (mr *MockIRepositoryMockRecorder) DeleteProduct(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockIRepository)(nil).DeleteProduct), arg0, arg1, arg2, arg3)
+}
+
+// SearchProducts mocks base method
+func (m *MockIRepository) SearchProducts(arg0 context.Context, arg1 *models.SearchRequest) ([]*models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchProducts", arg0, arg1)
+	ret0, _ := ret[0].([]*models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchProducts indicates an expected call of SearchProducts
+func (mr *MockIRepositoryMockRecorder) SearchProducts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchProducts", reflect.TypeOf((*MockIRepository)(nil).SearchProducts), arg0, arg1)
+}
+
+// SelectProductHistory mocks base method
+func (m *MockIRepository) SelectProductHistory(arg0 context.Context, arg1, arg2 int64) ([]*models.ProductChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectProductHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*models.ProductChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectProductHistory indicates an expected call of SelectProductHistory
+func (mr *MockIRepositoryMockRecorder) SelectProductHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProductHistory", reflect.TypeOf((*MockIRepository)(nil).SelectProductHistory), arg0, arg1, arg2)
+}
+
+// SelectTaskChanges mocks base method
+func (m *MockIRepository) SelectTaskChanges(arg0 context.Context, arg1 int64) ([]*models.ProductChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskChanges", arg0, arg1)
+	ret0, _ := ret[0].([]*models.ProductChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskChanges indicates an expected call of SelectTaskChanges
+func (mr *MockIRepositoryMockRecorder) SelectTaskChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskChanges", reflect.TypeOf((*MockIRepository)(nil).SelectTaskChanges), arg0, arg1)
+}
+
+// RestoreProduct mocks base method
+func (m *MockIRepository) RestoreProduct(arg0 context.Context, arg1, arg2, arg3 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProduct", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct
+func (mr *MockIRepositoryMockRecorder) RestoreProduct(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockIRepository)(nil).RestoreProduct), arg0, arg1, arg2, arg3)
+}
+
+// PurgeDeletedProducts mocks base method
+func (m *MockIRepository) PurgeDeletedProducts(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedProducts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedProducts indicates an expected call of PurgeDeletedProducts
+func (mr *MockIRepositoryMockRecorder) PurgeDeletedProducts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedProducts", reflect.TypeOf((*MockIRepository)(nil).PurgeDeletedProducts), arg0, arg1)
+}
+
+// SelectTaskState mocks base method
+func (m *MockIRepository) SelectTaskState(arg0 context.Context, arg1 int64) (*models.TaskState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskState", arg0, arg1)
+	ret0, _ := ret[0].(*models.TaskState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskState indicates an expected call of SelectTaskState
+func (mr *MockIRepositoryMockRecorder) SelectTaskState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskState", reflect.TypeOf((*MockIRepository)(nil).SelectTaskState), arg0, arg1)
+}
+
+// CreateTask mocks base method
+func (m *MockIRepository) CreateTask(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTask", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTask indicates an expected call of CreateTask
+func (mr *MockIRepositoryMockRecorder) CreateTask(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockIRepository)(nil).CreateTask), arg0)
+}
+
+// UpdateTaskState mocks base method
+func (m *MockIRepository) UpdateTaskState(arg0 context.Context, arg1 int64, arg2 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTaskState", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTaskState indicates an expected call of UpdateTaskState
+func (mr *MockIRepositoryMockRecorder) UpdateTaskState(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTaskState", reflect.TypeOf((*MockIRepository)(nil).UpdateTaskState), arg0, arg1, arg2)
+}
+
+// SelectTaskStatsByTaskID mocks base method
+func (m *MockIRepository) SelectTaskStatsByTaskID(arg0 context.Context, arg1 int64) (*models.TaskStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskStatsByTaskID", arg0, arg1)
+	ret0, _ := ret[0].(*models.TaskStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskStatsByTaskID indicates an expected call of SelectTaskStatsByTaskID
+func (mr *MockIRepositoryMockRecorder) SelectTaskStatsByTaskID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskStatsByTaskID", reflect.TypeOf((*MockIRepository)(nil).SelectTaskStatsByTaskID), arg0, arg1)
+}
+
+// CreateTaskStats mocks base method
+func (m *MockIRepository) CreateTaskStats(arg0 context.Context, arg1 *models.TaskStats) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTaskStats", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTaskStats indicates an expected call of CreateTaskStats
+func (mr *MockIRepositoryMockRecorder) CreateTaskStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskStats", reflect.TypeOf((*MockIRepository)(nil).CreateTaskStats), arg0, arg1)
+}
+
+// CreateTaskPreview mocks base method
+func (m *MockIRepository) CreateTaskPreview(arg0 context.Context, arg1 []*models.PreviewItem) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTaskPreview", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTaskPreview indicates an expected call of CreateTaskPreview
+func (mr *MockIRepositoryMockRecorder) CreateTaskPreview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskPreview", reflect.TypeOf((*MockIRepository)(nil).CreateTaskPreview), arg0, arg1)
+}
+
+// SelectTaskPreview mocks base method
+func (m *MockIRepository) SelectTaskPreview(arg0 context.Context, arg1 int64) ([]*models.PreviewItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskPreview", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PreviewItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskPreview indicates an expected call of SelectTaskPreview
+func (mr *MockIRepositoryMockRecorder) SelectTaskPreview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskPreview", reflect.TypeOf((*MockIRepository)(nil).SelectTaskPreview), arg0, arg1)
+}
+
+// CreateTaskErrors mocks base method
+func (m *MockIRepository) CreateTaskErrors(arg0 context.Context, arg1 int64, arg2 []models.RowError) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTaskErrors", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTaskErrors indicates an expected call of CreateTaskErrors
+func (mr *MockIRepositoryMockRecorder) CreateTaskErrors(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskErrors", reflect.TypeOf((*MockIRepository)(nil).CreateTaskErrors), arg0, arg1, arg2)
+}
+
+// SelectTaskErrors mocks base method
+func (m *MockIRepository) SelectTaskErrors(arg0 context.Context, arg1 int64) ([]models.RowError, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskErrors", arg0, arg1)
+	ret0, _ := ret[0].([]models.RowError)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskErrors indicates an expected call of SelectTaskErrors
+func (mr *MockIRepositoryMockRecorder) SelectTaskErrors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskErrors", reflect.TypeOf((*MockIRepository)(nil).SelectTaskErrors), arg0, arg1)
+}
+
+// SelectExchangeRates mocks base method
+func (m *MockIRepository) SelectExchangeRates(arg0 context.Context) ([]*models.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectExchangeRates", arg0)
+	ret0, _ := ret[0].([]*models.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectExchangeRates indicates an expected call of SelectExchangeRates
+func (mr *MockIRepositoryMockRecorder) SelectExchangeRates(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectExchangeRates", reflect.TypeOf((*MockIRepository)(nil).SelectExchangeRates), arg0)
+}
+
+// UpdateExchangeRate mocks base method
+func (m *MockIRepository) UpdateExchangeRate(arg0 context.Context, arg1 *models.ExchangeRate) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateExchangeRate indicates an expected call of UpdateExchangeRate
+func (mr *MockIRepositoryMockRecorder) UpdateExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExchangeRate", reflect.TypeOf((*MockIRepository)(nil).UpdateExchangeRate), arg0, arg1)
+}
+
+// DeleteExchangeRate mocks base method
+func (m *MockIRepository) DeleteExchangeRate(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExchangeRate indicates an expected call of DeleteExchangeRate
+func (mr *MockIRepositoryMockRecorder) DeleteExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExchangeRate", reflect.TypeOf((*MockIRepository)(nil).DeleteExchangeRate), arg0, arg1)
+}
@@ -1,18 +1,38 @@
 package businessConnService
 
-import "github.com/Toringol/avito-mx-backend-test-task/app/models"
+import (
+	"context"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
 
 type IUsecase interface {
-	SelectProduct(int64, int64) (*models.ProductInfo, error)
-	SelectProductsBySpecificProductInfo(*models.UserListRequest) ([]*models.ProductInfo, error)
-	CreateProduct(*models.ProductInfo) (int64, error)
-	UpdateProduct(*models.ProductInfo) (int64, error)
-	DeleteProduct(int64, int64) (int64, error)
-
-	SelectTaskState(int64) (*models.TaskState, error)
-	CreateTask() (int64, error)
-	UpdateTaskState(int64, string) (int64, error)
-
-	SelectTaskStatsByTaskID(int64) (*models.TaskStats, error)
-	CreateTaskStats(*models.TaskStats) (int64, error)
+	SelectProduct(context.Context, int64, int64) (*models.ProductInfo, error)
+	SelectProductsBySpecificProductInfo(context.Context, *models.UserListRequest) ([]*models.ProductInfo, error)
+	CreateProduct(context.Context, *models.ProductInfo) (int64, error)
+	UpdateProduct(context.Context, *models.ProductInfo) (int64, error)
+	DeleteProduct(context.Context, int64, int64, int64) (int64, error)
+	SearchProducts(context.Context, *models.SearchRequest) ([]*models.SearchResult, error)
+	SelectProductHistory(context.Context, int64, int64) ([]*models.ProductChange, error)
+	RestoreProduct(context.Context, int64, int64, int64) (int64, error)
+	PurgeDeletedProducts(context.Context, time.Time) (int64, error)
+
+	SelectTaskState(context.Context, int64) (*models.TaskState, error)
+	CreateTask(context.Context) (int64, error)
+	UpdateTaskState(context.Context, int64, string) (int64, error)
+	RollbackTask(context.Context, int64) (*models.TaskStats, []*models.ProductChange, error)
+
+	SelectTaskStatsByTaskID(context.Context, int64) (*models.TaskStats, error)
+	CreateTaskStats(context.Context, *models.TaskStats) (int64, error)
+
+	CreateTaskPreview(context.Context, []*models.PreviewItem) (int64, error)
+	SelectTaskPreview(context.Context, int64) ([]*models.PreviewItem, error)
+
+	CreateTaskErrors(context.Context, int64, []models.RowError) (int64, error)
+	SelectTaskErrors(context.Context, int64) ([]models.RowError, error)
+
+	SelectExchangeRates(context.Context) ([]*models.ExchangeRate, error)
+	UpdateExchangeRate(context.Context, *models.ExchangeRate) (int64, error)
+	DeleteExchangeRate(context.Context, string) (int64, error)
 }
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+	"github.com/shopspring/decimal"
+)
+
+func (us usecase) UpdateExchangeRate(ctx context.Context, rate *models.ExchangeRate) (int64, error) {
+	if rate.Currency == models.DefaultCurrency {
+		return 0, domainErrors.InvalidFields("Invalid exchange rate",
+			domainErrors.FieldError{Field: "currency", Message: "rate of " + models.DefaultCurrency + " is always 1"})
+	}
+
+	return us.repo.UpdateExchangeRate(ctx, rate)
+}
+
+// normalizePrices - convert prices of products to currency of request and
+// filter and sort products by converted prices. Without currency products
+// are filtered and sorted by their own prices. Products in currency without
+// exchange rate have no converted price, they are skipped by price filters
+// and are the last ones in sort
+func (us usecase) normalizePrices(ctx context.Context, userListRequest *models.UserListRequest,
+	products []*models.ProductInfo) ([]*models.ProductInfo, error) {
+
+	switch userListRequest.Sort {
+	case "", models.SortByPrice, models.SortByPriceDesc:
+	default:
+		return nil, domainErrors.InvalidFields("Unknown sort",
+			domainErrors.FieldError{Field: "sort", Message: "must be price or -price"})
+	}
+
+	price := func(product *models.ProductInfo) *decimal.Decimal {
+		return &product.Price
+	}
+
+	if userListRequest.Currency != "" {
+		rates, err := us.exchangeRates(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		target, ok := rates[userListRequest.Currency]
+		if !ok {
+			return nil, domainErrors.InvalidFields("Unknown currency",
+				domainErrors.FieldError{Field: "currency", Message: "has no exchange rate"})
+		}
+
+		for _, product := range products {
+			if rate, ok := rates[product.Currency]; ok {
+				converted := tools.RoundPrice(product.Price.Mul(rate).Div(target))
+				product.ConvertedPrice = &converted
+			}
+		}
+
+		price = func(product *models.ProductInfo) *decimal.Decimal {
+			return product.ConvertedPrice
+		}
+	}
+
+	if userListRequest.MinPrice != nil || userListRequest.MaxPrice != nil {
+		filtered := []*models.ProductInfo{}
+
+		for _, product := range products {
+			value := price(product)
+
+			switch {
+			case value == nil:
+			case userListRequest.MinPrice != nil && value.LessThan(*userListRequest.MinPrice):
+			case userListRequest.MaxPrice != nil && value.GreaterThan(*userListRequest.MaxPrice):
+			default:
+				filtered = append(filtered, product)
+			}
+		}
+
+		products = filtered
+	}
+
+	if userListRequest.Sort != "" {
+		desc := userListRequest.Sort == models.SortByPriceDesc
+
+		sort.SliceStable(products, func(i, j int) bool {
+			left, right := price(products[i]), price(products[j])
+			if left == nil || right == nil {
+				return right == nil && left != nil
+			}
+
+			if desc {
+				return left.GreaterThan(*right)
+			}
+			return left.LessThan(*right)
+		})
+	}
+
+	return products, nil
+}
+
+// exchangeRates - rates of all currencies by code including DefaultCurrency
+func (us usecase) exchangeRates(ctx context.Context) (map[string]decimal.Decimal, error) {
+	rates, err := us.repo.SelectExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]decimal.Decimal{
+		models.DefaultCurrency: decimal.New(1, 0),
+	}
+
+	for _, rate := range rates {
+		result[rate.Currency] = rate.Rate
+	}
+
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectProductsNormalizedPrices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+
+	us := NewUsecase(repo)
+
+	newProducts := func() []*models.ProductInfo {
+		return []*models.ProductInfo{
+			{SellerID: 1, OfferID: 1, Price: decimal.RequireFromString("180"), Currency: "RUB"},
+			{SellerID: 1, OfferID: 2, Price: decimal.RequireFromString("1.5"), Currency: "USD"},
+			{SellerID: 1, OfferID: 3, Price: decimal.RequireFromString("10"), Currency: "KZT"},
+			{SellerID: 1, OfferID: 4, Price: decimal.RequireFromString("3"), Currency: "EUR"},
+		}
+	}
+
+	rates := []*models.ExchangeRate{
+		{Currency: "EUR", Rate: decimal.RequireFromString("100")},
+		{Currency: "USD", Rate: decimal.RequireFromString("90")},
+	}
+
+	offerIDs := func(products []*models.ProductInfo) []int64 {
+		ids := []int64{}
+		for _, product := range products {
+			ids = append(ids, product.OfferID)
+		}
+		return ids
+	}
+
+	// test conversion to USD, sort by converted price, KZT has no rate
+
+	request := &models.UserListRequest{SellerID: 1, Currency: "USD", Sort: models.SortByPriceDesc}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), request).Return(newProducts(), nil)
+	repo.EXPECT().SelectExchangeRates(gomock.Any()).Return(rates, nil)
+
+	products, err := us.SelectProductsBySpecificProductInfo(context.Background(), request)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{4, 1, 2, 3}, offerIDs(products))
+		assert.Equal(t, "3.33", products[0].ConvertedPrice.String())
+		assert.Equal(t, "2", products[1].ConvertedPrice.String())
+		assert.Equal(t, "1.5", products[2].ConvertedPrice.String())
+		assert.Nil(t, products[3].ConvertedPrice)
+	}
+
+	// test filter by converted price
+
+	minPrice, maxPrice := decimal.RequireFromString("1.5"), decimal.RequireFromString("2")
+	request = &models.UserListRequest{Currency: "USD", MinPrice: &minPrice, MaxPrice: &maxPrice, Sort: models.SortByPrice}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), request).Return(newProducts(), nil)
+	repo.EXPECT().SelectExchangeRates(gomock.Any()).Return(rates, nil)
+
+	products, err = us.SelectProductsBySpecificProductInfo(context.Background(), request)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{2, 1}, offerIDs(products))
+	}
+
+	// test sort by own price without currency, rates are not needed
+
+	request = &models.UserListRequest{Sort: models.SortByPrice}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), request).Return(newProducts(), nil)
+
+	products, err = us.SelectProductsBySpecificProductInfo(context.Background(), request)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int64{2, 4, 3, 1}, offerIDs(products))
+	}
+
+	// test currency without rate
+
+	request = &models.UserListRequest{Currency: "KZT"}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), request).Return(newProducts(), nil)
+	repo.EXPECT().SelectExchangeRates(gomock.Any()).Return(rates, nil)
+
+	_, err = us.SelectProductsBySpecificProductInfo(context.Background(), request)
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+
+	// test unknown sort
+
+	request = &models.UserListRequest{Sort: "name"}
+
+	repo.EXPECT().SelectProductsBySpecificProductInfo(gomock.Any(), request).Return(newProducts(), nil)
+
+	_, err = us.SelectProductsBySpecificProductInfo(context.Background(), request)
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+}
+
+func TestUpdateExchangeRate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+
+	us := NewUsecase(repo)
+
+	rate := &models.ExchangeRate{Currency: "USD", Rate: decimal.RequireFromString("90")}
+
+	repo.EXPECT().UpdateExchangeRate(gomock.Any(), rate).Return(int64(1), nil)
+
+	_, err := us.UpdateExchangeRate(context.Background(), rate)
+	assert.NoError(t, err)
+
+	// test rate of default currency
+
+	_, err = us.UpdateExchangeRate(context.Background(),
+		&models.ExchangeRate{Currency: models.DefaultCurrency, Rate: decimal.RequireFromString("2")})
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+}
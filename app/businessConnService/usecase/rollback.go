@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// RollbackTask - revert all changes of products made by task, newest first.
+// Change is reverted only if product was not changed after it, such changes
+// are skipped, counted as rows with errors and returned as conflicts.
+// Reverting changes are saved in history with source from ctx. On error
+// stats and conflicts of changes reverted before it are returned with it
+func (us usecase) RollbackTask(ctx context.Context, taskID int64) (*models.TaskStats, []*models.ProductChange, error) {
+	changes, err := us.repo.SelectTaskChanges(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats := new(models.TaskStats)
+	conflicts := []*models.ProductChange{}
+
+	for _, change := range changes {
+		if err := ctx.Err(); err != nil {
+			return stats, conflicts, err
+		}
+
+		rowsAffected, err := us.revertChange(ctx, change)
+		if err != nil {
+			return stats, conflicts, err
+		}
+
+		if rowsAffected == 0 {
+			stats.RowsWithErrors++
+			conflicts = append(conflicts, change)
+			continue
+		}
+
+		switch change.Action {
+		case models.ProductCreated:
+			stats.ProductsDeleted += rowsAffected
+		case models.ProductUpdated:
+			stats.ProductsUpdated += rowsAffected
+		case models.ProductDeleted:
+			stats.ProductsCreated += rowsAffected
+		}
+	}
+
+	return stats, conflicts, nil
+}
+
+// revertChange - return product to state before change, product must have
+// version set by change, otherwise nothing is changed
+func (us usecase) revertChange(ctx context.Context, change *models.ProductChange) (int64, error) {
+	switch change.Action {
+	case models.ProductCreated:
+		return us.repo.DeleteProduct(ctx, change.SellerID, change.OfferID, change.NewValue.Version)
+	case models.ProductUpdated:
+		product := *change.OldValue
+		product.Version = change.NewValue.Version
+
+		return us.repo.UpdateProduct(ctx, &product)
+	case models.ProductDeleted:
+		// delete only marks product and increases its version
+		return us.repo.RestoreProduct(ctx, change.SellerID, change.OfferID, change.OldValue.Version+1)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+)
+
+func TestRollbackTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+
+	us := NewUsecase(repo)
+
+	oldValue := &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "tele", Price: decimal.RequireFromString("10"), Currency: "RUB",
+		Quantity: 1, Available: true, Version: 3}
+	newValue := &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "tele", Price: decimal.RequireFromString("20"), Currency: "RUB",
+		Quantity: 5, Available: true, Version: 4}
+
+	changes := []*models.ProductChange{
+		{ID: 4, SellerID: 1, OfferID: 2, Action: models.ProductUpdated, OldValue: oldValue, NewValue: newValue},
+		{ID: 3, SellerID: 1, OfferID: 3, Action: models.ProductCreated,
+			NewValue: &models.ProductInfo{SellerID: 1, OfferID: 3, Version: 1}},
+		{ID: 2, SellerID: 1, OfferID: 4, Action: models.ProductDeleted,
+			OldValue: &models.ProductInfo{SellerID: 1, OfferID: 4, Version: 7}},
+		{ID: 1, SellerID: 1, OfferID: 5, Action: models.ProductCreated,
+			NewValue: &models.ProductInfo{SellerID: 1, OfferID: 5, Version: 1}},
+	}
+
+	reverted := *oldValue
+	reverted.Version = newValue.Version
+
+	// test expect behavior, last created product was changed by other task
+
+	gomock.InOrder(
+		repo.EXPECT().SelectTaskChanges(gomock.Any(), int64(9)).Return(changes, nil),
+		repo.EXPECT().UpdateProduct(gomock.Any(), &reverted).Return(int64(1), nil),
+		repo.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(3), int64(1)).Return(int64(1), nil),
+		repo.EXPECT().RestoreProduct(gomock.Any(), int64(1), int64(4), int64(8)).Return(int64(1), nil),
+		repo.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(5), int64(1)).Return(int64(0), nil),
+	)
+
+	stats, conflicts, err := us.RollbackTask(context.Background(), 9)
+	if err != nil {
+		t.Errorf("unexpected err: %s", err)
+		return
+	}
+
+	expectStats := &models.TaskStats{
+		ProductsCreated: 1,
+		ProductsUpdated: 1,
+		ProductsDeleted: 1,
+		RowsWithErrors:  1,
+	}
+	if !reflect.DeepEqual(stats, expectStats) {
+		t.Errorf("results not match, want %v, have %v", expectStats, stats)
+		return
+	}
+	if len(conflicts) != 1 || conflicts[0] != changes[3] {
+		t.Errorf("results not match, want %v, have %v", changes[3:], conflicts)
+		return
+	}
+
+	// test db error stops rollback, stats of reverted changes are returned
+
+	gomock.InOrder(
+		repo.EXPECT().SelectTaskChanges(gomock.Any(), int64(9)).Return(changes, nil),
+		repo.EXPECT().UpdateProduct(gomock.Any(), &reverted).Return(int64(1), nil),
+		repo.EXPECT().DeleteProduct(gomock.Any(), int64(1), int64(3), int64(1)).
+			Return(int64(0), errors.New("db error")),
+	)
+
+	stats, conflicts, err = us.RollbackTask(context.Background(), 9)
+	if err == nil {
+		t.Errorf("expected error, got nil")
+		return
+	}
+	if !reflect.DeepEqual(stats, &models.TaskStats{ProductsUpdated: 1}) || len(conflicts) != 0 {
+		t.Errorf("results not match, want %v, have %v", &models.TaskStats{ProductsUpdated: 1}, stats)
+		return
+	}
+
+	// test cancelled context stops rollback
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo.EXPECT().SelectTaskChanges(gomock.Any(), int64(9)).Return(changes, nil)
+
+	_, _, err = us.RollbackTask(ctx, 9)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
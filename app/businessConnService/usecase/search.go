@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
+)
+
+// SearchProducts - full-text search of products, request without limit
+// returns DefaultSearchLimit results
+func (us usecase) SearchProducts(ctx context.Context, searchRequest *models.SearchRequest) ([]*models.SearchResult, error) {
+	fields := []domainErrors.FieldError{}
+
+	if len(tools.SearchWords(searchRequest.Query)) == 0 {
+		fields = append(fields, domainErrors.FieldError{Field: "q", Message: "must contain words"})
+	}
+
+	if searchRequest.Limit < 0 || searchRequest.Limit > models.MaxSearchLimit {
+		fields = append(fields, domainErrors.FieldError{Field: "limit",
+			Message: fmt.Sprintf("must be between 1 and %d", models.MaxSearchLimit)})
+	}
+
+	if searchRequest.Offset < 0 {
+		fields = append(fields, domainErrors.FieldError{Field: "offset", Message: "must not be negative"})
+	}
+
+	if len(fields) > 0 {
+		return nil, domainErrors.InvalidFields("Invalid search request", fields...)
+	}
+
+	if searchRequest.Limit == 0 {
+		request := *searchRequest
+		request.Limit = models.DefaultSearchLimit
+		searchRequest = &request
+	}
+
+	return us.repo.SearchProducts(ctx, searchRequest)
+}
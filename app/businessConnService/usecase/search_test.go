@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchProducts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := businessConnService.NewMockIRepository(ctrl)
+
+	us := NewUsecase(repo)
+
+	results := []*models.SearchResult{
+		{Product: &models.ProductInfo{SellerID: 1, OfferID: 1, Name: "телефон"}, Rank: 0.5,
+			Snippet: "<b>телефон</b>"},
+	}
+
+	// test default limit
+
+	repo.EXPECT().SearchProducts(gomock.Any(),
+		&models.SearchRequest{Query: "телефон", Limit: models.DefaultSearchLimit}).Return(results, nil)
+
+	found, err := us.SearchProducts(context.Background(), &models.SearchRequest{Query: "телефон"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, results, found)
+	}
+
+	// test invalid request
+
+	_, err = us.SearchProducts(context.Background(), &models.SearchRequest{Query: "&!", Limit: 101, Offset: -1})
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "q", Message: "must contain words"},
+		{Field: "limit", Message: "must be between 1 and 100"},
+		{Field: "offset", Message: "must not be negative"},
+	}, domainErrors.Fields(err))
+}
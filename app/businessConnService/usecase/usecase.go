@@ -1,7 +1,12 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
 )
 
@@ -14,42 +19,124 @@ func NewUsecase(repo businessConnService.IRepository) businessConnService.IUseca
 	return usecase{repo: repo}
 }
 
-func (us usecase) SelectProduct(sellerID, offerID int64) (*models.ProductInfo, error) {
-	return us.repo.SelectProduct(sellerID, offerID)
+func (us usecase) SelectProduct(ctx context.Context, sellerID, offerID int64) (*models.ProductInfo, error) {
+	return us.repo.SelectProduct(ctx, sellerID, offerID)
+}
+
+func (us usecase) SelectProductsBySpecificProductInfo(ctx context.Context, userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
+	if err := validateListIDs(userListRequest); err != nil {
+		return nil, err
+	}
+
+	products, err := us.repo.SelectProductsBySpecificProductInfo(ctx, userListRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.normalizePrices(ctx, userListRequest, products)
+}
+
+// validateListIDs - lists of ids of request are not longer than
+// models.MaxListIDs and contain positive ids only
+func validateListIDs(userListRequest *models.UserListRequest) error {
+	fields := []domainErrors.FieldError{}
+
+	for _, list := range []struct {
+		field string
+		ids   []int64
+	}{
+		{"seller_ids", userListRequest.SellerIDs},
+		{"offer_ids", userListRequest.OfferIDs},
+		{"exclude_seller_ids", userListRequest.ExcludeSellerIDs},
+		{"exclude_offer_ids", userListRequest.ExcludeOfferIDs},
+	} {
+		if len(list.ids) > models.MaxListIDs {
+			fields = append(fields, domainErrors.FieldError{
+				Field:   list.field,
+				Message: fmt.Sprintf("must contain at most %d ids", models.MaxListIDs),
+			})
+			continue
+		}
+
+		for _, id := range list.ids {
+			if id <= 0 {
+				fields = append(fields, domainErrors.FieldError{Field: list.field, Message: "must contain positive ids"})
+				break
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return domainErrors.InvalidFields("Invalid lists of ids", fields...)
+	}
+
+	return nil
+}
+
+func (us usecase) CreateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	return us.repo.CreateProduct(ctx, productInfo)
+}
+
+func (us usecase) UpdateProduct(ctx context.Context, productInfo *models.ProductInfo) (int64, error) {
+	return us.repo.UpdateProduct(ctx, productInfo)
+}
+
+func (us usecase) DeleteProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	return us.repo.DeleteProduct(ctx, sellerID, offerID, version)
+}
+
+func (us usecase) SelectProductHistory(ctx context.Context, sellerID, offerID int64) ([]*models.ProductChange, error) {
+	return us.repo.SelectProductHistory(ctx, sellerID, offerID)
+}
+
+func (us usecase) RestoreProduct(ctx context.Context, sellerID, offerID, version int64) (int64, error) {
+	return us.repo.RestoreProduct(ctx, sellerID, offerID, version)
+}
+
+func (us usecase) PurgeDeletedProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return us.repo.PurgeDeletedProducts(ctx, deletedBefore)
+}
+
+func (us usecase) SelectTaskState(ctx context.Context, taskID int64) (*models.TaskState, error) {
+	return us.repo.SelectTaskState(ctx, taskID)
+}
+
+func (us usecase) CreateTask(ctx context.Context) (int64, error) {
+	return us.repo.CreateTask(ctx)
 }
 
-func (us usecase) SelectProductsBySpecificProductInfo(userListRequest *models.UserListRequest) ([]*models.ProductInfo, error) {
-	return us.repo.SelectProductsBySpecificProductInfo(userListRequest)
+func (us usecase) UpdateTaskState(ctx context.Context, taskID int64, state string) (int64, error) {
+	return us.repo.UpdateTaskState(ctx, taskID, state)
 }
 
-func (us usecase) CreateProduct(productInfo *models.ProductInfo) (int64, error) {
-	return us.repo.CreateProduct(productInfo)
+func (us usecase) SelectTaskStatsByTaskID(ctx context.Context, taskID int64) (*models.TaskStats, error) {
+	return us.repo.SelectTaskStatsByTaskID(ctx, taskID)
 }
 
-func (us usecase) UpdateProduct(productInfo *models.ProductInfo) (int64, error) {
-	return us.repo.UpdateProduct(productInfo)
+func (us usecase) CreateTaskStats(ctx context.Context, taskStats *models.TaskStats) (int64, error) {
+	return us.repo.CreateTaskStats(ctx, taskStats)
 }
 
-func (us usecase) DeleteProduct(sellerID, offerID int64) (int64, error) {
-	return us.repo.DeleteProduct(sellerID, offerID)
+func (us usecase) CreateTaskPreview(ctx context.Context, items []*models.PreviewItem) (int64, error) {
+	return us.repo.CreateTaskPreview(ctx, items)
 }
 
-func (us usecase) SelectTaskState(taskID int64) (*models.TaskState, error) {
-	return us.repo.SelectTaskState(taskID)
+func (us usecase) SelectTaskPreview(ctx context.Context, taskID int64) ([]*models.PreviewItem, error) {
+	return us.repo.SelectTaskPreview(ctx, taskID)
 }
 
-func (us usecase) CreateTask() (int64, error) {
-	return us.repo.CreateTask()
+func (us usecase) CreateTaskErrors(ctx context.Context, taskID int64, rowErrors []models.RowError) (int64, error) {
+	return us.repo.CreateTaskErrors(ctx, taskID, rowErrors)
 }
 
-func (us usecase) UpdateTaskState(taskID int64, state string) (int64, error) {
-	return us.repo.UpdateTaskState(taskID, state)
+func (us usecase) SelectTaskErrors(ctx context.Context, taskID int64) ([]models.RowError, error) {
+	return us.repo.SelectTaskErrors(ctx, taskID)
 }
 
-func (us usecase) SelectTaskStatsByTaskID(taskID int64) (*models.TaskStats, error) {
-	return us.repo.SelectTaskStatsByTaskID(taskID)
+func (us usecase) SelectExchangeRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return us.repo.SelectExchangeRates(ctx)
 }
 
-func (us usecase) CreateTaskStats(taskStats *models.TaskStats) (int64, error) {
-	return us.repo.CreateTaskStats(taskStats)
+func (us usecase) DeleteExchangeRate(ctx context.Context, currency string) (int64, error) {
+	return us.repo.DeleteExchangeRate(ctx, currency)
 }
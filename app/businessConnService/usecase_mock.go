@@ -5,9 +5,11 @@
 package businessConnService
 
 import (
+	context "context"
 	models "github.com/Toringol/avito-mx-backend-test-task/app/models"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockIUsecase is a mock of IUsecase interface
@@ -34,151 +36,332 @@ func (m *MockIUsecase) EXPECT() *MockIUsecaseMockRecorder {
 }
 
 // SelectProduct mocks base method
-func (m *MockIUsecase) SelectProduct(arg0, arg1 int64) (*models.ProductInfo, error) {
+func (m *MockIUsecase) SelectProduct(arg0 context.Context, arg1, arg2 int64) (*models.ProductInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectProduct", arg0, arg1)
+	ret := m.ctrl.Call(m, "SelectProduct", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.ProductInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectProduct indicates an expected call of SelectProduct
-func (mr *MockIUsecaseMockRecorder) SelectProduct(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) SelectProduct(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProduct", reflect.TypeOf((*MockIUsecase)(nil).SelectProduct), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProduct", reflect.TypeOf((*MockIUsecase)(nil).SelectProduct), arg0, arg1, arg2)
 }
 
 // SelectProductsBySpecificProductInfo mocks base method
-func (m *MockIUsecase) SelectProductsBySpecificProductInfo(arg0 *models.UserListRequest) ([]*models.ProductInfo, error) {
+func (m *MockIUsecase) SelectProductsBySpecificProductInfo(arg0 context.Context, arg1 *models.UserListRequest) ([]*models.ProductInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectProductsBySpecificProductInfo", arg0)
+	ret := m.ctrl.Call(m, "SelectProductsBySpecificProductInfo", arg0, arg1)
 	ret0, _ := ret[0].([]*models.ProductInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectProductsBySpecificProductInfo indicates an expected call of SelectProductsBySpecificProductInfo
-func (mr *MockIUsecaseMockRecorder) SelectProductsBySpecificProductInfo(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) SelectProductsBySpecificProductInfo(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProductsBySpecificProductInfo", reflect.TypeOf((*MockIUsecase)(nil).SelectProductsBySpecificProductInfo), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProductsBySpecificProductInfo", reflect.TypeOf((*MockIUsecase)(nil).SelectProductsBySpecificProductInfo), arg0, arg1)
 }
 
 // CreateProduct mocks base method
-func (m *MockIUsecase) CreateProduct(arg0 *models.ProductInfo) (int64, error) {
+func (m *MockIUsecase) CreateProduct(arg0 context.Context, arg1 *models.ProductInfo) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateProduct", arg0)
+	ret := m.ctrl.Call(m, "CreateProduct", arg0, arg1)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateProduct indicates an expected call of CreateProduct
-func (mr *MockIUsecaseMockRecorder) CreateProduct(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) CreateProduct(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockIUsecase)(nil).CreateProduct), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProduct", reflect.TypeOf((*MockIUsecase)(nil).CreateProduct), arg0, arg1)
 }
 
 // UpdateProduct mocks base method
-func (m *MockIUsecase) UpdateProduct(arg0 *models.ProductInfo) (int64, error) {
+func (m *MockIUsecase) UpdateProduct(arg0 context.Context, arg1 *models.ProductInfo) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProduct", arg0)
+	ret := m.ctrl.Call(m, "UpdateProduct", arg0, arg1)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateProduct indicates an expected call of UpdateProduct
-func (mr *MockIUsecaseMockRecorder) UpdateProduct(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) UpdateProduct(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockIUsecase)(nil).UpdateProduct), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProduct", reflect.TypeOf((*MockIUsecase)(nil).UpdateProduct), arg0, arg1)
 }
 
 // DeleteProduct mocks base method
-func (m *MockIUsecase) DeleteProduct(arg0, arg1 int64) (int64, error) {
+func (m *MockIUsecase) DeleteProduct(arg0 context.Context, arg1, arg2, arg3 int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteProduct", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteProduct", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteProduct indicates an expected call of DeleteProduct
-func (mr *MockIUsecaseMockRecorder) DeleteProduct(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) DeleteProduct(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockIUsecase)(nil).DeleteProduct), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockIUsecase)(nil).DeleteProduct), arg0, arg1, arg2, arg3)
+}
+
+// SearchProducts mocks base method
+func (m *MockIUsecase) SearchProducts(arg0 context.Context, arg1 *models.SearchRequest) ([]*models.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchProducts", arg0, arg1)
+	ret0, _ := ret[0].([]*models.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchProducts indicates an expected call of SearchProducts
+func (mr *MockIUsecaseMockRecorder) SearchProducts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchProducts", reflect.TypeOf((*MockIUsecase)(nil).SearchProducts), arg0, arg1)
+}
+
+// SelectProductHistory mocks base method
+func (m *MockIUsecase) SelectProductHistory(arg0 context.Context, arg1, arg2 int64) ([]*models.ProductChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectProductHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*models.ProductChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectProductHistory indicates an expected call of SelectProductHistory
+func (mr *MockIUsecaseMockRecorder) SelectProductHistory(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectProductHistory", reflect.TypeOf((*MockIUsecase)(nil).SelectProductHistory), arg0, arg1, arg2)
+}
+
+// RestoreProduct mocks base method
+func (m *MockIUsecase) RestoreProduct(arg0 context.Context, arg1, arg2, arg3 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreProduct", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreProduct indicates an expected call of RestoreProduct
+func (mr *MockIUsecaseMockRecorder) RestoreProduct(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreProduct", reflect.TypeOf((*MockIUsecase)(nil).RestoreProduct), arg0, arg1, arg2, arg3)
+}
+
+// PurgeDeletedProducts mocks base method
+func (m *MockIUsecase) PurgeDeletedProducts(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedProducts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedProducts indicates an expected call of PurgeDeletedProducts
+func (mr *MockIUsecaseMockRecorder) PurgeDeletedProducts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedProducts", reflect.TypeOf((*MockIUsecase)(nil).PurgeDeletedProducts), arg0, arg1)
 }
 
 // SelectTaskState mocks base method
-func (m *MockIUsecase) SelectTaskState(arg0 int64) (*models.TaskState, error) {
+func (m *MockIUsecase) SelectTaskState(arg0 context.Context, arg1 int64) (*models.TaskState, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectTaskState", arg0)
+	ret := m.ctrl.Call(m, "SelectTaskState", arg0, arg1)
 	ret0, _ := ret[0].(*models.TaskState)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectTaskState indicates an expected call of SelectTaskState
-func (mr *MockIUsecaseMockRecorder) SelectTaskState(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) SelectTaskState(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskState", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskState), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskState", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskState), arg0, arg1)
 }
 
 // CreateTask mocks base method
-func (m *MockIUsecase) CreateTask() (int64, error) {
+func (m *MockIUsecase) CreateTask(arg0 context.Context) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTask")
+	ret := m.ctrl.Call(m, "CreateTask", arg0)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTask indicates an expected call of CreateTask
-func (mr *MockIUsecaseMockRecorder) CreateTask() *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) CreateTask(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockIUsecase)(nil).CreateTask))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockIUsecase)(nil).CreateTask), arg0)
 }
 
 // UpdateTaskState mocks base method
-func (m *MockIUsecase) UpdateTaskState(arg0 int64, arg1 string) (int64, error) {
+func (m *MockIUsecase) UpdateTaskState(arg0 context.Context, arg1 int64, arg2 string) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateTaskState", arg0, arg1)
+	ret := m.ctrl.Call(m, "UpdateTaskState", arg0, arg1, arg2)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateTaskState indicates an expected call of UpdateTaskState
-func (mr *MockIUsecaseMockRecorder) UpdateTaskState(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) UpdateTaskState(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTaskState", reflect.TypeOf((*MockIUsecase)(nil).UpdateTaskState), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTaskState", reflect.TypeOf((*MockIUsecase)(nil).UpdateTaskState), arg0, arg1, arg2)
+}
+
+// RollbackTask mocks base method
+func (m *MockIUsecase) RollbackTask(arg0 context.Context, arg1 int64) (*models.TaskStats, []*models.ProductChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackTask", arg0, arg1)
+	ret0, _ := ret[0].(*models.TaskStats)
+	ret1, _ := ret[1].([]*models.ProductChange)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RollbackTask indicates an expected call of RollbackTask
+func (mr *MockIUsecaseMockRecorder) RollbackTask(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackTask", reflect.TypeOf((*MockIUsecase)(nil).RollbackTask), arg0, arg1)
 }
 
 // SelectTaskStatsByTaskID mocks base method
-func (m *MockIUsecase) SelectTaskStatsByTaskID(arg0 int64) (*models.TaskStats, error) {
+func (m *MockIUsecase) SelectTaskStatsByTaskID(arg0 context.Context, arg1 int64) (*models.TaskStats, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SelectTaskStatsByTaskID", arg0)
+	ret := m.ctrl.Call(m, "SelectTaskStatsByTaskID", arg0, arg1)
 	ret0, _ := ret[0].(*models.TaskStats)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SelectTaskStatsByTaskID indicates an expected call of SelectTaskStatsByTaskID
-func (mr *MockIUsecaseMockRecorder) SelectTaskStatsByTaskID(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) SelectTaskStatsByTaskID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskStatsByTaskID", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskStatsByTaskID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskStatsByTaskID", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskStatsByTaskID), arg0, arg1)
 }
 
 // CreateTaskStats mocks base method
-func (m *MockIUsecase) CreateTaskStats(arg0 *models.TaskStats) (int64, error) {
+func (m *MockIUsecase) CreateTaskStats(arg0 context.Context, arg1 *models.TaskStats) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTaskStats", arg0)
+	ret := m.ctrl.Call(m, "CreateTaskStats", arg0, arg1)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTaskStats indicates an expected call of CreateTaskStats
-func (mr *MockIUsecaseMockRecorder) CreateTaskStats(arg0 interface{}) *gomock.Call {
+func (mr *MockIUsecaseMockRecorder) CreateTaskStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskStats", reflect.TypeOf((*MockIUsecase)(nil).CreateTaskStats), arg0, arg1)
+}
+
+// CreateTaskPreview mocks base method
+func (m *MockIUsecase) CreateTaskPreview(arg0 context.Context, arg1 []*models.PreviewItem) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTaskPreview", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTaskPreview indicates an expected call of CreateTaskPreview
+func (mr *MockIUsecaseMockRecorder) CreateTaskPreview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskPreview", reflect.TypeOf((*MockIUsecase)(nil).CreateTaskPreview), arg0, arg1)
+}
+
+// SelectTaskPreview mocks base method
+func (m *MockIUsecase) SelectTaskPreview(arg0 context.Context, arg1 int64) ([]*models.PreviewItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskPreview", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PreviewItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskPreview indicates an expected call of SelectTaskPreview
+func (mr *MockIUsecaseMockRecorder) SelectTaskPreview(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskPreview", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskPreview), arg0, arg1)
+}
+
+// CreateTaskErrors mocks base method
+func (m *MockIUsecase) CreateTaskErrors(arg0 context.Context, arg1 int64, arg2 []models.RowError) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTaskErrors", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTaskErrors indicates an expected call of CreateTaskErrors
+func (mr *MockIUsecaseMockRecorder) CreateTaskErrors(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskErrors", reflect.TypeOf((*MockIUsecase)(nil).CreateTaskErrors), arg0, arg1, arg2)
+}
+
+// SelectTaskErrors mocks base method
+func (m *MockIUsecase) SelectTaskErrors(arg0 context.Context, arg1 int64) ([]models.RowError, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectTaskErrors", arg0, arg1)
+	ret0, _ := ret[0].([]models.RowError)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectTaskErrors indicates an expected call of SelectTaskErrors
+func (mr *MockIUsecaseMockRecorder) SelectTaskErrors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectTaskErrors", reflect.TypeOf((*MockIUsecase)(nil).SelectTaskErrors), arg0, arg1)
+}
+
+// SelectExchangeRates mocks base method
+func (m *MockIUsecase) SelectExchangeRates(arg0 context.Context) ([]*models.ExchangeRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectExchangeRates", arg0)
+	ret0, _ := ret[0].([]*models.ExchangeRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectExchangeRates indicates an expected call of SelectExchangeRates
+func (mr *MockIUsecaseMockRecorder) SelectExchangeRates(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectExchangeRates", reflect.TypeOf((*MockIUsecase)(nil).SelectExchangeRates), arg0)
+}
+
+// UpdateExchangeRate mocks base method
+func (m *MockIUsecase) UpdateExchangeRate(arg0 context.Context, arg1 *models.ExchangeRate) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateExchangeRate indicates an expected call of UpdateExchangeRate
+func (mr *MockIUsecaseMockRecorder) UpdateExchangeRate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExchangeRate", reflect.TypeOf((*MockIUsecase)(nil).UpdateExchangeRate), arg0, arg1)
+}
+
+// DeleteExchangeRate mocks base method
+func (m *MockIUsecase) DeleteExchangeRate(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExchangeRate", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExchangeRate indicates an expected call of DeleteExchangeRate
+func (mr *MockIUsecaseMockRecorder) DeleteExchangeRate(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTaskStats", reflect.TypeOf((*MockIUsecase)(nil).CreateTaskStats), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExchangeRate", reflect.TypeOf((*MockIUsecase)(nil).DeleteExchangeRate), arg0, arg1)
 }
@@ -0,0 +1,122 @@
+package domainErrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors, layers above repository must compare errors only
+// with them (using errors.Is) and never with errors of database/sql
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+	// ErrPreconditionFailed - entity was changed since client read it
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// FieldError - description of invalid field of request
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error - domain error with kind and message which can be shown to client
+type Error struct {
+	Kind    error
+	Message string
+	// Fields describes invalid fields for validation errors
+	Fields []FieldError
+	// Err is original error which caused domain error, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+// Is - error matches its kind
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New - create domain error of kind with formatted message
+func New(kind error, format string, args ...interface{}) error {
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Wrap - create domain error of kind with message caused by err
+func Wrap(kind error, err error, message string) error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// NotFound - create ErrNotFound error
+func NotFound(format string, args ...interface{}) error {
+	return New(ErrNotFound, format, args...)
+}
+
+// Conflict - create ErrConflict error
+func Conflict(format string, args ...interface{}) error {
+	return New(ErrConflict, format, args...)
+}
+
+// Validation - create ErrValidation error
+func Validation(format string, args ...interface{}) error {
+	return New(ErrValidation, format, args...)
+}
+
+// InvalidFields - create ErrValidation error with description of every invalid field
+func InvalidFields(message string, fields ...FieldError) error {
+	return &Error{
+		Kind:    ErrValidation,
+		Message: message,
+		Fields:  fields,
+	}
+}
+
+// Forbidden - create ErrForbidden error
+func Forbidden(format string, args ...interface{}) error {
+	return New(ErrForbidden, format, args...)
+}
+
+// PreconditionFailed - create ErrPreconditionFailed error
+func PreconditionFailed(format string, args ...interface{}) error {
+	return New(ErrPreconditionFailed, format, args...)
+}
+
+// Message - return message of domain error which can be shown to client,
+// for other errors empty string is returned
+func Message(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Message
+	}
+
+	return ""
+}
+
+// Fields - return invalid fields of domain error, if any
+func Fields(err error) []FieldError {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Fields
+	}
+
+	return nil
+}
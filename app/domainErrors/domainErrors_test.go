@@ -0,0 +1,40 @@
+package domainErrors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	err := NotFound("task %d not found", 1)
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrConflict))
+	assert.Equal(t, "task 1 not found", err.Error())
+	assert.Equal(t, "task 1 not found", Message(err))
+
+	// kind is kept after wrapping with fmt.Errorf
+	wrapped := fmt.Errorf("select task: %w", err)
+	assert.True(t, errors.Is(wrapped, ErrNotFound))
+	assert.Equal(t, "task 1 not found", Message(wrapped))
+
+	// cause is available
+	err = Wrap(ErrConflict, sql.ErrTxDone, "product already exists")
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.True(t, errors.Is(err, sql.ErrTxDone))
+	assert.Equal(t, "product already exists", Message(err))
+
+	assert.True(t, errors.Is(Validation("bad"), ErrValidation))
+	assert.True(t, errors.Is(Forbidden("no"), ErrForbidden))
+	assert.Equal(t, "", Message(errors.New("db error")))
+
+	// invalid fields
+	err = InvalidFields("invalid product", FieldError{Field: "price", Message: "must not be negative"})
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.Equal(t, []FieldError{{Field: "price", Message: "must not be negative"}}, Fields(err))
+	assert.Nil(t, Fields(NotFound("no")))
+}
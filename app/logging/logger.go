@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Names of fields attached to log entries, every part of service must use
+// them so that entries of one request can be found by the same keys
+const (
+	RequestIDField      = "request_id"
+	TraceIDField        = "trace_id"
+	TaskIDField         = "task_id"
+	SellerIDField       = "seller_id"
+	OfferIDField        = "offer_id"
+	FileField           = "file"
+	SheetField          = "sheet"
+	RowField            = "row"
+	RollbackTaskIDField = "rollback_task_id"
+)
+
+// NewLogger - create new logrus logger with level (debug, info, warn...)
+// and format (text or json) from config
+func NewLogger(level, format string) (*logrus.Logger, error) {
+	logger := logrus.New()
+
+	if level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		logger.SetLevel(lvl)
+	}
+
+	switch format {
+	case "", "text":
+		logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	case "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return nil, fmt.Errorf("unknown log format: %q", format)
+	}
+
+	return logger, nil
+}
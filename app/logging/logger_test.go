@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	logger, err := NewLogger("debug", "json")
+	if assert.NoError(t, err) {
+		assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+		assert.IsType(t, &logrus.JSONFormatter{}, logger.Formatter)
+	}
+
+	logger, err = NewLogger("", "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, logrus.InfoLevel, logger.GetLevel())
+		assert.IsType(t, &logrus.TextFormatter{}, logger.Formatter)
+	}
+
+	_, err = NewLogger("loud", "text")
+	assert.Error(t, err)
+
+	_, err = NewLogger("info", "xml")
+	assert.Error(t, err)
+}
+
+func TestContext(t *testing.T) {
+	logger := logrus.New()
+
+	// without entry in context new entry is returned
+	entry := FromContext(context.Background(), logger)
+	assert.Empty(t, entry.Data)
+
+	ctx := WithRequestID(context.Background(), "abc")
+	ctx = NewContext(ctx, logger.WithField(RequestIDField, "abc"))
+
+	assert.Equal(t, "abc", RequestIDFromContext(ctx))
+	assert.Equal(t, "abc", FromContext(ctx, logger).Data[RequestIDField])
+
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+	assert.Len(t, NewRequestID(), 32)
+}
@@ -0,0 +1,21 @@
+package models
+
+// RowError - why row of upload was not uploaded, row is number of product
+// in batch
+// swagger:model RowError
+type RowError struct {
+	Row     int64         `json:"row"`
+	OfferID int64         `json:"offer_id,omitempty"`
+	Error   string        `json:"error"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// BatchResult - result of synchronous batch upload, it is returned
+// instead of task id when task is DONE
+// swagger:model BatchResult
+type BatchResult struct {
+	TaskID int64      `json:"task_id"`
+	State  string     `json:"state"`
+	Stats  TaskStats  `json:"stats"`
+	Errors []RowError `json:"errors"`
+}
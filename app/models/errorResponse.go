@@ -0,0 +1,18 @@
+package models
+
+// ErrorResponse is body of response when request failed, code is
+// machine-readable reason of error (e.g. invalid_seller_id, not_found)
+// swagger:model ErrorResponse
+type ErrorResponse struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Details   []ErrorDetail `json:"details,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// ErrorDetail describes one invalid field of request
+// swagger:model ErrorDetail
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
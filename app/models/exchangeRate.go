@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate - price of one unit of Currency in DefaultCurrency, rates are
+// maintained by admin, rate of DefaultCurrency is always one
+// swagger:model ExchangeRate
+type ExchangeRate struct {
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Actions of ProductChange
+const (
+	ProductCreated  = "CREATE"
+	ProductUpdated  = "UPDATE"
+	ProductDeleted  = "DELETE"
+	ProductRestored = "RESTORE"
+)
+
+// ChangeSource - origin of change of product: row of file uploaded by task
+// or request to api
+// swagger:model ChangeSource
+type ChangeSource struct {
+	TaskID    int64  `json:"task_id,omitempty"`
+	File      string `json:"file,omitempty"`
+	Sheet     string `json:"sheet,omitempty"`
+	Row       int64  `json:"row,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProductChange - record of history of product, OldValue is nil for
+// created product and NewValue is nil for deleted one
+// swagger:model ProductChange
+type ProductChange struct {
+	ID        int64        `json:"id"`
+	SellerID  int64        `json:"seller_id"`
+	OfferID   int64        `json:"offer_id"`
+	Action    string       `json:"action"`
+	OldValue  *ProductInfo `json:"old_value"`
+	NewValue  *ProductInfo `json:"new_value"`
+	Source    ChangeSource `json:"source"`
+	ChangedAt time.Time    `json:"changed_at"`
+}
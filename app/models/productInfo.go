@@ -1,12 +1,74 @@
 package models
 
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCurrency - currency of product price when it is not set
+const DefaultCurrency = "RUB"
+
+// CategorySeparator - separator of levels in category path of product
+const CategorySeparator = "/"
+
 // ProductInfo - DB model description of product
+// Price is fixed-point decimal, it is written to json as string to keep
+// precision, Currency is ISO 4217 code
+// SKU, Description, Category (path like "Electronics/Phones"), Brand, Images,
+// Weight (kg) and Dimensions are optional, Extras keeps any other attributes
+// ConvertedPrice is price in currency asked in search, it is not stored
+// Version increases on every update of product, it is used as ETag
+// DeletedAt is set for deleted product, it can be restored until it is purged
 // swagger:model ProductInfo
 type ProductInfo struct {
-	SellerID  int64   `json:"seller_id"`
-	OfferID   int64   `json:"offer_id"`
-	Name      string  `json:"name"`
-	Price     float64 `json:"price"`
-	Quantity  int64   `json:"quantity"`
-	Available bool    `json:"available"`
+	SellerID  int64           `json:"seller_id"`
+	OfferID   int64           `json:"offer_id"`
+	Name      string          `json:"name"`
+	Price     decimal.Decimal `json:"price"`
+	Currency  string          `json:"currency"`
+	Quantity  int64           `json:"quantity"`
+	Available bool            `json:"available"`
+
+	SKU         string                 `json:"sku,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Category    string                 `json:"category,omitempty"`
+	Brand       string                 `json:"brand,omitempty"`
+	Images      []string               `json:"images,omitempty"`
+	Weight      *decimal.Decimal       `json:"weight,omitempty"`
+	Dimensions  *Dimensions            `json:"dimensions,omitempty"`
+	Extras      map[string]interface{} `json:"extras,omitempty"`
+
+	Version   int64      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	ConvertedPrice *decimal.Decimal `json:"converted_price,omitempty"`
+}
+
+// Dimensions - size of product in package in cm
+// swagger:model Dimensions
+type Dimensions struct {
+	Length decimal.Decimal `json:"length"`
+	Width  decimal.Decimal `json:"width"`
+	Height decimal.Decimal `json:"height"`
+}
+
+// ProductPatch - fields of product which are changed by PATCH request,
+// nil fields are kept as is
+// swagger:model ProductPatch
+type ProductPatch struct {
+	Name      *string          `json:"name"`
+	Price     *decimal.Decimal `json:"price"`
+	Currency  *string          `json:"currency"`
+	Quantity  *int64           `json:"quantity"`
+	Available *bool            `json:"available"`
+
+	SKU         *string                `json:"sku"`
+	Description *string                `json:"description"`
+	Category    *string                `json:"category"`
+	Brand       *string                `json:"brand"`
+	Images      *[]string              `json:"images"`
+	Weight      *decimal.Decimal       `json:"weight"`
+	Dimensions  *Dimensions            `json:"dimensions"`
+	Extras      map[string]interface{} `json:"extras"`
 }
@@ -0,0 +1,28 @@
+package models
+
+// DefaultSearchLimit - number of search results returned when limit is not set
+const DefaultSearchLimit = 20
+
+// MaxSearchLimit - max number of search results returned by one request
+const MaxSearchLimit = 100
+
+// SearchRequest is request of full-text search of products, every word of
+// Query is matched by prefix in name, sku, brand, category and description
+// of product. Deleted products are not found
+// swagger:model SearchRequest
+type SearchRequest struct {
+	Query    string `json:"q"`
+	SellerID int64  `json:"seller_id"`
+	Limit    int    `json:"limit"`
+	Offset   int    `json:"offset"`
+}
+
+// SearchResult - product found by full-text search, results are sorted by
+// Rank (relevance of product to query). Snippet is html escaped fragment
+// of name and description with found words highlighted by <b></b>
+// swagger:model SearchResult
+type SearchResult struct {
+	Product *ProductInfo `json:"product"`
+	Rank    float64      `json:"rank"`
+	Snippet string       `json:"snippet"`
+}
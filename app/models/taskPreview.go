@@ -0,0 +1,33 @@
+package models
+
+// Actions of PreviewItem which are not changes of product
+const (
+	PreviewSkipped = "SKIP"
+	PreviewError   = "ERROR"
+)
+
+// FieldChange - change of one field of product
+// swagger:model FieldChange
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// PreviewItem - what upload in dry run would do with product from one row:
+// CREATE, UPDATE with changed fields, DELETE, SKIP (delete of missing
+// product) or ERROR (invalid row)
+// swagger:model PreviewItem
+type PreviewItem struct {
+	TaskID   int64         `json:"task_id"`
+	SellerID int64         `json:"seller_id"`
+	OfferID  int64         `json:"offer_id,omitempty"`
+	Action   string        `json:"action"`
+	OldValue *ProductInfo  `json:"old_value,omitempty"`
+	NewValue *ProductInfo  `json:"new_value,omitempty"`
+	Changes  []FieldChange `json:"changes,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	File     string        `json:"file"`
+	Sheet    string        `json:"sheet"`
+	Row      int64         `json:"row"`
+}
@@ -1,13 +1,38 @@
 package models
 
-import "mime/multipart"
+import (
+	"encoding/json"
+	"mime/multipart"
+)
 
 // Task is model for taskQueue
 // When user loads files in running main goroutine, task adds
 // to taskQueue futher we can process all tasks concurrently
 // swagger:model Task
 type Task struct {
-	TaskID   int64
-	SellerID int64
-	Files    map[string][]*multipart.FileHeader
+	TaskID    int64
+	SellerID  int64
+	RequestID string
+	// TraceCarrier keeps span context of request that created task
+	TraceCarrier map[string]string
+	Files        map[string][]*multipart.FileHeader
+	// SourceURL - http(s) or file url of xlsx file which is fetched by task
+	// and uploaded as one more file, SourceSHA256 is its expected checksum
+	// in hex, if it is set
+	SourceURL    string
+	SourceSHA256 string
+	// Products are rows of batch upload, every one is json ProductInfo, they
+	// are uploaded as rows of one more sheet
+	Products []json.RawMessage
+	// Currency of prices in rows of files without currency column
+	Currency string
+	// DryRun task does not change products, it saves preview of changes
+	DryRun bool
+	// RollbackTaskID is set for task which reverts changes of other task
+	// instead of uploading files
+	RollbackTaskID int64
+	// Result receives result of upload when task is DONE, it is closed
+	// without result if task failed or was cancelled. It is set for
+	// synchronous batch upload and must be buffered
+	Result chan<- BatchResult
 }
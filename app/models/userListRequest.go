@@ -1,10 +1,43 @@
 package models
 
+import "github.com/shopspring/decimal"
+
+// Sorts of products found by UserListRequest
+const (
+	SortByPrice     = "price"
+	SortByPriceDesc = "-price"
+)
+
+// MaxListIDs - max number of ids in every list of ids of UserListRequest
+const MaxListIDs = 1000
+
 // UserListRequest is request for searching specific products
-// by info in request
+// by info in request, deleted products are found only with IncludeDeleted.
+// Category finds products of category and all its subcategories.
+// With Currency prices are converted to it by exchange rates, MinPrice,
+// MaxPrice and Sort are applied to converted prices then.
+// Lists of ids find products with any of ids and exclude lists skip products
+// with any of ids, they are combined with SellerID and OfferID and with each
+// other, empty list does not filter products
 // swagger:model UserListRequest
 type UserListRequest struct {
 	SellerID int64  `json:"seller_id"`
 	OfferID  int64  `json:"offer_id"`
 	Name     string `json:"name"`
+
+	SellerIDs        []int64 `json:"seller_ids,omitempty"`
+	OfferIDs         []int64 `json:"offer_ids,omitempty"`
+	ExcludeSellerIDs []int64 `json:"exclude_seller_ids,omitempty"`
+	ExcludeOfferIDs  []int64 `json:"exclude_offer_ids,omitempty"`
+
+	SKU      string `json:"sku"`
+	Category string `json:"category"`
+	Brand    string `json:"brand"`
+
+	IncludeDeleted bool `json:"include_deleted"`
+
+	Currency string           `json:"currency"`
+	MinPrice *decimal.Decimal `json:"min_price"`
+	MaxPrice *decimal.Decimal `json:"max_price"`
+	Sort     string           `json:"sort"`
 }
@@ -1,24 +1,108 @@
+-- schema of sqlite storage in app/businessConnService/repository/sqliteMigrations.go
+-- is kept equivalent, changes of tables are added there as new migration
 DROP TABLE IF EXISTS productsInfo;
 CREATE TABLE productsInfo (
     seller_id bigint NOT NULL,
     offer_id bigint NOT NULL,
     name varchar(255) NOT NULL,
     price numeric NOT NULL,
+    currency char(3) NOT NULL DEFAULT 'RUB',
     quantity bigint NOT NULL,
-    available boolean NOT NULL
+    available boolean NOT NULL,
+    sku varchar(64) NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    category text NOT NULL DEFAULT '',
+    brand varchar(255) NOT NULL DEFAULT '',
+    images text[] NOT NULL DEFAULT '{}',
+    weight numeric,
+    length numeric,
+    width numeric,
+    height numeric,
+    extras jsonb NOT NULL DEFAULT '{}',
+    version bigint NOT NULL DEFAULT 1,
+    deleted_at timestamptz,
+    -- lexemes of full-text search, they are rebuilt on every write of product
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', name), 'A') ||
+        setweight(to_tsvector('english', name), 'A') ||
+        setweight(to_tsvector('simple', sku || ' ' || brand), 'A') ||
+        setweight(to_tsvector('russian', replace(category, '/', ' ')), 'B') ||
+        setweight(to_tsvector('english', replace(category, '/', ' ')), 'B') ||
+        setweight(to_tsvector('russian', description), 'C') ||
+        setweight(to_tsvector('english', description), 'C')
+    ) STORED,
+    PRIMARY KEY (seller_id, offer_id)
 );
+CREATE INDEX productsInfo_deleted_at_idx ON productsInfo (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX productsInfo_sku_idx ON productsInfo (seller_id, sku) WHERE sku <> '';
+CREATE INDEX productsInfo_category_idx ON productsInfo (category text_pattern_ops);
+CREATE INDEX productsInfo_brand_idx ON productsInfo (brand) WHERE brand <> '';
+CREATE INDEX productsInfo_search_idx ON productsInfo USING gin (search_vector);
 
 DROP TABLE IF EXISTS productUploadsTask;
 CREATE TABLE productUploadsTask (
-    task_id bigserial NOT NULL,
+    task_id bigserial NOT NULL PRIMARY KEY,
     state varchar(50) NOT NULL
 );
 
 DROP TABLE IF EXISTS productTaskStats;
 CREATE TABLE productTaskStats (
-    task_id bigint NOT NULL,
+    task_id bigint NOT NULL PRIMARY KEY,
     products_created bigint,
     products_updated bigint,
     products_deleted bigint,
     rows_with_errors bigint
-);
\ No newline at end of file
+);
+
+DROP TABLE IF EXISTS productHistory;
+CREATE TABLE productHistory (
+    id bigserial NOT NULL PRIMARY KEY,
+    seller_id bigint NOT NULL,
+    offer_id bigint NOT NULL,
+    action varchar(10) NOT NULL,
+    old_value jsonb,
+    new_value jsonb,
+    task_id bigint,
+    file text NOT NULL DEFAULT '',
+    sheet text NOT NULL DEFAULT '',
+    row_num bigint,
+    request_id text NOT NULL DEFAULT '',
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX productHistory_product_idx ON productHistory (seller_id, offer_id, id);
+CREATE INDEX productHistory_task_idx ON productHistory (task_id, id) WHERE task_id IS NOT NULL;
+
+DROP TABLE IF EXISTS productTaskPreview;
+CREATE TABLE productTaskPreview (
+    id bigserial NOT NULL PRIMARY KEY,
+    task_id bigint NOT NULL,
+    seller_id bigint NOT NULL,
+    offer_id bigint NOT NULL,
+    action varchar(10) NOT NULL,
+    old_value jsonb,
+    new_value jsonb,
+    changes jsonb,
+    error text NOT NULL DEFAULT '',
+    file text NOT NULL,
+    sheet text NOT NULL,
+    row_num bigint NOT NULL
+);
+CREATE INDEX productTaskPreview_task_idx ON productTaskPreview (task_id);
+
+DROP TABLE IF EXISTS productTaskErrors;
+CREATE TABLE productTaskErrors (
+    id bigserial NOT NULL PRIMARY KEY,
+    task_id bigint NOT NULL,
+    row_num bigint NOT NULL,
+    offer_id bigint NOT NULL DEFAULT 0,
+    error text NOT NULL,
+    details jsonb
+);
+CREATE INDEX productTaskErrors_task_idx ON productTaskErrors (task_id, row_num);
+
+DROP TABLE IF EXISTS exchangeRates;
+CREATE TABLE exchangeRates (
+    currency char(3) PRIMARY KEY,
+    rate numeric NOT NULL CHECK (rate > 0),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
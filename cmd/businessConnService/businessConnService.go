@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
 
-	businessConnService "github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/delivery/http"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService"
+	businessConnServiceHTTP "github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/delivery/http"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/delivery/purgeJob"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/delivery/taskManager"
+	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/middlewares"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/repository"
 	"github.com/Toringol/avito-mx-backend-test-task/app/businessConnService/usecase"
+	"github.com/Toringol/avito-mx-backend-test-task/app/logging"
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/Toringol/avito-mx-backend-test-task/app/tracing"
 	"github.com/Toringol/avito-mx-backend-test-task/config"
+	"github.com/Toringol/avito-mx-backend-test-task/tools"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 
 	_ "github.com/lib/pq"
 )
@@ -33,24 +42,119 @@ import (
 //
 // swagger:meta
 func main() {
-	if err := config.Init(); err != nil {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	if err != nil {
 		log.Fatalf("%s", err.Error())
 	}
 
-	logger := logrus.New()
+	logger, err := logging.NewLogger(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("%s", err.Error())
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	tools.SetPriceMaxScale(cfg.PriceMaxScale)
+
+	var repo businessConnService.IRepository
+	switch cfg.Storage {
+	case "postgres":
+		repo = repository.NewRepository(repository.PostgresConfig{
+			DSN:                  cfg.DBDSN,
+			Host:                 cfg.DBHost,
+			Port:                 cfg.DBPort,
+			User:                 cfg.DBUser,
+			Password:             cfg.DBPassword,
+			Name:                 cfg.DBName,
+			SSLMode:              cfg.DBSSLMode,
+			SSLCert:              cfg.DBSSLCert,
+			SSLKey:               cfg.DBSSLKey,
+			SSLRootCert:          cfg.DBSSLRootCert,
+			StatementTimeout:     cfg.DBStatementTimeout,
+			MaxOpenConns:         cfg.DBMaxOpenConns,
+			MaxIdleConns:         cfg.DBMaxIdleConns,
+			ConnMaxLifetime:      cfg.DBConnMaxLifetime,
+			ConnMaxIdleTime:      cfg.DBConnMaxIdleTime,
+			Replicas:             cfg.DBReplicas,
+			ReplicaMaxLag:        cfg.DBReplicaMaxLag,
+			ReplicaCheckInterval: cfg.DBReplicaCheckInterval,
+			ReadTimeout:          cfg.DBReadTimeout,
+			WriteTimeout:         cfg.DBWriteTimeout,
+		}, logger)
+	case "sqlite":
+		repo = repository.NewSQLiteRepository(repository.SQLiteConfig{
+			Path:         cfg.SQLitePath,
+			ReadTimeout:  cfg.DBReadTimeout,
+			WriteTimeout: cfg.DBWriteTimeout,
+		}, logger)
+	case "memory":
+		repo = repository.NewInMemoryRepository()
+	}
+	if repo == nil {
+		logger.Fatal("Storage is not available")
+	}
+
+	if cfg.CacheSize > 0 {
+		repo = repository.NewCachedRepository(repo, cfg.CacheSize, cfg.CacheTTL)
+	}
 
-	us := usecase.NewUsecase(repository.NewRepository())
+	us := usecase.NewUsecase(repo)
 	taskQueue := make(chan models.Task, 100)
 	statsQueue := make(chan models.TaskStats, 100)
 	stopCh := make(chan struct{})
 
-	taskManager := taskManager.NewTaskManager(us, taskQueue, statsQueue, stopCh, logger)
+	taskManager := taskManager.NewTaskManager(us, taskQueue, statsQueue, stopCh, taskManager.Config{
+		SourceDir:     cfg.SourceDir,
+		MaxSourceSize: cfg.MaxSourceSize,
+		SourceTimeout: cfg.SourceTimeout,
+		SourceHosts:   cfg.SourceHosts,
+	}, logger)
+	taskManager.SetWorkers(cfg.TaskWorkers)
 
 	go taskManager.TaskManager()
 
-	router := businessConnService.NewHandlers(us, taskQueue, logger)
+	purgeJob := purgeJob.NewPurgeJob(us, cfg.PurgeRetention, cfg.PurgeInterval, stopCh, logger)
+
+	go purgeJob.PurgeJob()
 
-	logger.Info("Starting server on port: ", viper.GetString("portListen"))
+	rateLimiter := middlewares.NewRateLimiter(cfg.RateLimit, cfg.RateBurst)
+	router := businessConnServiceHTTP.NewHandlers(us, taskQueue, businessConnServiceHTTP.Config{
+		MaxUploadSize: cfg.MaxUploadSize,
+		AdminToken:    cfg.AdminToken,
+		RateLimiter:   rateLimiter,
+	}, logger)
+
+	// settings are applied to running uploads and requests, so they are
+	// changed without restart
+	err = config.Watch(os.Args[1:], cfg, logger, func(cfg *config.Config) {
+		if level, err := logrus.ParseLevel(cfg.LogLevel); err == nil {
+			logger.SetLevel(level)
+		}
+		rateLimiter.SetLimit(cfg.RateLimit, cfg.RateBurst)
+		taskManager.SetWorkers(cfg.TaskWorkers)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Config is not watched")
+	}
+
+	logger.Info("Starting server on port: ", cfg.PortListen)
+
+	err = http.ListenAndServe(cfg.PortListen, router)
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.WithError(err).Error("Failed to flush spans")
+	}
 
-	logger.Fatal(http.ListenAndServe(viper.GetString("portListen"), router))
+	logger.Fatal(err)
 }
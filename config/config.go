@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config - settings of service. Every setting has key of config.yml
+// (mapstructure tag), environment variable AVITO_<env tag> and command-line
+// flag, for example DBPassword, AVITO_DB_PASSWORD and --db-password.
+// Settings with reload tag are applied without restart when config file is
+// changed, see Watch
+type Config struct {
+	PortListen string `mapstructure:"portListen" env:"PORT_LISTEN" usage:"address of http server"`
+	// MaxUploadSize - max size of /loadProduct body in bytes, 0 - without limit
+	MaxUploadSize int64 `mapstructure:"maxUploadSize" env:"MAX_UPLOAD_SIZE" usage:"max size of upload in bytes"`
+
+	// SourceDir - directory of files which can be uploaded by file:// source_url
+	SourceDir     string        `mapstructure:"sourceDir" env:"SOURCE_DIR" usage:"directory of file sources"`
+	MaxSourceSize int64         `mapstructure:"maxSourceSize" env:"MAX_SOURCE_SIZE" usage:"max size of source in bytes"`
+	SourceTimeout time.Duration `mapstructure:"sourceTimeout" env:"SOURCE_TIMEOUT" usage:"max time of download of source"`
+	// SourceHosts - hosts of http sources, empty - any host with public address
+	SourceHosts []string `mapstructure:"sourceHosts" env:"SOURCE_HOSTS" usage:"allowed hosts of http sources"`
+
+	LogLevel  string `mapstructure:"logLevel" env:"LOG_LEVEL" reload:"true" usage:"debug, info, warn or error"`
+	LogFormat string `mapstructure:"logFormat" env:"LOG_FORMAT" usage:"text or json"`
+
+	TracingExporter    string  `mapstructure:"tracingExporter" env:"TRACING_EXPORTER" usage:"none, stdout or otlp"`
+	TracingEndpoint    string  `mapstructure:"tracingEndpoint" env:"TRACING_ENDPOINT" usage:"otlp collector"`
+	TracingInsecure    bool    `mapstructure:"tracingInsecure" env:"TRACING_INSECURE" usage:"connect to collector without tls"`
+	TracingSampleRatio float64 `mapstructure:"tracingSampleRatio" env:"TRACING_SAMPLE_RATIO" usage:"ratio of sampled traces"`
+
+	PriceMaxScale int32 `mapstructure:"priceMaxScale" env:"PRICE_MAX_SCALE" usage:"max digits of price after decimal point"`
+
+	AdminToken string `mapstructure:"adminToken" env:"ADMIN_TOKEN" usage:"token of admin routes"`
+
+	RateLimit float64 `mapstructure:"rateLimit" env:"RATE_LIMIT" reload:"true" usage:"requests per second, 0 - without limit"`
+	RateBurst int     `mapstructure:"rateBurst" env:"RATE_BURST" reload:"true" usage:"requests over rate limit in burst"`
+	// TaskWorkers - max sheets of uploads processed at the same time
+	TaskWorkers int `mapstructure:"taskWorkers" env:"TASK_WORKERS" reload:"true" usage:"max processed sheets, 0 - without limit"`
+
+	PurgeRetention time.Duration `mapstructure:"purgeRetention" env:"PURGE_RETENTION" usage:"time deleted products can be restored"`
+	PurgeInterval  time.Duration `mapstructure:"purgeInterval" env:"PURGE_INTERVAL" usage:"interval of purge of deleted products"`
+
+	Storage    string `mapstructure:"storage" env:"STORAGE" usage:"postgres, sqlite or memory"`
+	SQLitePath string `mapstructure:"SQLitePath" env:"SQLITE_PATH" usage:"database file of sqlite storage"`
+
+	CacheSize int           `mapstructure:"cacheSize" env:"CACHE_SIZE" usage:"max cached products and lists, 0 - without cache"`
+	CacheTTL  time.Duration `mapstructure:"cacheTTL" env:"CACHE_TTL" usage:"time products are cached"`
+
+	DBHost        string `mapstructure:"DBHost" env:"DB_HOST" usage:"host of postgres"`
+	DBPort        int    `mapstructure:"DBPort" env:"DB_PORT" usage:"port of postgres"`
+	DBUser        string `mapstructure:"DBUser" env:"DB_USER" usage:"user of postgres"`
+	DBPassword    string `mapstructure:"DBPassword" env:"DB_PASSWORD" usage:"password of postgres"`
+	DBName        string `mapstructure:"DBName" env:"DB_NAME" usage:"database of postgres"`
+	DBSSLMode     string `mapstructure:"DBSSLMode" env:"DB_SSL_MODE" usage:"disable, require, verify-ca or verify-full"`
+	DBSSLCert     string `mapstructure:"DBSSLCert" env:"DB_SSL_CERT" usage:"client certificate file"`
+	DBSSLKey      string `mapstructure:"DBSSLKey" env:"DB_SSL_KEY" usage:"client key file"`
+	DBSSLRootCert string `mapstructure:"DBSSLRootCert" env:"DB_SSL_ROOT_CERT" usage:"root certificate file"`
+	DBDSN         string `mapstructure:"DBDSN" env:"DB_DSN" usage:"full connection string of postgres"`
+
+	DBMaxOpenConns     int           `mapstructure:"DBMaxOpenConns" env:"DB_MAX_OPEN_CONNS" usage:"max open connections"`
+	DBMaxIdleConns     int           `mapstructure:"DBMaxIdleConns" env:"DB_MAX_IDLE_CONNS" usage:"max idle connections"`
+	DBConnMaxLifetime  time.Duration `mapstructure:"DBConnMaxLifetime" env:"DB_CONN_MAX_LIFETIME" usage:"max lifetime of connection"`
+	DBConnMaxIdleTime  time.Duration `mapstructure:"DBConnMaxIdleTime" env:"DB_CONN_MAX_IDLE_TIME" usage:"max idle time of connection"`
+	DBStatementTimeout time.Duration `mapstructure:"DBStatementTimeout" env:"DB_STATEMENT_TIMEOUT" usage:"server side limit of statement"`
+
+	DBReplicas             []string      `mapstructure:"DBReplicas" env:"DB_REPLICAS" usage:"read replicas, host or host:port"`
+	DBReplicaMaxLag        time.Duration `mapstructure:"DBReplicaMaxLag" env:"DB_REPLICA_MAX_LAG" usage:"max tolerated lag of replica"`
+	DBReplicaCheckInterval time.Duration `mapstructure:"DBReplicaCheckInterval" env:"DB_REPLICA_CHECK_INTERVAL" usage:"interval of check of replicas"`
+
+	DBReadTimeout  time.Duration `mapstructure:"DBReadTimeout" env:"DB_READ_TIMEOUT" usage:"timeout of read query"`
+	DBWriteTimeout time.Duration `mapstructure:"DBWriteTimeout" env:"DB_WRITE_TIMEOUT" usage:"timeout of write query"`
+}
+
+// defaults - values of settings which are not set anywhere, configs
+// written before setting was added keep working with them
+var defaults = map[string]interface{}{
+	"portListen":             ":8080",
+	"logLevel":               "info",
+	"logFormat":              "text",
+	"tracingExporter":        "none",
+	"tracingSampleRatio":     1,
+	"maxSourceSize":          1 << 30,
+	"sourceTimeout":          "10m",
+	"priceMaxScale":          2,
+	"storage":                "postgres",
+	"SQLitePath":             "businessConnService.db",
+	"DBPort":                 5432,
+	"DBSSLMode":              "disable",
+	"DBMaxOpenConns":         10,
+	"DBMaxIdleConns":         2,
+	"DBReplicaMaxLag":        "5s",
+	"DBReplicaCheckInterval": "5s",
+}
+
+// storages - supported values of storage
+var storages = map[string]bool{"postgres": true, "sqlite": true, "memory": true}
+
+// Validate - check settings which are not checked by components they are
+// passed to, so misconfiguration is reported at start
+func (config *Config) Validate() error {
+	if config.LogLevel != "" {
+		if _, err := logrus.ParseLevel(config.LogLevel); err != nil {
+			return fmt.Errorf("invalid logLevel: %w", err)
+		}
+	}
+	if config.PortListen == "" {
+		return errors.New("portListen must be set")
+	}
+	if !storages[config.Storage] {
+		return fmt.Errorf("unknown storage %q, it must be one of postgres, sqlite, memory", config.Storage)
+	}
+	if config.Storage == "sqlite" && config.SQLitePath == "" {
+		return errors.New("SQLitePath must be set for sqlite storage")
+	}
+	if config.MaxUploadSize < 0 {
+		return fmt.Errorf("invalid maxUploadSize %d", config.MaxUploadSize)
+	}
+	if config.MaxSourceSize < 0 {
+		return fmt.Errorf("invalid maxSourceSize %d", config.MaxSourceSize)
+	}
+	if config.TracingSampleRatio < 0 || config.TracingSampleRatio > 1 {
+		return fmt.Errorf("invalid tracingSampleRatio %g, it must be in [0, 1]", config.TracingSampleRatio)
+	}
+	if config.PriceMaxScale < 0 {
+		return fmt.Errorf("invalid priceMaxScale %d", config.PriceMaxScale)
+	}
+	if config.RateLimit < 0 || config.RateBurst < 0 {
+		return errors.New("rateLimit and rateBurst must not be negative")
+	}
+	if config.TaskWorkers < 0 {
+		return fmt.Errorf("invalid taskWorkers %d", config.TaskWorkers)
+	}
+	if config.CacheSize < 0 {
+		return fmt.Errorf("invalid cacheSize %d", config.CacheSize)
+	}
+	if config.CacheSize > 0 && config.CacheTTL <= 0 {
+		return errors.New("cacheTTL must be positive if cache is enabled")
+	}
+
+	for name, duration := range map[string]time.Duration{
+		"sourceTimeout":          config.SourceTimeout,
+		"purgeRetention":         config.PurgeRetention,
+		"purgeInterval":          config.PurgeInterval,
+		"DBReplicaMaxLag":        config.DBReplicaMaxLag,
+		"DBReplicaCheckInterval": config.DBReplicaCheckInterval,
+		"DBReadTimeout":          config.DBReadTimeout,
+		"DBWriteTimeout":         config.DBWriteTimeout,
+	} {
+		if duration < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	return nil
+}
@@ -1,11 +1,157 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"time"
 
-func Init() error {
-	viper.AddConfigPath("../../config")
-	viper.AddConfigPath("config")
-	viper.SetConfigName("config")
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
 
-	return viper.ReadInConfig()
+// EnvPrefix - prefix of environment variables which override config, for
+// example AVITO_DB_PASSWORD overrides DBPassword
+const EnvPrefix = "AVITO_"
+
+// fileSuffix - suffix of environment variable with path to file with value
+// of setting, for example AVITO_DB_PASSWORD_FILE, so that secrets mounted by
+// docker or kubernetes are not passed in environment
+const fileSuffix = "_FILE"
+
+// setting - key of config.yml and names of its environment variable and flag
+type setting struct {
+	key       string
+	env       string
+	flag      string
+	usage     string
+	fieldType reflect.Type
+}
+
+// settings - settings of fields of Config
+func settings() []setting {
+	configType := reflect.TypeOf(Config{})
+
+	settings := make([]setting, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		env := field.Tag.Get("env")
+
+		settings = append(settings, setting{
+			key:       field.Tag.Get("mapstructure"),
+			env:       EnvPrefix + env,
+			flag:      strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			usage:     field.Tag.Get("usage"),
+			fieldType: field.Type,
+		})
+	}
+
+	return settings
+}
+
+// addFlag - add flag of setting with type of its field, so that value of
+// flag which is not passed is zero value of field
+func addFlag(flags *pflag.FlagSet, setting setting) {
+	usage := fmt.Sprintf("%s (%s)", setting.usage, setting.env)
+
+	switch setting.fieldType {
+	case reflect.TypeOf(time.Duration(0)):
+		flags.Duration(setting.flag, 0, usage)
+	case reflect.TypeOf([]string{}):
+		flags.StringSlice(setting.flag, nil, usage)
+	default:
+		switch setting.fieldType.Kind() {
+		case reflect.Bool:
+			flags.Bool(setting.flag, false, usage)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			flags.Int64(setting.flag, 0, usage)
+		case reflect.Float64:
+			flags.Float64(setting.flag, 0, usage)
+		default:
+			flags.String(setting.flag, "", usage)
+		}
+	}
+}
+
+// Load - read config from file, environment and command-line args, later
+// sources override earlier ones: defaults, config file, environment
+// variables, files of environment variables with _FILE suffix, flags.
+// Config file is --config or AVITO_CONFIG, without them config.yml is
+// looked for in "config" and "../../config" and it can be absent
+func Load(args []string) (*Config, error) {
+	config, _, err := load(args)
+
+	return config, err
+}
+
+// load - read config and return it with viper it was read by
+func load(args []string) (*Config, *viper.Viper, error) {
+	v := viper.New()
+
+	flags := pflag.NewFlagSet("businessConnService", pflag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(EnvPrefix+"CONFIG"), "path to config file")
+
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	for _, setting := range settings() {
+		if err := v.BindEnv(setting.key, setting.env); err != nil {
+			return nil, nil, err
+		}
+
+		addFlag(flags, setting)
+		if err := v.BindPFlag(setting.key, flags.Lookup(setting.flag)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		v.AddConfigPath("../../config")
+		v.AddConfigPath("config")
+		v.SetConfigName("config")
+
+		// all settings can be passed in environment, e.g. in containers
+		var notFound viper.ConfigFileNotFoundError
+		if err := v.ReadInConfig(); err != nil && !errors.As(err, &notFound) {
+			return nil, nil, err
+		}
+	}
+
+	for _, setting := range settings() {
+		path, ok := os.LookupEnv(setting.env + fileSuffix)
+		if !ok || flags.Changed(setting.flag) {
+			continue
+		}
+
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", setting.env+fileSuffix, err)
+		}
+		// files written by editors end with newline
+		v.Set(setting.key, strings.TrimRight(string(value), "\r\n"))
+	}
+
+	config := new(Config)
+	if err := v.Unmarshal(config); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return config, v, nil
 }
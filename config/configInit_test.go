@@ -0,0 +1,200 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv - set environment variable for test
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	// test config of repository is valid
+
+	config, err := Load([]string{"--config", "config.yml"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, ":8080", config.PortListen)
+		assert.Equal(t, "postgres", config.Storage)
+		assert.Equal(t, 5432, config.DBPort)
+		assert.Equal(t, 5*time.Second, config.DBReadTimeout)
+		assert.Equal(t, 720*time.Hour, config.PurgeRetention)
+	}
+
+	// test environment overrides file and flags override environment
+
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := ioutil.WriteFile(secret, []byte("from file\n"), 0600); err != nil {
+		t.Fatalf("Can`t write secret: %s", err)
+	}
+
+	setenv(t, "AVITO_DB_HOST", "db")
+	setenv(t, "AVITO_DB_PORT", "6432")
+	setenv(t, "AVITO_DB_PASSWORD_FILE", secret)
+	setenv(t, "AVITO_DB_REPLICAS", "replica1,replica2:5433")
+	setenv(t, "AVITO_CACHE_TTL", "1m")
+
+	config, err = Load([]string{"--config", "config.yml", "--db-port", "7432", "--log-level=debug"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "db", config.DBHost)
+		assert.Equal(t, 7432, config.DBPort)
+		assert.Equal(t, "from file", config.DBPassword)
+		assert.Equal(t, []string{"replica1", "replica2:5433"}, config.DBReplicas)
+		assert.Equal(t, time.Minute, config.CacheTTL)
+		assert.Equal(t, "debug", config.LogLevel)
+	}
+
+	// test secret flag overrides file
+
+	config, err = Load([]string{"--config", "config.yml", "--db-password", "from flag"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "from flag", config.DBPassword)
+	}
+
+	// test missing secret file is an error
+
+	setenv(t, "AVITO_DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err = Load([]string{"--config", "config.yml"})
+	assert.Error(t, err)
+}
+
+func TestLoadWithoutConfigFile(t *testing.T) {
+	// config.yml is not found from temp dir, settings are defaults and environment
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Can`t get working dir: %s", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Can`t change working dir: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	setenv(t, "AVITO_STORAGE", "memory")
+
+	config, err := Load(nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "memory", config.Storage)
+		assert.Equal(t, ":8080", config.PortListen)
+	}
+
+	// test explicit config file must exist
+
+	_, err = Load([]string{"--config", "missing.yml"})
+	assert.Error(t, err)
+
+	// test invalid settings are rejected
+
+	_, err = Load([]string{"--storage", "mongo"})
+	assert.Error(t, err)
+
+	_, err = Load([]string{"--cache-size", "-1"})
+	assert.Error(t, err)
+
+	_, err = Load([]string{"--db-read-timeout", "soon"})
+	assert.Error(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Can`t write config: %s", err)
+		}
+	}
+
+	write("storage: memory\nlogLevel: info\ntaskWorkers: 2\nportListen: :8080\n")
+
+	args := []string{"--config", path}
+	config, err := Load(args)
+	if err != nil {
+		t.Fatalf("Can`t load config: %s", err)
+	}
+
+	applied := make(chan *Config, 10)
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	if err := Watch(args, config, logger, func(config *Config) { applied <- config }); err != nil {
+		t.Fatalf("Can`t watch config: %s", err)
+	}
+
+	// test reloadable settings are applied and others are kept until restart
+
+	write("storage: memory\nlogLevel: debug\ntaskWorkers: 4\nportListen: :9090\n")
+
+	select {
+	case reloaded := <-applied:
+		assert.Equal(t, "debug", reloaded.LogLevel)
+		assert.Equal(t, 4, reloaded.TaskWorkers)
+		assert.Equal(t, ":8080", reloaded.PortListen)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Config is not reloaded")
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	current := &Config{LogLevel: "info", RateLimit: 10, DBPassword: "old"}
+	seen := *current
+
+	// warnings - warnings logged since last call
+	warnings := func() int {
+		count := 0
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel {
+				count++
+			}
+		}
+		hook.Reset()
+
+		return count
+	}
+
+	// test only not reloadable settings are changed
+
+	updated, changed := reloadSettings(current, &seen,
+		&Config{LogLevel: "info", RateLimit: 10, DBPassword: "new"}, logger)
+	assert.False(t, changed)
+	assert.Equal(t, current, updated)
+	assert.Equal(t, 1, warnings())
+
+	updated, changed = reloadSettings(current, &seen,
+		&Config{LogLevel: "warn", RateLimit: 10, DBPassword: "new"}, logger)
+	assert.True(t, changed)
+	assert.Equal(t, &Config{LogLevel: "warn", RateLimit: 10, DBPassword: "old"}, updated)
+
+	// test change of not reloadable setting is warned once
+
+	assert.Equal(t, 0, warnings())
+
+	_, _ = reloadSettings(current, &seen, &Config{LogLevel: "info", RateLimit: 10, DBPassword: "other"}, logger)
+	assert.Equal(t, 1, warnings())
+
+	// test setting returned to current value and changed again is warned
+
+	_, _ = reloadSettings(current, &seen, &Config{LogLevel: "info", RateLimit: 10, DBPassword: "old"}, logger)
+	assert.Equal(t, 0, warnings())
+
+	_, _ = reloadSettings(current, &seen, &Config{LogLevel: "info", RateLimit: 10, DBPassword: "other"}, logger)
+	assert.Equal(t, 1, warnings())
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Watch - reload config when config file is changed and pass it to apply,
+// args are the same as of Load. Only settings with reload tag are changed in
+// passed config, changes of other settings are logged and are applied after
+// restart. Invalid config is logged and is not applied. Without config file
+// there is nothing to watch
+func Watch(args []string, current *Config, logger *logrus.Logger, apply func(config *Config)) error {
+	_, v, err := load(args)
+	if err != nil {
+		return err
+	}
+
+	if v.ConfigFileUsed() == "" {
+		return nil
+	}
+
+	var mu sync.Mutex
+	applied := *current
+	// seen keeps loaded values of settings which are not reloaded, so their
+	// change is warned once instead of on every save of file
+	seen := *current
+
+	v.OnConfigChange(func(event fsnotify.Event) {
+		// file is read again with environment and flags, so they still
+		// override it
+		loaded, _, err := load(args)
+		if err != nil {
+			logger.WithError(err).WithField("file", event.Name).Error("Config is not reloaded")
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		updated, changed := reloadSettings(&applied, &seen, loaded, logger)
+		if !changed {
+			return
+		}
+
+		applied = *updated
+		apply(updated)
+	})
+	v.WatchConfig()
+
+	return nil
+}
+
+// reloadSettings - copy of current config with reloadable settings of
+// loaded one, every changed setting is logged. Settings which are not
+// reloaded are warned about if they differ from current and from seen
+// values of previous load, seen is updated by loaded ones. Their values are
+// not logged, they can be secrets
+func reloadSettings(current, seen, loaded *Config, logger *logrus.Logger) (*Config, bool) {
+	updated := *current
+	changed := false
+
+	currentValue := reflect.ValueOf(current).Elem()
+	seenValue := reflect.ValueOf(seen).Elem()
+	loadedValue := reflect.ValueOf(loaded).Elem()
+	updatedValue := reflect.ValueOf(&updated).Elem()
+
+	for i := 0; i < currentValue.NumField(); i++ {
+		field := currentValue.Type().Field(i)
+		log := logger.WithField("setting", field.Tag.Get("mapstructure"))
+
+		oldValue, newValue := currentValue.Field(i).Interface(), loadedValue.Field(i).Interface()
+
+		if field.Tag.Get("reload") != "true" {
+			if !reflect.DeepEqual(oldValue, newValue) && !reflect.DeepEqual(seenValue.Field(i).Interface(), newValue) {
+				log.Warn("Config setting is changed, it is applied after restart")
+			}
+			seenValue.Field(i).Set(loadedValue.Field(i))
+			continue
+		}
+
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"old": oldValue,
+			"new": newValue,
+		}).Info("Config setting is changed")
+
+		updatedValue.Field(i).Set(loadedValue.Field(i))
+		changed = true
+	}
+
+	return &updated, changed
+}
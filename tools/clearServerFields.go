@@ -0,0 +1,14 @@
+package tools
+
+import (
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// ClearServerFields - clear fields of product which are not stored or are
+// set by storage, values of them sent by client are ignored. It is applied
+// to every product decoded from json of request or batch
+func ClearServerFields(productInfo *models.ProductInfo) {
+	productInfo.Version = 0
+	productInfo.DeletedAt = nil
+	productInfo.ConvertedPrice = nil
+}
@@ -0,0 +1,28 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClearServerFields(t *testing.T) {
+	deletedAt := time.Now()
+	convertedPrice := decimal.RequireFromString("1.5")
+
+	productInfo := &models.ProductInfo{
+		SellerID:       1,
+		OfferID:        2,
+		Name:           "телефон",
+		Version:        3,
+		DeletedAt:      &deletedAt,
+		ConvertedPrice: &convertedPrice,
+	}
+
+	ClearServerFields(productInfo)
+
+	assert.Equal(t, &models.ProductInfo{SellerID: 1, OfferID: 2, Name: "телефон"}, productInfo)
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"encoding/json"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// ConvertJSONToProductInfo - parse product of batch upload, currency is used
+// for product without currency. Product without available is kept
+// available, available false deletes product as in xlsx row. seller_id can
+// be omitted, but it must be sellerID if it is set. Decoded product which is
+// not valid is returned with error, so its offer id is known
+func ConvertJSONToProductInfo(data []byte, sellerID int64, currency string) (*models.ProductInfo, error) {
+	productInfo := &models.ProductInfo{
+		SellerID:  sellerID,
+		Currency:  currency,
+		Available: true,
+	}
+
+	if err := json.Unmarshal(data, productInfo); err != nil {
+		return nil, domainErrors.Wrap(domainErrors.ErrValidation, err, "Row must be json ProductInfo")
+	}
+
+	if productInfo.SellerID != sellerID {
+		return productInfo, domainErrors.InvalidFields("Misrepresentation of values",
+			domainErrors.FieldError{Field: "seller_id", Message: "must be seller of batch"})
+	}
+
+	ClearServerFields(productInfo)
+
+	if err := ValidateProductInfo(productInfo); err != nil {
+		return productInfo, err
+	}
+
+	return productInfo, nil
+}
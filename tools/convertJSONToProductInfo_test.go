@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertJSONToProductInfo(t *testing.T) {
+	expectProductInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   2,
+		Name:      "a",
+		Price:     decimal.RequireFromString("10.5"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+	}
+
+	// test seller, currency and available are set by default
+
+	productInfo, err := ConvertJSONToProductInfo([]byte(`{"offer_id":2,"name":"a","price":"10.5","quantity":10}`),
+		1, "RUB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expectProductInfo, productInfo)
+	}
+
+	productInfo, err = ConvertJSONToProductInfo([]byte(`{"seller_id":1,"offer_id":2,"name":"a","price":"10.5",`+
+		`"currency":"USD","available":false,"version":7}`), 1, "RUB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "USD", productInfo.Currency)
+		assert.False(t, productInfo.Available)
+		assert.Equal(t, int64(0), productInfo.Version)
+	}
+
+	// test invalid rows
+
+	for _, data := range []string{
+		`{"offer_id":2,"name":"a","price":"10.5"`,
+		`[1, 2]`,
+		`{"offer_id":"2","name":"a","price":"10.5"}`,
+		`{"seller_id":3,"offer_id":2,"name":"a","price":"10.5"}`,
+		`{"offer_id":2,"price":"10.5"}`,
+		`{"offer_id":2,"name":"a","price":"-1"}`,
+	} {
+		_, err := ConvertJSONToProductInfo([]byte(data), 1, "RUB")
+		assert.True(t, errors.Is(err, domainErrors.ErrValidation), data)
+	}
+
+	// test decoded invalid product is returned with its offer id
+
+	productInfo, err = ConvertJSONToProductInfo([]byte(`{"offer_id":2,"price":"10.5"}`), 1, "RUB")
+	if assert.Error(t, err) && assert.NotNil(t, productInfo) {
+		assert.Equal(t, int64(2), productInfo.OfferID)
+	}
+}
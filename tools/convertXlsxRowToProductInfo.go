@@ -1,30 +1,93 @@
 package tools
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
 )
 
-const rowLength = 5
+// XlsxColumns - columns of xlsx row in order of file without header, first
+// requiredXlsxColumns are required, rest are optional
+var XlsxColumns = []string{"offer_id", "name", "price", "quantity", "available", "currency", "sku",
+	"description", "category", "brand", "images", "weight", "length", "width", "height", "extras"}
 
-func ConvertXlsxRowToProductInfo(row []string, sellerID int64) (*models.ProductInfo, error) {
-	if len(row) != rowLength {
+const requiredXlsxColumns = 5
+
+// XlsxMapping - index of column in xlsx row by its name, columns which are
+// not attributes of product are saved to extras
+type XlsxMapping map[string]int
+
+// DefaultXlsxMapping - mapping of file without header
+func DefaultXlsxMapping() XlsxMapping {
+	mapping := XlsxMapping{}
+	for i, column := range XlsxColumns {
+		mapping[column] = i
+	}
+
+	return mapping
+}
+
+// ParseXlsxHeader - mapping of columns by first row of sheet, row is header
+// only if it has names of all required columns
+func ParseXlsxHeader(row []string) (XlsxMapping, bool) {
+	mapping := XlsxMapping{}
+	for i, cell := range row {
+		if name := strings.ToLower(strings.TrimSpace(cell)); name != "" {
+			mapping[name] = i
+		}
+	}
+
+	for _, column := range XlsxColumns[:requiredXlsxColumns] {
+		if _, ok := mapping[column]; !ok {
+			return nil, false
+		}
+	}
+
+	return mapping, true
+}
+
+// ConvertXlsxRowToProductInfo - parse row of uploaded file without header,
+// currency is used for row without currency column
+func ConvertXlsxRowToProductInfo(row []string, sellerID int64, currency string) (*models.ProductInfo, error) {
+	if len(row) < requiredXlsxColumns || len(row) > len(XlsxColumns) {
 		return nil, errors.New("Xlsx row has wrong length")
 	}
 
-	offerIDStr := row[0]
-	nameStr := row[1]
-	priceStr := row[2]
-	quantityStr := row[3]
-	availableStr := row[4]
+	return ConvertMappedXlsxRowToProductInfo(DefaultXlsxMapping(), row, sellerID, currency)
+}
+
+// ConvertMappedXlsxRowToProductInfo - parse row of uploaded file with columns
+// found by mapping, currency is used for row without currency column
+func ConvertMappedXlsxRowToProductInfo(mapping XlsxMapping, row []string, sellerID int64,
+	currency string) (*models.ProductInfo, error) {
+
+	cell := func(column string) string {
+		i, ok := mapping[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	offerIDStr := cell("offer_id")
+	nameStr := cell("name")
+	priceStr := cell("price")
+	quantityStr := cell("quantity")
+	availableStr := cell("available")
 
 	if offerIDStr == "" || nameStr == "" || priceStr == "" ||
 		quantityStr == "" || availableStr == "" {
 		return nil, errors.New("Nil col value")
 	}
 
+	if value := cell("currency"); value != "" {
+		currency = value
+	}
+
 	productInfo := new(models.ProductInfo)
 
 	offerID, err := strconv.ParseInt(offerIDStr, 10, 64)
@@ -34,7 +97,8 @@ func ConvertXlsxRowToProductInfo(row []string, sellerID int64) (*models.ProductI
 
 	name := nameStr
 
-	price, err := strconv.ParseFloat(priceStr, 64)
+	// NaN and Inf are not parsed as decimal
+	price, err := decimal.NewFromString(priceStr)
 	if err != nil {
 		return nil, err
 	}
@@ -49,16 +113,125 @@ func ConvertXlsxRowToProductInfo(row []string, sellerID int64) (*models.ProductI
 		return nil, err
 	}
 
-	if offerID <= 0 || sellerID <= 0 || price < 0 || quantity < 0 {
-		return nil, errors.New("Misrepresentation of values")
-	}
-
 	productInfo.SellerID = sellerID
 	productInfo.OfferID = offerID
 	productInfo.Name = name
 	productInfo.Price = price
+	productInfo.Currency = currency
 	productInfo.Quantity = quantity
 	productInfo.Available = available
 
+	productInfo.SKU = cell("sku")
+	productInfo.Description = cell("description")
+	productInfo.Category = cell("category")
+	productInfo.Brand = cell("brand")
+
+	// urls of images are separated by spaces or new lines
+	if images := strings.Fields(cell("images")); len(images) > 0 {
+		productInfo.Images = images
+	}
+
+	if value := cell("weight"); value != "" {
+		weight, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, err
+		}
+		productInfo.Weight = &weight
+	}
+
+	if productInfo.Dimensions, err = convertXlsxDimensions(cell("length"), cell("width"), cell("height")); err != nil {
+		return nil, err
+	}
+
+	if productInfo.Extras, err = convertXlsxExtras(mapping, row); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateProductInfo(productInfo); err != nil {
+		return nil, err
+	}
+
 	return productInfo, nil
 }
+
+// convertXlsxDimensions - parse dimensions, they are set all together or
+// not set at all
+func convertXlsxDimensions(lengthStr, widthStr, heightStr string) (*models.Dimensions, error) {
+	if lengthStr == "" && widthStr == "" && heightStr == "" {
+		return nil, nil
+	}
+
+	if lengthStr == "" || widthStr == "" || heightStr == "" {
+		return nil, errors.New("length, width and height must be set together")
+	}
+
+	dimensions := new(models.Dimensions)
+
+	var err error
+	if dimensions.Length, err = decimal.NewFromString(lengthStr); err != nil {
+		return nil, err
+	}
+	if dimensions.Width, err = decimal.NewFromString(widthStr); err != nil {
+		return nil, err
+	}
+	if dimensions.Height, err = decimal.NewFromString(heightStr); err != nil {
+		return nil, err
+	}
+
+	return dimensions, nil
+}
+
+// convertXlsxExtras - collect json object of extras column and values of
+// columns which are not attributes of product
+func convertXlsxExtras(mapping XlsxMapping, row []string) (map[string]interface{}, error) {
+	extras := map[string]interface{}{}
+
+	if i, ok := mapping["extras"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+		if err := json.Unmarshal([]byte(row[i]), &extras); err != nil {
+			return nil, errors.New("extras must be json object")
+		}
+	}
+
+	known := DefaultXlsxMapping()
+	for column, i := range mapping {
+		if _, ok := known[column]; ok || i >= len(row) || strings.TrimSpace(row[i]) == "" {
+			continue
+		}
+		extras[column] = strings.TrimSpace(row[i])
+	}
+
+	if len(extras) == 0 {
+		return nil, nil
+	}
+
+	return extras, nil
+}
+
+// ConvertProductInfoToXlsxRow - values of XlsxColumns of product, file with
+// such rows can be uploaded back. Decimals are returned as decimal.Decimal,
+// absent optional values are nil
+func ConvertProductInfoToXlsxRow(product *models.ProductInfo) ([]interface{}, error) {
+	row := []interface{}{product.OfferID, product.Name, product.Price, product.Quantity, product.Available,
+		product.Currency, product.SKU, product.Description, product.Category, product.Brand,
+		strings.Join(product.Images, "\n"), nil, nil, nil, nil, nil}
+
+	if product.Weight != nil {
+		row[11] = *product.Weight
+	}
+
+	if product.Dimensions != nil {
+		row[12] = product.Dimensions.Length
+		row[13] = product.Dimensions.Width
+		row[14] = product.Dimensions.Height
+	}
+
+	if len(product.Extras) > 0 {
+		extras, err := json.Marshal(product.Extras)
+		if err != nil {
+			return nil, err
+		}
+		row[15] = string(extras)
+	}
+
+	return row, nil
+}
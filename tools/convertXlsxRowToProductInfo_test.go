@@ -1,9 +1,11 @@
 package tools
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,43 +17,166 @@ func TestConvertXlsxRowToProductInfo(t *testing.T) {
 		SellerID:  1,
 		OfferID:   1,
 		Name:      "a",
-		Price:     10.5,
+		Price:     decimal.RequireFromString("10.5"),
+		Currency:  "RUB",
 		Quantity:  10,
 		Available: true,
 	}
 
-	productInfo, err := ConvertXlsxRowToProductInfo(testRowData, testSellerID)
+	productInfo, err := ConvertXlsxRowToProductInfo(testRowData, testSellerID, "RUB")
 	if assert.NoError(t, err) {
 		assert.Equal(t, expectProductInfo, productInfo)
 	}
 
+	testRowDataWithCurrency := []string{"1", "a", "10.5", "10", "true", "USD"}
+
+	productInfo, err = ConvertXlsxRowToProductInfo(testRowDataWithCurrency, testSellerID, "RUB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "USD", productInfo.Currency)
+	}
+
+	productInfo, err = ConvertXlsxRowToProductInfo(testRowData, testSellerID, "EUR")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "EUR", productInfo.Currency)
+	}
+
 	testRowDataIncorrectOfferID := []string{"1.5", "a", "10.5", "10", "true"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectOfferID, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectOfferID, testSellerID, "RUB")
 	assert.Error(t, err)
 
 	testRowDataIncorrectPrice := []string{"1", "a", "abc", "10", "true"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectPrice, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectPrice, testSellerID, "RUB")
 	assert.Error(t, err)
 
+	for _, price := range []string{"NaN", "Inf", "-Inf", "1e300", "0.1e-5"} {
+		_, err = ConvertXlsxRowToProductInfo([]string{"1", "a", price, "10", "true"}, testSellerID, "RUB")
+		assert.Error(t, err, price)
+	}
+
 	testRowDataIncorrectQuantity := []string{"1", "a", "10.5", "10.5", "true"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectQuantity, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectQuantity, testSellerID, "RUB")
 	assert.Error(t, err)
 
 	testRowDataIncorrectLength := []string{"1", "a", "-10.5", "10"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectLength, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataIncorrectLength, testSellerID, "RUB")
 	assert.Error(t, err)
 
 	testRowDataWithEmptyField := []string{"1", "a", "", "-10", "true"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataWithEmptyField, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataWithEmptyField, testSellerID, "RUB")
 	assert.Error(t, err)
 
 	testRowDataNegativePrice := []string{"1", "a", "-10", "10", "true"}
 
-	_, err = ConvertXlsxRowToProductInfo(testRowDataNegativePrice, testSellerID)
+	_, err = ConvertXlsxRowToProductInfo(testRowDataNegativePrice, testSellerID, "RUB")
 	assert.Error(t, err)
 }
+
+func TestConvertMappedXlsxRowToProductInfo(t *testing.T) {
+	testSellerID := int64(1)
+
+	_, ok := ParseXlsxHeader([]string{"1", "a", "10.5", "10", "true"})
+	assert.False(t, ok)
+
+	mapping, ok := ParseXlsxHeader([]string{" Name ", "Offer_ID", "price", "available", "quantity",
+		"brand", "images", "weight", "length", "width", "height", "extras", "color"})
+	if !assert.True(t, ok) {
+		return
+	}
+
+	weight := decimal.RequireFromString("0.2")
+	expectProductInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "a",
+		Price:     decimal.RequireFromString("10.5"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Brand:     "acme",
+		Images:    []string{"http://img/1.png", "http://img/2.png"},
+		Weight:    &weight,
+		Dimensions: &models.Dimensions{
+			Length: decimal.RequireFromString("1"),
+			Width:  decimal.RequireFromString("2"),
+			Height: decimal.RequireFromString("3"),
+		},
+		Extras: map[string]interface{}{"size": "xl", "color": "black"},
+	}
+
+	productInfo, err := ConvertMappedXlsxRowToProductInfo(mapping, []string{"a", "1", "10.5", "true", "10",
+		"acme", "http://img/1.png\nhttp://img/2.png", "0.2", "1", "2", "3", `{"size": "xl"}`, "black"},
+		testSellerID, "RUB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, expectProductInfo, productInfo)
+	}
+
+	// test optional columns of row without header
+
+	productInfo, err = ConvertXlsxRowToProductInfo([]string{"1", "a", "10.5", "10", "true", "", "PH-1",
+		"", "электроника/телефоны"}, testSellerID, "RUB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "PH-1", productInfo.SKU)
+		assert.Equal(t, "электроника/телефоны", productInfo.Category)
+		assert.Nil(t, productInfo.Images)
+		assert.Nil(t, productInfo.Dimensions)
+		assert.Nil(t, productInfo.Extras)
+	}
+
+	// test invalid attributes
+
+	for _, row := range [][]string{
+		{"a", "1", "10.5", "true", "10", "", "", "", "1", "2", ""},
+		{"a", "1", "10.5", "true", "10", "", "", "abc"},
+		{"a", "1", "10.5", "true", "10", "", "", "", "", "", "", "[1]"},
+		{"a", "1", "10.5", "true", "10", "", "ftp://img/1.png"},
+		{"a", "1", "10.5", "true", "10", "", "", "-1"},
+	} {
+		_, err = ConvertMappedXlsxRowToProductInfo(mapping, row, testSellerID, "RUB")
+		assert.Error(t, err, row)
+	}
+}
+
+func TestConvertProductInfoToXlsxRow(t *testing.T) {
+	weight := decimal.RequireFromString("0.2")
+	productInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "a",
+		Price:     decimal.RequireFromString("10.5"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Category:  "электроника/телефоны",
+		Images:    []string{"http://img/1.png", "http://img/2.png"},
+		Weight:    &weight,
+		Extras:    map[string]interface{}{"color": "black"},
+	}
+
+	row, err := ConvertProductInfoToXlsxRow(productInfo)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []interface{}{int64(1), "a", productInfo.Price, int64(10), true, "RUB", "", "",
+		"электроника/телефоны", "", "http://img/1.png\nhttp://img/2.png", weight, nil, nil, nil,
+		`{"color":"black"}`}, row)
+
+	// exported row is uploaded back to the same product
+
+	cells := make([]string, len(row))
+	for i, value := range row {
+		if value != nil {
+			cells[i] = fmt.Sprint(value)
+		}
+	}
+
+	uploaded, err := ConvertXlsxRowToProductInfo(cells, 1, "USD")
+	if assert.NoError(t, err) {
+		assert.Equal(t, productInfo, uploaded)
+	}
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"reflect"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// DiffProductInfo - fields of product which differ in oldValue and newValue,
+// version is not compared
+func DiffProductInfo(oldValue, newValue *models.ProductInfo) []models.FieldChange {
+	changes := []models.FieldChange{}
+
+	if oldValue.Name != newValue.Name {
+		changes = append(changes, models.FieldChange{Field: "name", Old: oldValue.Name, New: newValue.Name})
+	}
+
+	if !oldValue.Price.Equal(newValue.Price) {
+		changes = append(changes, models.FieldChange{Field: "price", Old: oldValue.Price, New: newValue.Price})
+	}
+
+	if oldValue.Currency != newValue.Currency {
+		changes = append(changes, models.FieldChange{Field: "currency", Old: oldValue.Currency, New: newValue.Currency})
+	}
+
+	if oldValue.Quantity != newValue.Quantity {
+		changes = append(changes, models.FieldChange{Field: "quantity", Old: oldValue.Quantity, New: newValue.Quantity})
+	}
+
+	if oldValue.Available != newValue.Available {
+		changes = append(changes, models.FieldChange{Field: "available", Old: oldValue.Available, New: newValue.Available})
+	}
+
+	if oldValue.SKU != newValue.SKU {
+		changes = append(changes, models.FieldChange{Field: "sku", Old: oldValue.SKU, New: newValue.SKU})
+	}
+
+	if oldValue.Description != newValue.Description {
+		changes = append(changes, models.FieldChange{Field: "description", Old: oldValue.Description,
+			New: newValue.Description})
+	}
+
+	if oldValue.Category != newValue.Category {
+		changes = append(changes, models.FieldChange{Field: "category", Old: oldValue.Category, New: newValue.Category})
+	}
+
+	if oldValue.Brand != newValue.Brand {
+		changes = append(changes, models.FieldChange{Field: "brand", Old: oldValue.Brand, New: newValue.Brand})
+	}
+
+	if !reflect.DeepEqual(oldValue.Images, newValue.Images) {
+		changes = append(changes, models.FieldChange{Field: "images", Old: oldValue.Images, New: newValue.Images})
+	}
+
+	if !equalDecimals(oldValue.Weight, newValue.Weight) {
+		changes = append(changes, models.FieldChange{Field: "weight", Old: oldValue.Weight, New: newValue.Weight})
+	}
+
+	if !equalDimensions(oldValue.Dimensions, newValue.Dimensions) {
+		changes = append(changes, models.FieldChange{Field: "dimensions", Old: oldValue.Dimensions,
+			New: newValue.Dimensions})
+	}
+
+	if !reflect.DeepEqual(oldValue.Extras, newValue.Extras) {
+		changes = append(changes, models.FieldChange{Field: "extras", Old: oldValue.Extras, New: newValue.Extras})
+	}
+
+	return changes
+}
+
+// equalDecimals - both decimals are nil or have equal values
+func equalDecimals(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+// equalDimensions - both dimensions are nil or have equal values
+func equalDimensions(a, b *models.Dimensions) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Length.Equal(b.Length) && a.Width.Equal(b.Width) && a.Height.Equal(b.Height)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestDiffProductInfo(t *testing.T) {
+	oldValue := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Version:   1,
+	}
+
+	// test equal products
+
+	sameValue := *oldValue
+	sameValue.Price = decimal.RequireFromString("100.250")
+	sameValue.Version = 2
+
+	if changes := DiffProductInfo(oldValue, &sameValue); len(changes) != 0 {
+		t.Errorf("expected no changes, have %v", changes)
+		return
+	}
+
+	// test changed fields
+
+	newValue := *oldValue
+	newValue.Price = decimal.RequireFromString("150")
+	newValue.Currency = "USD"
+	newValue.Quantity = 5
+
+	expectChanges := []models.FieldChange{
+		{Field: "price", Old: oldValue.Price, New: newValue.Price},
+		{Field: "currency", Old: "RUB", New: "USD"},
+		{Field: "quantity", Old: int64(10), New: int64(5)},
+	}
+
+	if changes := DiffProductInfo(oldValue, &newValue); !reflect.DeepEqual(changes, expectChanges) {
+		t.Errorf("results not match, want %v, have %v", expectChanges, changes)
+	}
+}
+
+func TestDiffProductInfoAttributes(t *testing.T) {
+	weight := decimal.RequireFromString("0.5")
+	oldValue := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		Brand:     "acme",
+		Weight:    &weight,
+		Dimensions: &models.Dimensions{
+			Length: decimal.RequireFromString("15"),
+			Width:  decimal.RequireFromString("7.5"),
+			Height: decimal.RequireFromString("0.8"),
+		},
+	}
+
+	// test equal attributes
+
+	sameWeight := decimal.RequireFromString("0.50")
+	sameValue := *oldValue
+	sameValue.Weight = &sameWeight
+	sameValue.Dimensions = &models.Dimensions{
+		Length: decimal.RequireFromString("15.0"),
+		Width:  decimal.RequireFromString("7.50"),
+		Height: decimal.RequireFromString("0.8"),
+	}
+
+	if changes := DiffProductInfo(oldValue, &sameValue); len(changes) != 0 {
+		t.Errorf("expected no changes, have %v", changes)
+		return
+	}
+
+	// test changed attributes
+
+	newValue := *oldValue
+	newValue.Category = "электроника"
+	newValue.Brand = ""
+	newValue.Images = []string{"http://img/1.png"}
+	newValue.Weight = nil
+	newValue.Extras = map[string]interface{}{"color": "black"}
+
+	expectChanges := []models.FieldChange{
+		{Field: "category", Old: "", New: "электроника"},
+		{Field: "brand", Old: "acme", New: ""},
+		{Field: "images", Old: []string(nil), New: newValue.Images},
+		{Field: "weight", Old: oldValue.Weight, New: (*decimal.Decimal)(nil)},
+		{Field: "extras", Old: map[string]interface{}(nil), New: newValue.Extras},
+	}
+
+	if changes := DiffProductInfo(oldValue, &newValue); !reflect.DeepEqual(changes, expectChanges) {
+		t.Errorf("results not match, want %v, have %v", expectChanges, changes)
+	}
+}
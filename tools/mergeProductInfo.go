@@ -0,0 +1,46 @@
+package tools
+
+import "github.com/Toringol/avito-mx-backend-test-task/app/models"
+
+// MergeProductInfo - update product by row of uploaded file. Required fields
+// are replaced, optional attributes are replaced only if they are set in row,
+// so file without some columns does not clear them. Extras are merged by keys
+func MergeProductInfo(product, row *models.ProductInfo) {
+	product.Name = row.Name
+	product.Price = row.Price
+	product.Currency = row.Currency
+	product.Quantity = row.Quantity
+
+	if row.SKU != "" {
+		product.SKU = row.SKU
+	}
+	if row.Description != "" {
+		product.Description = row.Description
+	}
+	if row.Category != "" {
+		product.Category = row.Category
+	}
+	if row.Brand != "" {
+		product.Brand = row.Brand
+	}
+	if len(row.Images) > 0 {
+		product.Images = row.Images
+	}
+	if row.Weight != nil {
+		product.Weight = row.Weight
+	}
+	if row.Dimensions != nil {
+		product.Dimensions = row.Dimensions
+	}
+
+	if len(row.Extras) > 0 {
+		extras := map[string]interface{}{}
+		for key, value := range product.Extras {
+			extras[key] = value
+		}
+		for key, value := range row.Extras {
+			extras[key] = value
+		}
+		product.Extras = extras
+	}
+}
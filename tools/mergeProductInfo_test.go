@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeProductInfo(t *testing.T) {
+	weight := decimal.RequireFromString("0.2")
+	product := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("100.25"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+		SKU:       "PH-1",
+		Brand:     "acme",
+		Weight:    &weight,
+		Extras:    map[string]interface{}{"color": "black", "size": "m"},
+		Version:   3,
+	}
+
+	row := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "смартфон",
+		Price:     decimal.RequireFromString("90"),
+		Currency:  "USD",
+		Quantity:  5,
+		Available: true,
+		Category:  "электроника",
+		Extras:    map[string]interface{}{"size": "xl"},
+	}
+
+	MergeProductInfo(product, row)
+
+	assert.Equal(t, &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "смартфон",
+		Price:     decimal.RequireFromString("90"),
+		Currency:  "USD",
+		Quantity:  5,
+		Available: true,
+		SKU:       "PH-1",
+		Category:  "электроника",
+		Brand:     "acme",
+		Weight:    &weight,
+		Extras:    map[string]interface{}{"color": "black", "size": "xl"},
+		Version:   3,
+	}, product)
+}
@@ -0,0 +1,14 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchWords - words of full-text search query, everything except letters
+// and digits separates words, so words can not contain operators of query
+func SearchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
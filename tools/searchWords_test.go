@@ -0,0 +1,12 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWords(t *testing.T) {
+	assert.Equal(t, []string{"iphone", "12", "телефон"}, SearchWords(" iPhone-12 & Телефон:* "))
+	assert.Empty(t, SearchWords("!&|()"))
+}
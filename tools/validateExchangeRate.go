@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+)
+
+// ValidateExchangeRate - check currency and rate set by admin
+func ValidateExchangeRate(rate *models.ExchangeRate) error {
+	fields := []domainErrors.FieldError{}
+
+	if !IsCurrency(rate.Currency) {
+		fields = append(fields, domainErrors.FieldError{Field: "currency", Message: "must be ISO 4217 code"})
+	}
+
+	if !rate.Rate.IsPositive() {
+		fields = append(fields, domainErrors.FieldError{Field: "rate", Message: "must be positive"})
+	}
+
+	if len(fields) > 0 {
+		return domainErrors.InvalidFields("Misrepresentation of values", fields...)
+	}
+
+	return nil
+}
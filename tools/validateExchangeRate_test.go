@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExchangeRate(t *testing.T) {
+	assert.NoError(t, ValidateExchangeRate(&models.ExchangeRate{Currency: "USD", Rate: decimal.RequireFromString("90.5")}))
+
+	err := ValidateExchangeRate(&models.ExchangeRate{Currency: "US", Rate: decimal.Zero})
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "currency", Message: "must be ISO 4217 code"},
+		{Field: "rate", Message: "must be positive"},
+	}, domainErrors.Fields(err))
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// maxNameLength - length of name column in DB
+const maxNameLength = 255
+
+// Limits of optional attributes of product
+const (
+	maxSKULength         = 64
+	maxBrandLength       = 255
+	maxCategoryLength    = 1024
+	maxDescriptionLength = 10000
+	maxImages            = 20
+	maxExtras            = 50
+)
+
+// maxPrice - prices are less than 10^15, so values like 1e300 are rejected
+var maxPrice = decimal.New(1, 15)
+
+// priceMaxScale - max digits of price after decimal point
+var priceMaxScale int32 = 2
+
+// currencyRegexp - ISO 4217 code of currency
+var currencyRegexp = regexp.MustCompile("^[A-Z]{3}$")
+
+// SetPriceMaxScale - set max digits of price after decimal point, it is
+// called once on start before any validation
+func SetPriceMaxScale(scale int32) {
+	priceMaxScale = scale
+}
+
+// RoundPrice - round price to max digits after decimal point, it is used
+// for prices converted to other currency
+func RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return price.Round(priceMaxScale)
+}
+
+// IsCurrency - code is ISO 4217 code of currency
+func IsCurrency(code string) bool {
+	return currencyRegexp.MatchString(code)
+}
+
+// ValidateProductInfo - check values of product, the same rules are used for
+// rows of xlsx files and json bodies of requests
+func ValidateProductInfo(productInfo *models.ProductInfo) error {
+	fields := []domainErrors.FieldError{}
+
+	if productInfo.SellerID <= 0 {
+		fields = append(fields, domainErrors.FieldError{Field: "seller_id", Message: "must be positive"})
+	}
+
+	if productInfo.OfferID <= 0 {
+		fields = append(fields, domainErrors.FieldError{Field: "offer_id", Message: "must be positive"})
+	}
+
+	if productInfo.Name == "" {
+		fields = append(fields, domainErrors.FieldError{Field: "name", Message: "must not be empty"})
+	} else if utf8.RuneCountInString(productInfo.Name) > maxNameLength {
+		fields = append(fields, domainErrors.FieldError{Field: "name", Message: "is too long"})
+	}
+
+	switch {
+	case productInfo.Price.IsNegative():
+		fields = append(fields, domainErrors.FieldError{Field: "price", Message: "must not be negative"})
+	case productInfo.Price.GreaterThanOrEqual(maxPrice):
+		fields = append(fields, domainErrors.FieldError{Field: "price", Message: "is too large"})
+	case !productInfo.Price.Equal(productInfo.Price.Round(priceMaxScale)):
+		fields = append(fields, domainErrors.FieldError{Field: "price",
+			Message: fmt.Sprintf("must have at most %d digits after decimal point", priceMaxScale)})
+	}
+
+	if !IsCurrency(productInfo.Currency) {
+		fields = append(fields, domainErrors.FieldError{Field: "currency", Message: "must be ISO 4217 code"})
+	}
+
+	if productInfo.Quantity < 0 {
+		fields = append(fields, domainErrors.FieldError{Field: "quantity", Message: "must not be negative"})
+	}
+
+	fields = append(fields, validateAttributes(productInfo)...)
+
+	if len(fields) > 0 {
+		return domainErrors.InvalidFields("Misrepresentation of values", fields...)
+	}
+
+	return nil
+}
+
+// validateAttributes - check optional attributes of product
+func validateAttributes(productInfo *models.ProductInfo) []domainErrors.FieldError {
+	fields := []domainErrors.FieldError{}
+
+	if utf8.RuneCountInString(productInfo.SKU) > maxSKULength {
+		fields = append(fields, domainErrors.FieldError{Field: "sku", Message: "is too long"})
+	}
+
+	if utf8.RuneCountInString(productInfo.Description) > maxDescriptionLength {
+		fields = append(fields, domainErrors.FieldError{Field: "description", Message: "is too long"})
+	}
+
+	if productInfo.Category != "" {
+		if utf8.RuneCountInString(productInfo.Category) > maxCategoryLength {
+			fields = append(fields, domainErrors.FieldError{Field: "category", Message: "is too long"})
+		} else {
+			for _, level := range strings.Split(productInfo.Category, models.CategorySeparator) {
+				if strings.TrimSpace(level) == "" {
+					fields = append(fields, domainErrors.FieldError{Field: "category",
+						Message: "must not have empty levels"})
+					break
+				}
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(productInfo.Brand) > maxBrandLength {
+		fields = append(fields, domainErrors.FieldError{Field: "brand", Message: "is too long"})
+	}
+
+	if len(productInfo.Images) > maxImages {
+		fields = append(fields, domainErrors.FieldError{Field: "images",
+			Message: fmt.Sprintf("must have at most %d urls", maxImages)})
+	} else {
+		for _, image := range productInfo.Images {
+			if u, err := url.Parse(image); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				fields = append(fields, domainErrors.FieldError{Field: "images", Message: "must be http urls"})
+				break
+			}
+		}
+	}
+
+	if productInfo.Weight != nil && !productInfo.Weight.IsPositive() {
+		fields = append(fields, domainErrors.FieldError{Field: "weight", Message: "must be positive"})
+	}
+
+	if d := productInfo.Dimensions; d != nil && (!d.Length.IsPositive() || !d.Width.IsPositive() || !d.Height.IsPositive()) {
+		fields = append(fields, domainErrors.FieldError{Field: "dimensions", Message: "must be positive"})
+	}
+
+	if len(productInfo.Extras) > maxExtras {
+		fields = append(fields, domainErrors.FieldError{Field: "extras",
+			Message: fmt.Sprintf("must have at most %d keys", maxExtras)})
+	}
+
+	return fields
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Toringol/avito-mx-backend-test-task/app/domainErrors"
+	"github.com/Toringol/avito-mx-backend-test-task/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProductInfo(t *testing.T) {
+	productInfo := &models.ProductInfo{
+		SellerID:  1,
+		OfferID:   1,
+		Name:      "телефон",
+		Price:     decimal.RequireFromString("10.5"),
+		Currency:  "RUB",
+		Quantity:  10,
+		Available: true,
+	}
+
+	assert.NoError(t, ValidateProductInfo(productInfo))
+
+	invalidProductInfo := &models.ProductInfo{
+		SellerID: 0,
+		OfferID:  -1,
+		Name:     strings.Repeat("a", 256),
+		Price:    decimal.RequireFromString("-1"),
+		Currency: "RUB",
+		Quantity: -1,
+	}
+
+	err := ValidateProductInfo(invalidProductInfo)
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "seller_id", Message: "must be positive"},
+		{Field: "offer_id", Message: "must be positive"},
+		{Field: "name", Message: "is too long"},
+		{Field: "price", Message: "must not be negative"},
+		{Field: "quantity", Message: "must not be negative"},
+	}, domainErrors.Fields(err))
+
+	err = ValidateProductInfo(&models.ProductInfo{SellerID: 1, OfferID: 1, Currency: "RUB"})
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "name", Message: "must not be empty"},
+	}, domainErrors.Fields(err))
+
+	// test precision of price and currency
+
+	for _, price := range []string{"1.5", "1.50", "1.500", "999999999999999.99"} {
+		productInfo.Price = decimal.RequireFromString(price)
+		assert.NoError(t, ValidateProductInfo(productInfo), price)
+	}
+
+	productInfo.Price = decimal.RequireFromString("0.125")
+	productInfo.Currency = "rub"
+
+	err = ValidateProductInfo(productInfo)
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "price", Message: "must have at most 2 digits after decimal point"},
+		{Field: "currency", Message: "must be ISO 4217 code"},
+	}, domainErrors.Fields(err))
+
+	productInfo.Price = decimal.RequireFromString("1e300")
+	productInfo.Currency = "USD"
+
+	err = ValidateProductInfo(productInfo)
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "price", Message: "is too large"},
+	}, domainErrors.Fields(err))
+
+	SetPriceMaxScale(3)
+	defer SetPriceMaxScale(2)
+
+	productInfo.Price = decimal.RequireFromString("0.125")
+	assert.NoError(t, ValidateProductInfo(productInfo))
+}
+
+func TestValidateProductInfoAttributes(t *testing.T) {
+	weight := decimal.RequireFromString("0.5")
+	productInfo := &models.ProductInfo{
+		SellerID:    1,
+		OfferID:     1,
+		Name:        "телефон",
+		Price:       decimal.RequireFromString("10.5"),
+		Currency:    "RUB",
+		Quantity:    10,
+		Available:   true,
+		SKU:         "PH-1",
+		Description: "смартфон",
+		Category:    "электроника/телефоны",
+		Brand:       "acme",
+		Images:      []string{"https://img/1.png"},
+		Weight:      &weight,
+		Dimensions: &models.Dimensions{
+			Length: decimal.RequireFromString("15"),
+			Width:  decimal.RequireFromString("7.5"),
+			Height: decimal.RequireFromString("0.8"),
+		},
+		Extras: map[string]interface{}{"color": "black"},
+	}
+
+	assert.NoError(t, ValidateProductInfo(productInfo))
+
+	zero := decimal.Zero
+	extras := map[string]interface{}{}
+	for i := 0; i <= maxExtras; i++ {
+		extras[strings.Repeat("k", i+1)] = i
+	}
+
+	productInfo.SKU = strings.Repeat("a", maxSKULength+1)
+	productInfo.Category = "электроника//телефоны"
+	productInfo.Brand = strings.Repeat("a", maxBrandLength+1)
+	productInfo.Images = []string{"https://img/1.png", "img/2.png"}
+	productInfo.Weight = &zero
+	productInfo.Dimensions.Height = decimal.RequireFromString("-1")
+	productInfo.Extras = extras
+
+	err := ValidateProductInfo(productInfo)
+	assert.True(t, errors.Is(err, domainErrors.ErrValidation))
+	assert.Equal(t, []domainErrors.FieldError{
+		{Field: "sku", Message: "is too long"},
+		{Field: "category", Message: "must not have empty levels"},
+		{Field: "brand", Message: "is too long"},
+		{Field: "images", Message: "must be http urls"},
+		{Field: "weight", Message: "must be positive"},
+		{Field: "dimensions", Message: "must be positive"},
+		{Field: "extras", Message: "must have at most 50 keys"},
+	}, domainErrors.Fields(err))
+}